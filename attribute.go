@@ -3,6 +3,7 @@ package radius
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"math"
@@ -182,27 +183,47 @@ func UserPassword(a Attribute, secret, requestAuthenticator []byte) ([]byte, err
 // the plaintext is too long, the secret is empty, or the requestAuthenticator
 // is an invalid length.
 func NewUserPassword(plaintext, secret, requestAuthenticator []byte) (Attribute, error) {
-	if len(plaintext) > 128 {
-		return nil, errors.New("plaintext longer than 128 characters")
-	}
-	if len(secret) == 0 {
-		return nil, errors.New("empty secret")
+	if err := checkUserPassword(plaintext, secret); err != nil {
+		return nil, err
 	}
 	if len(requestAuthenticator) != 16 {
 		return nil, errors.New("requestAuthenticator not 16-bytes")
 	}
 
+	enc := make([]byte, userPasswordLen(plaintext))
+	encryptUserPassword(enc, plaintext, secret, requestAuthenticator)
+	return enc, nil
+}
+
+// checkUserPassword returns an error if plaintext cannot be encrypted as a
+// User-Password using secret.
+func checkUserPassword(plaintext, secret []byte) error {
+	if len(plaintext) > 128 {
+		return errors.New("plaintext longer than 128 characters")
+	}
+	if len(secret) == 0 {
+		return errors.New("empty secret")
+	}
+	return nil
+}
+
+// userPasswordLen returns the length of the User-Password value that
+// plaintext is encrypted to.
+func userPasswordLen(plaintext []byte) int {
 	chunks := (len(plaintext) + 16 - 1) / 16
 	if chunks == 0 {
 		chunks = 1
 	}
+	return chunks * 16
+}
 
-	enc := make([]byte, 0, chunks*16)
-
+// encryptUserPassword writes the encryption of plaintext to enc, which must be
+// userPasswordLen(plaintext) bytes long.
+func encryptUserPassword(enc, plaintext, secret, requestAuthenticator []byte) {
 	hash := md5.New()
 	hash.Write(secret)
 	hash.Write(requestAuthenticator)
-	enc = hash.Sum(enc)
+	hash.Sum(enc[:0])
 
 	for i := 0; i < 16 && i < len(plaintext); i++ {
 		enc[i] ^= plaintext[i]
@@ -212,14 +233,12 @@ func NewUserPassword(plaintext, secret, requestAuthenticator []byte) (Attribute,
 		hash.Reset()
 		hash.Write(secret)
 		hash.Write(enc[i-16 : i])
-		enc = hash.Sum(enc)
+		hash.Sum(enc[i:i])
 
 		for j := 0; j < 16 && i+j < len(plaintext); j++ {
 			enc[i+j] ^= plaintext[i+j]
 		}
 	}
-
-	return enc, nil
 }
 
 // Date returns the given Attribute as time.Time. An error is returned if the
@@ -268,6 +287,67 @@ func NewVendorSpecific(vendorID uint32, value Attribute) (Attribute, error) {
 	return a, nil
 }
 
+// TaggedInteger returns the tag and value of the given RFC 2868 tagged
+// integer attribute. The tag occupies the first octet, leaving 24 bits for the
+// value. An error is returned if the attribute is not 4 bytes long or if the
+// tag is out of range.
+func TaggedInteger(a Attribute) (tag byte, value uint32, err error) {
+	if len(a) != 4 {
+		err = errors.New("invalid length")
+		return
+	}
+	if a[0] > 0x1F {
+		err = errors.New("invalid tag")
+		return
+	}
+	tag = a[0]
+	value = binary.BigEndian.Uint32(a) & 0x00FFFFFF
+	return
+}
+
+// NewTaggedInteger returns a new RFC 2868 tagged integer attribute. An error
+// is returned if the tag is greater than 0x1F or if value does not fit in 24
+// bits.
+func NewTaggedInteger(tag byte, value uint32) (Attribute, error) {
+	if tag > 0x1F {
+		return nil, errors.New("invalid tag")
+	}
+	if value > 0x00FFFFFF {
+		return nil, errors.New("value too large")
+	}
+	a := NewInteger(value)
+	a[0] = tag
+	return a, nil
+}
+
+// TaggedString returns the tag and value of the given RFC 2868 tagged string
+// attribute. Per RFC 2868, the first octet is only a tag if it is in the range
+// 0x01-0x1F; a leading 0x00 is also treated as an (unused) tag. Otherwise, the
+// attribute is untagged, and 0 is returned as the tag along with the entire
+// attribute.
+func TaggedString(a Attribute) (tag byte, value Attribute) {
+	if len(a) > 0 && a[0] <= 0x1F {
+		return a[0], a[1:]
+	}
+	return 0, a
+}
+
+// NewTaggedString returns a new RFC 2868 tagged string attribute. The tag
+// octet is always included, even if tag is 0. An error is returned if the tag
+// is greater than 0x1F or if value is longer than 252 bytes.
+func NewTaggedString(tag byte, value string) (Attribute, error) {
+	if tag > 0x1F {
+		return nil, errors.New("invalid tag")
+	}
+	if len(value) > 252 {
+		return nil, errors.New("value too long")
+	}
+	a := make(Attribute, 1+len(value))
+	a[0] = tag
+	copy(a[1:], value)
+	return a, nil
+}
+
 // Integer64 returns the given attribute as an integer. An error is returned if
 // the attribute is not 8 bytes long.
 func Integer64(a Attribute) (uint64, error) {
@@ -330,6 +410,48 @@ func NewTunnelPassword(password, salt, secret, requestAuthenticator []byte) (Att
 	if len(password) > 249 {
 		return nil, errors.New("invalid password length")
 	}
+	return encryptSalted(password, salt, secret, requestAuthenticator)
+}
+
+// TunnelPassword decrypts an RFC 2868 encrypted Tunnel-Password.
+// The Attribute must not be prefixed with a tag.
+// The requestAuthenticator must be from the Access-Request packet.
+func TunnelPassword(a Attribute, secret, requestAuthenticator []byte) (password, salt []byte, err error) {
+	return decryptSalted(a, secret, requestAuthenticator)
+}
+
+// EncryptSalted encrypts value using the salt-encryption scheme of RFC 2868
+// section 3.5, which is also used by vendor attributes such as the RFC 2548
+// MS-MPPE-Send-Key and MS-MPPE-Recv-Key. A random salt is generated and
+// prepended to the returned Attribute. Tagged attributes must have their tag
+// added on to the returned Attribute.
+//
+// The requestAuthenticator must be from the Access-Request packet. An error is
+// returned if value is longer than 239 bytes (the most that fits in an
+// attribute).
+func EncryptSalted(value, secret, requestAuthenticator []byte) (Attribute, error) {
+	if len(value) > 239 {
+		return nil, errors.New("invalid value length")
+	}
+	var salt [2]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, err
+	}
+	salt[0] |= 0x80 // MSB must be 1
+	return encryptSalted(value, salt[:], secret, requestAuthenticator)
+}
+
+// DecryptSalted decrypts an Attribute that was encrypted using the
+// salt-encryption scheme of RFC 2868 section 3.5 (see EncryptSalted). The
+// Attribute must not be prefixed with a tag.
+//
+// The requestAuthenticator must be from the Access-Request packet.
+func DecryptSalted(a Attribute, secret, requestAuthenticator []byte) ([]byte, error) {
+	value, _, err := decryptSalted(a, secret, requestAuthenticator)
+	return value, err
+}
+
+func encryptSalted(value, salt, secret, requestAuthenticator []byte) (Attribute, error) {
 	if len(salt) != 2 {
 		return nil, errors.New("invalid salt length")
 	}
@@ -343,15 +465,15 @@ func NewTunnelPassword(password, salt, secret, requestAuthenticator []byte) (Att
 		return nil, errors.New("invalid requestAuthenticator length")
 	}
 
-	chunks := (1 + len(password) + 16 - 1) / 16
+	chunks := (1 + len(value) + 16 - 1) / 16
 	if chunks == 0 {
 		chunks = 1
 	}
 
 	attr := make([]byte, 2+chunks*16)
 	copy(attr[:2], salt)
-	attr[2] = byte(len(password))
-	copy(attr[3:], password)
+	attr[2] = byte(len(value))
+	copy(attr[3:], value)
 
 	hash := md5.New()
 	var b [md5.Size]byte
@@ -376,10 +498,7 @@ func NewTunnelPassword(password, salt, secret, requestAuthenticator []byte) (Att
 	return attr, nil
 }
 
-// TunnelPassword decrypts an RFC 2868 encrypted Tunnel-Password.
-// The Attribute must not be prefixed with a tag.
-// The requestAuthenticator must be from the Access-Request packet.
-func TunnelPassword(a Attribute, secret, requestAuthenticator []byte) (password, salt []byte, err error) {
+func decryptSalted(a Attribute, secret, requestAuthenticator []byte) (value, salt []byte, err error) {
 	if len(a) > 252 || len(a) < 18 || (len(a)-2)%16 != 0 {
 		err = errors.New("invalid length")
 		return
@@ -423,12 +542,12 @@ func TunnelPassword(a Attribute, secret, requestAuthenticator []byte) (password,
 		}
 	}
 
-	passwordLength := plaintext[0]
-	if int(passwordLength) > (len(plaintext) - 1) {
-		err = errors.New("invalid password length")
+	valueLength := plaintext[0]
+	if int(valueLength) > (len(plaintext) - 1) {
+		err = errors.New("invalid value length")
 		return
 	}
-	password = plaintext[1 : 1+passwordLength]
+	value = plaintext[1 : 1+valueLength]
 	return
 }
 
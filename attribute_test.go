@@ -156,3 +156,84 @@ func ipNetEquals(a, b *net.IPNet) bool {
 	}
 	return a.IP.Equal(b.IP)
 }
+
+func TestTaggedInteger(t *testing.T) {
+	a, err := NewTaggedInteger(0x05, 0x0102)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, []byte{0x05, 0x00, 0x01, 0x02}) {
+		t.Fatalf("got %#v", a)
+	}
+	tag, value, err := TaggedInteger(a)
+	if err != nil || tag != 0x05 || value != 0x0102 {
+		t.Fatalf("got %d, %d, %v; expecting 5, 258, nil", tag, value, err)
+	}
+
+	if _, err := NewTaggedInteger(0x20, 1); err == nil {
+		t.Fatal("expecting invalid tag error")
+	}
+	if _, err := NewTaggedInteger(0x01, 0x01000000); err == nil {
+		t.Fatal("expecting value too large error")
+	}
+	if _, _, err := TaggedInteger(Attribute{0x01, 0x02}); err == nil {
+		t.Fatal("expecting invalid length error")
+	}
+}
+
+func TestTaggedString(t *testing.T) {
+	a, err := NewTaggedString(0x02, "vlan10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, []byte("\x02vlan10")) {
+		t.Fatalf("got %#v", a)
+	}
+	if tag, value := TaggedString(a); tag != 0x02 || string(value) != "vlan10" {
+		t.Fatalf("got %d, %q; expecting 2, vlan10", tag, value)
+	}
+	if tag, value := TaggedString(Attribute("vlan10")); tag != 0 || string(value) != "vlan10" {
+		t.Fatalf("got %d, %q; expecting untagged vlan10", tag, value)
+	}
+
+	if _, err := NewTaggedString(0x20, ""); err == nil {
+		t.Fatal("expecting invalid tag error")
+	}
+	if _, err := NewTaggedString(0x01, string(make([]byte, 253))); err == nil {
+		t.Fatal("expecting value too long error")
+	}
+}
+
+func TestSalted(t *testing.T) {
+	secret := []byte(`12345`)
+	requestAuthenticator := bytes.Repeat([]byte{0x01}, 16)
+	value := []byte(`mppe-key-material`)
+
+	a, err := EncryptSalted(value, secret, requestAuthenticator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 2+32 || a[0]&0x80 == 0 {
+		t.Fatalf("unexpected encrypted attribute %x", a)
+	}
+
+	decrypted, err := DecryptSalted(a, secret, requestAuthenticator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, value) {
+		t.Fatalf("got %q; expecting %q", decrypted, value)
+	}
+
+	password, salt, err := TunnelPassword(a, secret, requestAuthenticator)
+	if err != nil || !bytes.Equal(password, value) || !bytes.Equal(salt, a[:2]) {
+		t.Fatalf("got %q, %x, %v; expecting TunnelPassword to decrypt the same value", password, salt, err)
+	}
+
+	if _, err := EncryptSalted(make([]byte, 240), secret, requestAuthenticator); err == nil {
+		t.Fatal("expecting invalid value length error")
+	}
+	if _, err := DecryptSalted(a[:17], secret, requestAuthenticator); err == nil {
+		t.Fatal("expecting invalid length error")
+	}
+}
@@ -1,7 +1,9 @@
 package radius
 
 import (
+	"bytes"
 	"errors"
+	"sort"
 )
 
 // Type is the RADIUS attribute type.
@@ -24,29 +26,196 @@ type Attributes []*AVP
 // ParseAttributes parses the wire-encoded RADIUS attributes and returns a new
 // Attributes value. An error is returned if the buffer is malformed.
 func ParseAttributes(b []byte) (Attributes, error) {
-	var attrs Attributes
+	attrs, _, err := ParseAttributesFiltered(b, nil)
+	return attrs, err
+}
+
+// ParseAttributesFiltered parses the wire-encoded RADIUS attributes like
+// ParseAttributes, but only keeps attributes whose type is permitted by allow.
+// The types of attributes that were dropped are returned in wire order. If
+// allow is nil, all attributes are kept.
+//
+// The entire buffer is validated, including dropped attributes.
+//
+// Consecutive attributes of a type that was set using SetConcatType are joined
+// into a single Attribute.
+func ParseAttributesFiltered(b []byte, allow func(Type) bool) (attrs Attributes, dropped []Type, err error) {
+	return parseAttributes(b, allow, nil)
+}
+
+// ParseOptions configures how strictly wire-encoded attributes are parsed by
+// ParseAttributesWith and ParseWith. The zero value matches the behavior of
+// ParseAttributes.
+type ParseOptions struct {
+	// RejectTypeZero fails parsing if an attribute of type 0, which is
+	// reserved, is present.
+	RejectTypeZero bool
+
+	// RejectEmptyValues fails parsing if an attribute with a zero-length value
+	// is present, and its type is a standard attribute type that requires a
+	// value (see ValidateStandard).
+	RejectEmptyValues bool
+
+	// MaxAttributes, if greater than zero, is the maximum number of attributes
+	// that may be present, counted on the wire. A *LimitExceededError is
+	// returned if the limit is exceeded.
+	MaxAttributes int
+
+	// MaxValueBytes, if greater than zero, is the maximum total length of the
+	// attribute values that are kept. A *LimitExceededError is returned if the
+	// limit is exceeded.
+	MaxValueBytes int
+
+	// IgnoreTrailingData stops parsing at the first malformed attribute
+	// header, discarding it and any data that follows, instead of failing.
+	IgnoreTrailingData bool
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of a
+	// packet accepted by ParseWith, up to MaxExtendedPacketLength. If zero,
+	// MaxPacketLength is used. It is ignored when parsing attributes alone.
+	MaxPacketSize int
+
+	// NoCopy makes attribute values reference the input buffer instead of
+	// copying each value. The caller must then not modify the buffer while
+	// the attributes are in use; values that need to outlive it should be
+	// copied, or the attributes cloned with Clone. Values are capped, so
+	// appending to one never overwrites the rest of the buffer. Packets
+	// parsed with ParseWith also keep referencing the buffer to verify their
+	// authenticators.
+	NoCopy bool
+}
 
+// ParseAttributesWith parses the wire-encoded RADIUS attributes like
+// ParseAttributes, using the given options.
+func ParseAttributesWith(b []byte, opts ParseOptions) (Attributes, error) {
+	attrs, _, err := parseAttributes(b, nil, &opts)
+	return attrs, err
+}
+
+func parseAttributes(b []byte, allow func(Type) bool, opts *ParseOptions) (attrs Attributes, dropped []Type, err error) {
+	if opts == nil {
+		opts = &ParseOptions{}
+	}
+	var count, valueBytes int
+	var lastConcat bool
 	for len(b) > 0 {
 		if len(b) < 2 {
-			return nil, errors.New("short buffer")
+			if opts.IgnoreTrailingData {
+				break
+			}
+			return nil, nil, errors.New("short buffer")
 		}
 		length := int(b[1])
 		if length > len(b) || length < 2 || length > 255 {
-			return nil, errors.New("invalid attribute length")
+			if opts.IgnoreTrailingData {
+				break
+			}
+			return nil, nil, errors.New("invalid attribute length")
+		}
+
+		typ := Type(b[0])
+		if opts.RejectTypeZero && typ == 0 {
+			return nil, nil, errors.New("radius: attribute type 0 is reserved")
+		}
+		if opts.RejectEmptyValues && length == 2 {
+			if c, ok := standardLengths[typ]; ok && c.Min > 0 {
+				return nil, nil, &InvalidAttributeLengthError{
+					Type: typ,
+					Min:  c.Min,
+					Max:  c.Max,
+				}
+			}
+		}
+		count++
+		if opts.MaxAttributes > 0 && count > opts.MaxAttributes {
+			return nil, nil, &LimitExceededError{
+				Limit: "attribute count",
+				Max:   opts.MaxAttributes,
+			}
+		}
+
+		if allow != nil && !allow(typ) {
+			dropped = append(dropped, typ)
+			lastConcat = false
+			b = b[length:]
+			continue
+		}
+
+		valueBytes += length - 2
+		if opts.MaxValueBytes > 0 && valueBytes > opts.MaxValueBytes {
+			return nil, nil, &LimitExceededError{
+				Limit: "attribute value size",
+				Max:   opts.MaxValueBytes,
+			}
+		}
+
+		if last := len(attrs) - 1; lastConcat && last >= 0 && attrs[last].Type == typ {
+			attrs[last].Attribute = append(attrs[last].Attribute, b[2:length]...)
+			b = b[length:]
+			continue
 		}
 
 		avp := &AVP{
-			Type: Type(b[0]),
+			Type: typ,
 		}
 		if length > 2 {
-			avp.Attribute = append(Attribute(nil), b[2:length]...)
+			if opts.NoCopy {
+				avp.Attribute = Attribute(b[2:length:length])
+			} else {
+				avp.Attribute = append(Attribute(nil), b[2:length]...)
+			}
 		}
 		attrs = append(attrs, avp)
+		lastConcat = isConcatType(typ)
 
 		b = b[length:]
 	}
 
-	return attrs, nil
+	return attrs, dropped, nil
+}
+
+// Clone returns a deep copy of a. Modifying the returned Attributes, or the
+// values they contain, does not affect a.
+func (a *Attributes) Clone() Attributes {
+	if *a == nil {
+		return nil
+	}
+	clone := make(Attributes, len(*a))
+	for i, avp := range *a {
+		clone[i] = &AVP{
+			Type: avp.Type,
+		}
+		if avp.Attribute != nil {
+			clone[i].Attribute = append(Attribute(nil), avp.Attribute...)
+		}
+	}
+	return clone
+}
+
+// Filter returns a new Attributes that contains the attributes of a for which
+// keep returns true, in wire order. a is not modified.
+func (a *Attributes) Filter(keep func(key Type, value Attribute) bool) Attributes {
+	var filtered Attributes
+	for _, avp := range *a {
+		if keep(avp.Type, avp.Attribute) {
+			filtered.Add(avp.Type, avp.Attribute)
+		}
+	}
+	return filtered
+}
+
+// Map returns a new Attributes that contains the result of calling fn on each
+// attribute of a, in wire order. Attributes for which fn returns false are
+// dropped. a is not modified, although fn may return the same value it is
+// given.
+func (a *Attributes) Map(fn func(key Type, value Attribute) (Type, Attribute, bool)) Attributes {
+	var mapped Attributes
+	for _, avp := range *a {
+		if key, value, ok := fn(avp.Type, avp.Attribute); ok {
+			mapped.Add(key, value)
+		}
+	}
+	return mapped
 }
 
 // Add appends the given Attribute to the list of attributes.
@@ -57,6 +226,40 @@ func (a *Attributes) Add(key Type, value Attribute) {
 	})
 }
 
+// InsertAt inserts the given Attribute into a so that it has the given index
+// in the wire order. Attributes at or after index are shifted back by one.
+// Inserting at len(*a) is equivalent to Add.
+//
+// InsertAt panics if index is out of range.
+func (a *Attributes) InsertAt(index int, key Type, value Attribute) {
+	if index < 0 || index > len(*a) {
+		panic("radius: InsertAt index out of range")
+	}
+	*a = append(*a, nil)
+	copy((*a)[index+1:], (*a)[index:])
+	(*a)[index] = &AVP{
+		Type:      key,
+		Attribute: value,
+	}
+}
+
+// IndexOf returns the index in the wire order of a of the nth (starting at
+// zero) Attribute of Type key. -1 is returned if there is no such Attribute.
+func (a *Attributes) IndexOf(key Type, n int) int {
+	if n < 0 {
+		return -1
+	}
+	for i, avp := range *a {
+		if avp.Type == key {
+			if n == 0 {
+				return i
+			}
+			n--
+		}
+	}
+	return -1
+}
+
 // Del removes all Attributes of the given type from a.
 func (a *Attributes) Del(key Type) {
 	for i := 0; i < len(*a); {
@@ -68,6 +271,45 @@ func (a *Attributes) Del(key Type) {
 	}
 }
 
+// DelNth removes the nth (starting at zero) Attribute of Type key from a,
+// preserving the order of the remaining attributes. A negative n counts from
+// the last Attribute of Type key (e.g. -1 removes the last one). false is
+// returned if there is no such Attribute.
+func (a *Attributes) DelNth(key Type, n int) bool {
+	if n < 0 {
+		n += a.count(key)
+	}
+	i := a.IndexOf(key, n)
+	if i == -1 {
+		return false
+	}
+	*a = append((*a)[:i], (*a)[i+1:]...)
+	return true
+}
+
+// DelValue removes the first Attribute of Type key whose value equals value,
+// preserving the order of the remaining attributes. false is returned if
+// there is no such Attribute.
+func (a *Attributes) DelValue(key Type, value Attribute) bool {
+	for i, avp := range *a {
+		if avp.Type == key && bytes.Equal(avp.Attribute, value) {
+			*a = append((*a)[:i], (*a)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Attributes) count(key Type) int {
+	var n int
+	for _, avp := range *a {
+		if avp.Type == key {
+			n++
+		}
+	}
+	return n
+}
+
 // Get returns the first Attribute of Type key. nil is returned if no Attribute
 // of Type key exists in a.
 func (a *Attributes) Get(key Type) Attribute {
@@ -86,6 +328,42 @@ func (a *Attributes) Lookup(key Type) (Attribute, bool) {
 	return nil, false
 }
 
+// GetAllWithPositions returns every Attribute of Type key, along with its
+// index in the wire order of a.
+func (a *Attributes) GetAllWithPositions(key Type) []struct {
+	Index int
+	Value Attribute
+} {
+	var positions []struct {
+		Index int
+		Value Attribute
+	}
+	for i, attr := range *a {
+		if attr.Type == key {
+			positions = append(positions, struct {
+				Index int
+				Value Attribute
+			}{i, attr.Attribute})
+		}
+	}
+	return positions
+}
+
+// GetAllUint32 returns all Attributes of Type key decoded as 32-bit integers,
+// in wire order. Attributes that are not 4 bytes long are skipped.
+func (a *Attributes) GetAllUint32(key Type) []uint32 {
+	var values []uint32
+	for _, avp := range *a {
+		if avp.Type != key {
+			continue
+		}
+		if i, err := Integer(avp.Attribute); err == nil {
+			values = append(values, i)
+		}
+	}
+	return values
+}
+
 // Set removes all Attributes of Type key and appends value.
 func (a *Attributes) Set(key Type, value Attribute) {
 	foundKey := false
@@ -110,31 +388,340 @@ func (a *Attributes) Set(key Type, value Attribute) {
 	}
 }
 
+// SetNth replaces the value of the nth (starting at zero) Attribute of Type
+// key, without changing its position or the other attributes in a. A negative
+// n counts from the last Attribute of Type key. false is returned if there is
+// no such Attribute.
+func (a *Attributes) SetNth(key Type, n int, value Attribute) bool {
+	if n < 0 {
+		n += a.count(key)
+	}
+	i := a.IndexOf(key, n)
+	if i == -1 {
+		return false
+	}
+	(*a)[i] = &AVP{
+		Type:      key,
+		Attribute: value,
+	}
+	return true
+}
+
+// Merge adds the attributes of other to a.
+//
+// If overwrite is false, the attributes of other are appended to a in their
+// wire order. If overwrite is true, all attributes of a whose type appears in
+// other are replaced by the attributes of that type from other, which take
+// the position of the first replaced attribute; attributes of types that are
+// not in a are appended. In both cases, the relative order of the attributes
+// from other is preserved.
+func (a *Attributes) Merge(other *Attributes, overwrite bool) {
+	if !overwrite {
+		for _, avp := range *other {
+			a.Add(avp.Type, avp.Attribute)
+		}
+		return
+	}
+
+	byType := make(map[Type]Attributes)
+	var order []Type
+	for _, avp := range *other {
+		if _, ok := byType[avp.Type]; !ok {
+			order = append(order, avp.Type)
+		}
+		byType[avp.Type] = append(byType[avp.Type], &AVP{
+			Type:      avp.Type,
+			Attribute: avp.Attribute,
+		})
+	}
+
+	merged := make(Attributes, 0, len(*a)+len(*other))
+	replaced := make(map[Type]bool)
+	for _, avp := range *a {
+		replacement, ok := byType[avp.Type]
+		if !ok {
+			merged = append(merged, avp)
+			continue
+		}
+		if !replaced[avp.Type] {
+			merged = append(merged, replacement...)
+			replaced[avp.Type] = true
+		}
+	}
+	for _, typ := range order {
+		if !replaced[typ] {
+			merged = append(merged, byType[typ]...)
+		}
+	}
+	*a = merged
+}
+
+// GetFlags returns the first Attribute of Type key decoded as a 32-bit flags
+// integer. false is returned if no such Attribute exists, or if it is not 4
+// bytes long.
+func (a *Attributes) GetFlags(key Type) (uint32, bool) {
+	attr, ok := a.Lookup(key)
+	if !ok {
+		return 0, false
+	}
+	flags, err := Integer(attr)
+	if err != nil {
+		return 0, false
+	}
+	return flags, true
+}
+
+// HasFlag returns true if all bits in flag are set in the first Attribute of
+// Type key.
+func (a *Attributes) HasFlag(key Type, flag uint32) bool {
+	flags, ok := a.GetFlags(key)
+	return ok && flags&flag == flag
+}
+
+// SetFlag sets (if value is true) or clears (if value is false) the bits in
+// flag on the Attribute of Type key, replacing any existing Attributes of that
+// type. A missing or malformed Attribute is treated as having no bits set.
+func (a *Attributes) SetFlag(key Type, flag uint32, value bool) {
+	flags, _ := a.GetFlags(key)
+	if value {
+		flags |= flag
+	} else {
+		flags &^= flag
+	}
+	a.Set(key, NewInteger(flags))
+}
+
+// typeTunnelPreference is the RFC 2868 Tunnel-Preference attribute type.
+const typeTunnelPreference Type = 83
+
+// PreferredTunnel returns the tag of the tunnel with the highest preference
+// (the lowest Tunnel-Preference value, RFC 2868 section 3.8). If multiple
+// tunnels share the highest preference, the first one is returned.
+// Malformed Tunnel-Preference attributes are ignored.
+//
+// false is returned if a contains no valid Tunnel-Preference attributes.
+func (a *Attributes) PreferredTunnel() (tag byte, ok bool) {
+	var best uint32
+	for _, avp := range *a {
+		if avp.Type != typeTunnelPreference {
+			continue
+		}
+		t, preference, err := TaggedInteger(avp.Attribute)
+		if err != nil {
+			continue
+		}
+		if !ok || preference < best {
+			tag, best, ok = t, preference, true
+		}
+	}
+	return
+}
+
+// attributeTag returns the RFC 2868 tag of a, or 0 if a is untagged.
+func attributeTag(a Attribute) byte {
+	if len(a) > 0 && a[0] <= 0x1F {
+		return a[0]
+	}
+	return 0
+}
+
+// TaggedLookup returns the first Attribute of Type key that carries the given
+// RFC 2868 tag. The returned Attribute includes the tag octet; use
+// TaggedInteger or TaggedString to decode it. nil and false is returned if no
+// such Attribute exists in a.
+func (a *Attributes) TaggedLookup(key Type, tag byte) (Attribute, bool) {
+	for _, avp := range *a {
+		if avp.Type == key && attributeTag(avp.Attribute) == tag {
+			return avp.Attribute, true
+		}
+	}
+	return nil, false
+}
+
+// GroupByTag groups the Attributes of the given types by their RFC 2868 tag,
+// preserving wire order within each group. This allows the attributes that
+// describe a single tunnel (e.g. Tunnel-Type, Tunnel-Medium-Type, and
+// Tunnel-Private-Group-ID) to be handled together. Untagged attributes are
+// grouped under tag 0. Attributes of other types are ignored, as their first
+// octet is not a tag.
+func (a *Attributes) GroupByTag(types ...Type) map[byte]Attributes {
+	groups := make(map[byte]Attributes)
+	for _, avp := range *a {
+		for _, typ := range types {
+			if avp.Type == typ {
+				tag := attributeTag(avp.Attribute)
+				groups[tag] = append(groups[tag], avp)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+const (
+	// typeServiceType is the RFC 2865 Service-Type attribute type.
+	typeServiceType Type = 6
+	// serviceTypeAuthorizeOnly is the RFC 5176 Authorize-Only Service-Type.
+	serviceTypeAuthorizeOnly = 17
+)
+
+// IsAuthorizeOnly returns true if a contains a Service-Type of Authorize-Only
+// (RFC 5176 section 3.1), which asks the NAS to re-authorize the session
+// identified in a CoA-Request.
+func (a *Attributes) IsAuthorizeOnly() bool {
+	attr, ok := a.Lookup(typeServiceType)
+	if !ok {
+		return false
+	}
+	serviceType, err := Integer(attr)
+	return err == nil && serviceType == serviceTypeAuthorizeOnly
+}
+
+// ReorderLike reorders a so that its attributes follow the wire order of ref
+// as closely as possible.
+//
+// The nth attribute of a given type in a is moved to the position of the nth
+// attribute of that type in ref. Additional attributes of a type are placed
+// after the last attribute of that type in ref, and attributes whose type does
+// not appear in ref are moved to the end. Otherwise, the relative order of
+// attributes in a is preserved.
+func (a *Attributes) ReorderLike(ref *Attributes) {
+	refPositions := make(map[Type][]int)
+	for i, avp := range *ref {
+		refPositions[avp.Type] = append(refPositions[avp.Type], i)
+	}
+
+	keys := make(map[*AVP]int, len(*a))
+	seen := make(map[Type]int)
+	for _, avp := range *a {
+		positions := refPositions[avp.Type]
+		n := seen[avp.Type]
+		seen[avp.Type]++
+		switch {
+		case len(positions) == 0:
+			keys[avp] = len(*ref)
+		case n < len(positions):
+			keys[avp] = positions[n]
+		default:
+			keys[avp] = positions[len(positions)-1]
+		}
+	}
+
+	sort.SliceStable(*a, func(i, j int) bool {
+		return keys[(*a)[i]] < keys[(*a)[j]]
+	})
+}
+
 func (a Attributes) encodeTo(b []byte) {
 	for _, attr := range a {
-		if attr.Type < 0 || 255 < attr.Type || len(attr.Attribute) > 253 {
-			continue
+		b = attr.encodeTo(b)
+	}
+}
+
+// encodeTo writes avp in wire format to the start of b, and returns the rest
+// of b. Attributes that cannot be encoded are skipped.
+func (avp *AVP) encodeTo(b []byte) []byte {
+	if avp.Type < 0 || 255 < avp.Type {
+		return b
+	}
+	value := avp.Attribute
+	if len(value) > 253 && !isConcatType(avp.Type) {
+		return b
+	}
+	for {
+		n := len(value)
+		if n > 253 {
+			n = 253
 		}
-		size := 1 + 1 + len(attr.Attribute)
-		b[0] = byte(attr.Type)
+		size := 1 + 1 + n
+		b[0] = byte(avp.Type)
 		b[1] = byte(size)
-		copy(b[2:], attr.Attribute)
+		copy(b[2:], value[:n])
 		b = b[size:]
+		value = value[n:]
+		if len(value) == 0 {
+			return b
+		}
 	}
 }
 
 // AttributesEncodedLen returns the encoded length of all attributes in a. An error is
 // returned if any attribute in a exceeds the permitted size.
+//
+// Attributes of a type that was set using SetConcatType may exceed 253 bytes;
+// they are encoded as multiple attributes.
 func AttributesEncodedLen(a Attributes) (int, error) {
 	var n int
 	for _, attr := range a {
-		if attr.Type < 0 || 255 < attr.Type {
-			continue
-		}
-		if len(attr.Attribute) > 253 {
-			return 0, errors.New("radius: attribute too large")
+		size, err := attr.encodedLen()
+		if err != nil {
+			return 0, err
 		}
-		n += 1 + 1 + len(attr.Attribute)
+		n += size
 	}
 	return n, nil
 }
+
+// encodedLen returns the encoded length of avp, or an error if it exceeds
+// the permitted size.
+func (avp *AVP) encodedLen() (int, error) {
+	if avp.Type < 0 || 255 < avp.Type {
+		return 0, nil
+	}
+	if len(avp.Attribute) <= 253 {
+		return 1 + 1 + len(avp.Attribute), nil
+	}
+	if !isConcatType(avp.Type) {
+		return 0, errors.New("radius: attribute too large")
+	}
+	fragments := (len(avp.Attribute) + 252) / 253
+	return 2*fragments + len(avp.Attribute), nil
+}
+
+// AppendTo appends the wire encoding of a to dst and returns the extended
+// buffer. Attributes are encoded like they are in a packet, without a packet
+// header. An error is returned if any attribute in a exceeds the permitted
+// size.
+func (a Attributes) AppendTo(dst []byte) ([]byte, error) {
+	n, err := AttributesEncodedLen(a)
+	if err != nil {
+		return dst, err
+	}
+	if cap(dst)-len(dst) < n {
+		grown := make([]byte, len(dst), len(dst)+n)
+		copy(grown, dst)
+		dst = grown
+	}
+	b := dst[len(dst) : len(dst)+n]
+	a.encodeTo(b)
+	return dst[:len(dst)+n], nil
+}
+
+// Bytes returns the wire encoding of a, as per AppendTo.
+func (a Attributes) Bytes() ([]byte, error) {
+	return a.AppendTo(nil)
+}
+
+// RemainingWire returns the number of attribute value bytes that can still be
+// added to a without its encoded length exceeding max bytes. The result
+// accounts for the two byte header of each attribute that would be needed to
+// carry the values (e.g. when splitting an EAP-Message across multiple
+// attributes of up to 253 bytes).
+//
+// To fill a packet, max should exclude the 20 byte packet header (i.e.
+// MaxPacketLength-20), along with space for any attributes that will be added
+// later, such as Message-Authenticator.
+//
+// 0 is returned if a already exceeds max, or if it cannot be encoded.
+func (a *Attributes) RemainingWire(max int) int {
+	n, err := AttributesEncodedLen(*a)
+	if err != nil || n >= max {
+		return 0
+	}
+	remaining := max - n
+	values := (remaining / 255) * 253
+	if partial := remaining % 255; partial > 2 {
+		values += partial - 2
+	}
+	return values
+}
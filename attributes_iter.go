@@ -0,0 +1,47 @@
+//go:build go1.23
+
+package radius
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the attributes in a, in wire order.
+func (a *Attributes) All() iter.Seq2[Type, Attribute] {
+	return func(yield func(Type, Attribute) bool) {
+		for _, avp := range *a {
+			if !yield(avp.Type, avp.Attribute) {
+				return
+			}
+		}
+	}
+}
+
+// Types returns an iterator over the distinct attribute types in a, in the
+// order in which they first appear.
+func (a *Attributes) Types() iter.Seq[Type] {
+	return func(yield func(Type) bool) {
+		seen := make(map[Type]struct{})
+		for _, avp := range *a {
+			if _, ok := seen[avp.Type]; ok {
+				continue
+			}
+			seen[avp.Type] = struct{}{}
+			if !yield(avp.Type) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the Attributes of Type key in a, in wire
+// order.
+func (a *Attributes) Values(key Type) iter.Seq[Attribute] {
+	return func(yield func(Attribute) bool) {
+		for _, avp := range *a {
+			if avp.Type == key && !yield(avp.Attribute) {
+				return
+			}
+		}
+	}
+}
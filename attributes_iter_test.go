@@ -0,0 +1,45 @@
+//go:build go1.23
+
+package radius
+
+import (
+	"testing"
+)
+
+func TestAttributes_iterators(t *testing.T) {
+	var a Attributes
+	a.Add(1, Attribute(`bob`))
+	a.Add(33, Attribute(`ps1`))
+	a.Add(4, Attribute{10, 0, 0, 1})
+	a.Add(33, Attribute(`ps2`))
+
+	var types []Type
+	var values []string
+	for typ, attr := range a.All() {
+		types = append(types, typ)
+		values = append(values, string(attr))
+	}
+	if len(types) != 4 || types[0] != 1 || types[1] != 33 || types[2] != 4 || types[3] != 33 {
+		t.Fatalf("All: got types %v", types)
+	}
+	if values[1] != "ps1" || values[3] != "ps2" {
+		t.Fatalf("All: got values %q", values)
+	}
+
+	types = types[:0]
+	for typ := range a.Types() {
+		types = append(types, typ)
+	}
+	if len(types) != 3 || types[0] != 1 || types[1] != 33 || types[2] != 4 {
+		t.Fatalf("Types: got %v", types)
+	}
+
+	values = values[:0]
+	for attr := range a.Values(33) {
+		values = append(values, string(attr))
+		break
+	}
+	if len(values) != 1 || values[0] != "ps1" {
+		t.Fatalf("Values: got %q", values)
+	}
+}
@@ -128,3 +128,561 @@ func TestAttributesSkipInvalid(t *testing.T) {
 		attrs.encodeTo(b)
 	}
 }
+
+func TestAttributes_GetAllWithPositions(t *testing.T) {
+	var a Attributes
+	a.Add(33, []byte(`state1`))
+	a.Add(26, []byte(`vsa`))
+	a.Add(33, []byte(`state2`))
+
+	positions := a.GetAllWithPositions(33)
+	if len(positions) != 2 {
+		t.Fatalf("got %d positions; expecting 2", len(positions))
+	}
+	if positions[0].Index != 0 || !bytes.Equal(positions[0].Value, []byte(`state1`)) {
+		t.Fatalf("unexpected first position %+v", positions[0])
+	}
+	if positions[1].Index != 2 || !bytes.Equal(positions[1].Value, []byte(`state2`)) {
+		t.Fatalf("unexpected second position %+v", positions[1])
+	}
+
+	if positions := a.GetAllWithPositions(1); positions != nil {
+		t.Fatalf("got %+v; expecting nil", positions)
+	}
+}
+
+func TestAttributes_flags(t *testing.T) {
+	var a Attributes
+	if _, ok := a.GetFlags(10); ok {
+		t.Fatal("expecting no flags")
+	}
+
+	a.SetFlag(10, 0x01|0x04, true)
+	if flags, ok := a.GetFlags(10); !ok || flags != 0x05 {
+		t.Fatalf("got %#x, %v; expecting 0x05, true", flags, ok)
+	}
+	if !a.HasFlag(10, 0x04) || a.HasFlag(10, 0x02) {
+		t.Fatal("unexpected HasFlag result")
+	}
+
+	a.SetFlag(10, 0x01, false)
+	if flags, _ := a.GetFlags(10); flags != 0x04 {
+		t.Fatalf("got %#x; expecting 0x04", flags)
+	}
+	if len(a) != 1 {
+		t.Fatalf("got %d attributes; expecting 1", len(a))
+	}
+
+	a.Set(11, []byte{0x01})
+	if _, ok := a.GetFlags(11); ok {
+		t.Fatal("expecting malformed flags to be rejected")
+	}
+}
+
+func TestAttributes_PreferredTunnel(t *testing.T) {
+	var a Attributes
+	if _, ok := a.PreferredTunnel(); ok {
+		t.Fatal("expecting no preferred tunnel")
+	}
+
+	a.Add(83, Attribute{0x01, 0x00, 0x00, 0x05})
+	a.Add(83, Attribute{0x02, 0x00, 0x00, 0x01})
+	a.Add(83, Attribute{0x03, 0x00, 0x00, 0x01})
+	a.Add(83, Attribute{0x04, 0x00})
+
+	tag, ok := a.PreferredTunnel()
+	if !ok || tag != 0x02 {
+		t.Fatalf("got %d, %v; expecting 2, true", tag, ok)
+	}
+}
+
+func TestAttributes_ReorderLike(t *testing.T) {
+	var ref Attributes
+	ref.Add(1, []byte(`user`))
+	ref.Add(33, []byte(`ps1`))
+	ref.Add(4, []byte(`nas`))
+	ref.Add(33, []byte(`ps2`))
+
+	var a Attributes
+	a.Add(26, []byte(`new1`))
+	a.Add(4, []byte(`nas`))
+	a.Add(33, []byte(`ps1`))
+	a.Add(33, []byte(`ps2`))
+	a.Add(33, []byte(`ps3`))
+	a.Add(1, []byte(`user`))
+	a.Add(25, []byte(`new2`))
+
+	a.ReorderLike(&ref)
+
+	expected := []string{`user`, `ps1`, `nas`, `ps2`, `ps3`, `new1`, `new2`}
+	if len(a) != len(expected) {
+		t.Fatalf("got %d attributes; expecting %d", len(a), len(expected))
+	}
+	for i, e := range expected {
+		if string(a[i].Attribute) != e {
+			t.Fatalf("attribute %d: got %q; expecting %q", i, a[i].Attribute, e)
+		}
+	}
+}
+
+func TestAttributes_RemainingWire(t *testing.T) {
+	var a Attributes
+	a.Add(1, make(Attribute, 8)) // 10 bytes encoded
+
+	tests := []struct {
+		Max      int
+		Expected int
+	}{
+		{5, 0},
+		{10, 0},
+		{12, 0},
+		{13, 1},
+		{10 + 255, 253},
+		{10 + 255 + 2, 253},
+		{10 + 255 + 3, 254},
+		{MaxPacketLength - 20, 4034},
+	}
+	for _, tt := range tests {
+		if got := a.RemainingWire(tt.Max); got != tt.Expected {
+			t.Errorf("RemainingWire(%d) = %d; expecting %d", tt.Max, got, tt.Expected)
+		}
+	}
+
+	// Filling the remaining space must produce attributes of exactly max bytes.
+	remaining := a.RemainingWire(MaxPacketLength - 20)
+	for remaining > 0 {
+		chunk := remaining
+		if chunk > 253 {
+			chunk = 253
+		}
+		a.Add(79, make(Attribute, chunk))
+		remaining -= chunk
+	}
+	if n, _ := AttributesEncodedLen(a); n != MaxPacketLength-20 {
+		t.Fatalf("got encoded length %d; expecting %d", n, MaxPacketLength-20)
+	}
+}
+
+func TestAttributes_GetAllUint32(t *testing.T) {
+	var a Attributes
+	a.Add(64, NewInteger(3))
+	a.Add(1, Attribute(`bob`))
+	a.Add(64, Attribute{0x01})
+	a.Add(64, NewInteger(13))
+
+	values := a.GetAllUint32(64)
+	if len(values) != 2 || values[0] != 3 || values[1] != 13 {
+		t.Fatalf("got %v; expecting [3 13]", values)
+	}
+	if values := a.GetAllUint32(2); values != nil {
+		t.Fatalf("got %v; expecting nil", values)
+	}
+}
+
+func TestParseAttributesFiltered(t *testing.T) {
+	b := []byte("\x01\x05bob\x1a\x07\x00\x00\x00\x09x\x21\x04ps\x1a\x06\x00\x00\x00\x01")
+	allow := func(typ Type) bool {
+		return typ != 26
+	}
+
+	attrs, dropped, err := ParseAttributesFiltered(b, allow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 2 || attrs[0].Type != 1 || attrs[1].Type != 33 {
+		t.Fatalf("got %v; expecting User-Name and Proxy-State", attrs)
+	}
+	if len(dropped) != 2 || dropped[0] != 26 || dropped[1] != 26 {
+		t.Fatalf("got dropped %v; expecting [26 26]", dropped)
+	}
+
+	if _, _, err := ParseAttributesFiltered([]byte("\x01\x05bob\x1a\x09\x00"), allow); err == nil {
+		t.Fatal("expecting dropped attributes to be validated")
+	}
+}
+
+func TestAttributes_Clone(t *testing.T) {
+	var a Attributes
+	a.Add(1, Attribute(`bob`))
+	a.Add(33, Attribute(`ps`))
+
+	clone := a.Clone()
+	clone[0].Attribute[0] = 'B'
+	clone.Add(4, Attribute{10, 0, 0, 1})
+	clone[1].Type = 34
+
+	if len(a) != 2 || string(a[0].Attribute) != "bob" || a[1].Type != 33 {
+		t.Fatalf("original modified through clone: %v", a)
+	}
+
+	var empty Attributes
+	if empty.Clone() != nil {
+		t.Fatal("expecting clone of nil Attributes to be nil")
+	}
+}
+
+func TestAttributes_InsertAt(t *testing.T) {
+	var a Attributes
+	a.Add(1, []byte(`user`))
+	a.Add(4, []byte(`nas`))
+
+	a.InsertAt(0, 33, []byte(`ps`))
+	a.InsertAt(2, 80, []byte(`ma`))
+	a.InsertAt(len(a), 26, []byte(`vsa`))
+
+	expected := []string{`ps`, `user`, `ma`, `nas`, `vsa`}
+	if len(a) != len(expected) {
+		t.Fatalf("got %d attributes; expecting %d", len(a), len(expected))
+	}
+	for i, e := range expected {
+		if string(a[i].Attribute) != e {
+			t.Fatalf("attribute %d: got %q; expecting %q", i, a[i].Attribute, e)
+		}
+	}
+
+	if i := a.IndexOf(80, 0); i != 2 {
+		t.Fatalf("got index %d; expecting 2", i)
+	}
+	if i := a.IndexOf(80, 1); i != -1 {
+		t.Fatalf("got index %d; expecting -1", i)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expecting panic")
+		}
+	}()
+	a.InsertAt(len(a)+1, 1, nil)
+}
+
+func TestAttributes_DelNth(t *testing.T) {
+	var a Attributes
+	a.Add(33, []byte(`ps1`))
+	a.Add(1, []byte(`user`))
+	a.Add(33, []byte(`ps2`))
+	a.Add(33, []byte(`ps3`))
+
+	if !a.DelNth(33, -1) {
+		t.Fatal("expecting last Proxy-State to be removed")
+	}
+	if !a.DelNth(33, 0) {
+		t.Fatal("expecting first Proxy-State to be removed")
+	}
+	if a.DelNth(33, 1) || a.DelNth(33, -2) {
+		t.Fatal("expecting out of range removal to fail")
+	}
+	if len(a) != 2 || string(a[0].Attribute) != `user` || string(a[1].Attribute) != `ps2` {
+		t.Fatalf("unexpected attributes %v", a)
+	}
+
+	if a.DelValue(33, []byte(`ps1`)) {
+		t.Fatal("expecting missing value removal to fail")
+	}
+	if !a.DelValue(33, []byte(`ps2`)) {
+		t.Fatal("expecting value to be removed")
+	}
+	if len(a) != 1 || string(a[0].Attribute) != `user` {
+		t.Fatalf("unexpected attributes %v", a)
+	}
+}
+
+func TestAttributes_SetNth(t *testing.T) {
+	var a Attributes
+	a.Add(18, []byte(`msg1`))
+	a.Add(1, []byte(`user`))
+	a.Add(18, []byte(`msg2`))
+	a.Add(18, []byte(`msg3`))
+
+	if !a.SetNth(18, 1, []byte(`new2`)) || !a.SetNth(18, -1, []byte(`new3`)) {
+		t.Fatal("expecting replacement to succeed")
+	}
+	if a.SetNth(18, 3, nil) || a.SetNth(1, -2, nil) {
+		t.Fatal("expecting out of range replacement to fail")
+	}
+
+	expected := []string{`msg1`, `user`, `new2`, `new3`}
+	for i, e := range expected {
+		if string(a[i].Attribute) != e {
+			t.Fatalf("attribute %d: got %q; expecting %q", i, a[i].Attribute, e)
+		}
+	}
+}
+
+func TestAttributes_tagged(t *testing.T) {
+	var a Attributes
+	a.Add(64, Attribute{0x01, 0x00, 0x00, 0x03}) // Tunnel-Type:1 = L2TP
+	a.Add(64, Attribute{0x02, 0x00, 0x00, 0x0d}) // Tunnel-Type:2 = VLAN
+	a.Add(1, []byte("\x01user"))
+	a.Add(81, []byte("\x02vlan10")) // Tunnel-Private-Group-ID:2
+	a.Add(81, []byte("group"))      // untagged
+
+	if attr, ok := a.TaggedLookup(64, 2); !ok || attr[3] != 0x0d {
+		t.Fatalf("got %x, %v; expecting VLAN tunnel type", attr, ok)
+	}
+	if _, ok := a.TaggedLookup(64, 3); ok {
+		t.Fatal("expecting missing tag lookup to fail")
+	}
+	if attr, ok := a.TaggedLookup(81, 0); !ok || string(attr) != "group" {
+		t.Fatalf("got %q, %v; expecting untagged group", attr, ok)
+	}
+
+	groups := a.GroupByTag(64, 81)
+	if len(groups) != 3 || len(groups[1]) != 1 || len(groups[2]) != 2 || len(groups[0]) != 1 {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+	if groups[2][0].Type != 64 || groups[2][1].Type != 81 {
+		t.Fatalf("unexpected group order %v", groups[2])
+	}
+}
+
+func TestAttributes_concat(t *testing.T) {
+	SetConcatType(79, true)
+	defer SetConcatType(79, false)
+
+	eap := bytes.Repeat([]byte{0x01}, 600)
+	var a Attributes
+	a.Add(79, eap)
+	a.Add(79, []byte(`next`))
+	a.Add(1, []byte(`user`))
+
+	n, err := AttributesEncodedLen(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (2 + 253) + (2 + 253) + (2 + 94) + (2 + 4) + (2 + 4); n != expected {
+		t.Fatalf("got length %d; expecting %d", n, expected)
+	}
+	b := make([]byte, n)
+	a.encodeTo(b)
+	if b[0] != 79 || b[1] != 255 || b[255] != 79 || b[256] != 255 || b[510] != 79 || b[511] != 96 {
+		t.Fatal("unexpected fragment headers")
+	}
+
+	parsed, err := ParseAttributes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 2 {
+		t.Fatalf("got %d attributes; expecting 2", len(parsed))
+	}
+	if expected := append(append([]byte(nil), eap...), "next"...); !bytes.Equal(parsed[0].Attribute, expected) {
+		t.Fatal("expecting EAP-Message fragments to be joined")
+	}
+
+	SetConcatType(79, false)
+	if _, err := AttributesEncodedLen(a); err == nil {
+		t.Fatal("expecting attribute too large error")
+	}
+	if parsed, _ := ParseAttributes(b); len(parsed) != 5 {
+		t.Fatalf("got %d attributes; expecting 5", len(parsed))
+	}
+}
+
+func TestParseAttributesWith(t *testing.T) {
+	b := []byte{0x00, 0x03, 'a', 0x01, 0x02, 0x01, 0x03, 'b', 0x01}
+
+	if _, err := ParseAttributesWith(b, ParseOptions{}); err == nil {
+		t.Fatal("expecting trailing data error")
+	}
+
+	attrs, err := ParseAttributesWith(b, ParseOptions{IgnoreTrailingData: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 3 || attrs[0].Type != 0 || attrs[1].Attribute != nil || string(attrs[2].Attribute) != "b" {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+
+	if _, err := ParseAttributesWith(b, ParseOptions{IgnoreTrailingData: true, RejectTypeZero: true}); err == nil {
+		t.Fatal("expecting type 0 error")
+	}
+	if _, err := ParseAttributesWith(b[3:], ParseOptions{IgnoreTrailingData: true, RejectEmptyValues: true}); err == nil {
+		t.Fatal("expecting empty User-Name error")
+	} else if lengthErr, ok := err.(*InvalidAttributeLengthError); !ok || lengthErr.Type != 1 {
+		t.Fatalf("got %v; expecting *InvalidAttributeLengthError for User-Name", err)
+	}
+	if _, err := ParseAttributesWith(b, ParseOptions{IgnoreTrailingData: true, MaxAttributes: 2}); err == nil {
+		t.Fatal("expecting too many attributes error")
+	} else if limitErr, ok := err.(*LimitExceededError); !ok || limitErr.Max != 2 {
+		t.Fatalf("got %v; expecting *LimitExceededError", err)
+	}
+	if _, err := ParseAttributesWith(b, ParseOptions{IgnoreTrailingData: true, MaxAttributes: 3}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseAttributesWith_maxValueBytes(t *testing.T) {
+	b := []byte{0x01, 0x05, 'a', 'b', 'c', 0x02, 0x04, 'd', 'e'}
+
+	if _, err := ParseAttributesWith(b, ParseOptions{MaxValueBytes: 5}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ParseAttributesWith(b, ParseOptions{MaxValueBytes: 4})
+	if limitErr, ok := err.(*LimitExceededError); !ok || limitErr.Limit != "attribute value size" {
+		t.Fatalf("got %v; expecting *LimitExceededError", err)
+	}
+	if expected := "radius: attribute value size limit of 4 exceeded"; err.Error() != expected {
+		t.Fatalf("got %q; expecting %q", err, expected)
+	}
+}
+
+func TestParseAttributesWith_noCopy(t *testing.T) {
+	b := []byte{0x01, 0x05, 'a', 'b', 'c', 0x02, 0x04, 'd', 'e'}
+
+	attrs, err := ParseAttributesWith(b, ParseOptions{NoCopy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[2] = 'x'
+	if v := string(attrs.Get(1)); v != "xbc" {
+		t.Fatalf("got %q; expecting value to alias input buffer", v)
+	}
+
+	extended := append(attrs.Get(1), 'z')
+	if extended[3] != 'z' || b[5] != 0x02 {
+		t.Fatal("expecting append to not overwrite input buffer")
+	}
+
+	copied, err := ParseAttributesWith(b, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[2] = 'y'
+	if v := string(copied.Get(1)); v != "xbc" {
+		t.Fatalf("got %q; expecting copied value", v)
+	}
+}
+
+func TestAttributes_Merge(t *testing.T) {
+	base := func() Attributes {
+		var a Attributes
+		a.Add(6, []byte(`service`))
+		a.Add(18, []byte(`welcome`))
+		a.Add(27, []byte(`timeout`))
+		a.Add(18, []byte(`banner`))
+		return a
+	}
+	var user Attributes
+	user.Add(25, []byte(`class`))
+	user.Add(18, []byte(`hello`))
+	user.Add(18, []byte(`user`))
+
+	tests := []struct {
+		Overwrite bool
+		Expected  []string
+	}{
+		{false, []string{`service`, `welcome`, `timeout`, `banner`, `class`, `hello`, `user`}},
+		{true, []string{`service`, `hello`, `user`, `timeout`, `class`}},
+	}
+	for _, tt := range tests {
+		a := base()
+		a.Merge(&user, tt.Overwrite)
+		if len(a) != len(tt.Expected) {
+			t.Fatalf("overwrite %v: got %d attributes; expecting %d", tt.Overwrite, len(a), len(tt.Expected))
+		}
+		for i, e := range tt.Expected {
+			if string(a[i].Attribute) != e {
+				t.Fatalf("overwrite %v: attribute %d: got %q; expecting %q", tt.Overwrite, i, a[i].Attribute, e)
+			}
+		}
+	}
+	if len(user) != 3 {
+		t.Fatal("expecting other to be left unchanged")
+	}
+}
+
+func TestAttributes_FilterMap(t *testing.T) {
+	var a Attributes
+	a.Add(1, []byte(`bob@example.com`))
+	a.Add(26, []byte(`vsa`))
+	a.Add(4, []byte(`nas`))
+
+	filtered := a.Filter(func(key Type, value Attribute) bool {
+		return key != 26
+	})
+	if len(filtered) != 2 || filtered[0].Type != 1 || filtered[1].Type != 4 {
+		t.Fatalf("unexpected filtered attributes %v", filtered)
+	}
+
+	mapped := a.Map(func(key Type, value Attribute) (Type, Attribute, bool) {
+		switch key {
+		case 1:
+			return key, bytes.TrimSuffix(value, []byte(`@example.com`)), true
+		case 26:
+			return key, nil, false
+		}
+		return key, value, true
+	})
+	if len(mapped) != 2 || string(mapped[0].Attribute) != `bob` || string(mapped[1].Attribute) != `nas` {
+		t.Fatalf("unexpected mapped attributes %v", mapped)
+	}
+
+	if len(a) != 3 || string(a[0].Attribute) != `bob@example.com` {
+		t.Fatal("expecting original attributes to be unchanged")
+	}
+}
+
+func TestAttributes_repeatedOrder(t *testing.T) {
+	var a Attributes
+	a.Add(33, []byte(`ps1`))
+	a.Add(1, []byte(`user`))
+	a.Add(33, []byte(`ps2`))
+	a.Add(18, []byte(`msg`))
+	a.Add(33, []byte(`ps3`))
+
+	a.Set(33, []byte(`ps`))
+	a.InsertAt(0, 80, make([]byte, 16))
+	a.Del(18)
+	a.InsertAt(2, 18, []byte(`msg`))
+
+	n, err := AttributesEncodedLen(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, n)
+	a.encodeTo(b)
+	parsed, err := ParseAttributes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Type{80, 33, 18, 1}
+	if len(parsed) != len(expected) {
+		t.Fatalf("got %d attributes; expecting %d", len(parsed), len(expected))
+	}
+	for i, e := range expected {
+		if parsed[i].Type != e {
+			t.Fatalf("attribute %d: got type %d; expecting %d", i, parsed[i].Type, e)
+		}
+	}
+	if string(parsed[1].Attribute) != `ps` {
+		t.Fatalf("got %q; expecting Set to replace the first instance in place", parsed[1].Attribute)
+	}
+}
+
+func TestAttributes_AppendTo(t *testing.T) {
+	var a Attributes
+	a.Add(1, []byte(`bob`))
+	a.Add(24, nil)
+
+	b, err := a.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte("\x01\x05bob\x18\x02"); !bytes.Equal(b, expected) {
+		t.Fatalf("got %x; expecting %x", b, expected)
+	}
+
+	b, err = a.AppendTo([]byte{0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte("\xff\x01\x05bob\x18\x02"); !bytes.Equal(b, expected) {
+		t.Fatalf("got %x; expecting %x", b, expected)
+	}
+
+	a.Add(25, make([]byte, 254))
+	if b, err := a.AppendTo([]byte{0xff}); err == nil || len(b) != 1 {
+		t.Fatalf("got %x, %v; expecting attribute too large error", b, err)
+	}
+}
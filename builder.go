@@ -0,0 +1,115 @@
+package radius
+
+import (
+	"net"
+)
+
+const (
+	// RFC 2865 attribute types used by Builder.
+	typeUserName      Type = 1
+	typeNASIPAddress  Type = 4
+	typeNASPort       Type = 5
+	typeNASIdentifier Type = 32
+)
+
+// Builder incrementally builds a packet. Each With method returns the builder
+// so that calls can be chained:
+//
+//	packet, err := radius.NewRequest(radius.CodeAccessRequest, secret).
+//		WithUserName("bob").
+//		WithUserPassword("hunter2").
+//		WithNASIP(net.IPv4(10, 0, 0, 1)).
+//		Packet()
+//
+// If a With method is given an invalid value, the attribute is not added and
+// the error is returned by Packet; subsequent calls have no effect.
+type Builder struct {
+	packet *Packet
+	err    error
+}
+
+// NewRequest returns a Builder for a new packet with the given code and
+// secret. The packet's Identifier and Authenticator are filled with random
+// values, as per New.
+func NewRequest(code Code, secret []byte) *Builder {
+	return &Builder{
+		packet: New(code, secret),
+	}
+}
+
+func (b *Builder) add(key Type, value Attribute, err error) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.packet.Add(key, value)
+	return b
+}
+
+// WithAttribute adds an attribute with the given type and wire value.
+func (b *Builder) WithAttribute(key Type, value Attribute) *Builder {
+	return b.add(key, value, nil)
+}
+
+// WithUserName adds a User-Name attribute.
+func (b *Builder) WithUserName(name string) *Builder {
+	attr, err := NewString(name)
+	return b.add(typeUserName, attr, err)
+}
+
+// WithUserPassword sets the plaintext User-Password, which is encrypted when
+// the packet is encoded (see Packet.SetUserPassword).
+func (b *Builder) WithUserPassword(password string) *Builder {
+	if b.err == nil {
+		b.packet.SetUserPassword([]byte(password))
+	}
+	return b
+}
+
+// WithNASIP adds a NAS-IP-Address attribute. ip must be an IPv4 address.
+func (b *Builder) WithNASIP(ip net.IP) *Builder {
+	attr, err := NewIPAddr(ip)
+	return b.add(typeNASIPAddress, attr, err)
+}
+
+// WithNASPort adds a NAS-Port attribute.
+func (b *Builder) WithNASPort(port uint32) *Builder {
+	return b.add(typeNASPort, NewInteger(port), nil)
+}
+
+// WithNASIdentifier adds a NAS-Identifier attribute.
+func (b *Builder) WithNASIdentifier(identifier string) *Builder {
+	attr, err := NewString(identifier)
+	return b.add(typeNASIdentifier, attr, err)
+}
+
+// WithVendor adds a Vendor-Specific attribute containing a single
+// sub-attribute (see Attributes.AddVendor).
+func (b *Builder) WithVendor(vendorID uint32, subType byte, value Attribute) *Builder {
+	if b.err == nil {
+		b.err = b.packet.AddVendor(vendorID, subType, value)
+	}
+	return b
+}
+
+// WithMessageAuthenticator sets the packet's Message-Authenticator policy,
+// which adds a Message-Authenticator attribute when the packet is encoded.
+func (b *Builder) WithMessageAuthenticator() *Builder {
+	if b.err == nil {
+		b.packet.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+	}
+	return b
+}
+
+// Packet returns the built packet, or the first error encountered while
+// building it. The returned packet must be encoded (e.g. by Client.Exchange)
+// before it can be sent.
+func (b *Builder) Packet() (*Packet, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.packet, nil
+}
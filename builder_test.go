@@ -0,0 +1,60 @@
+package radius
+
+import (
+	"net"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	secret := []byte(`12345`)
+	p, err := NewRequest(CodeAccessRequest, secret).
+		WithUserName("bob").
+		WithUserPassword("hunter2").
+		WithNASIP(net.IPv4(10, 0, 0, 1)).
+		WithNASPort(7).
+		WithNASIdentifier("nas1").
+		WithVendor(9, 1, Attribute("shell:priv-lvl=15")).
+		WithAttribute(30, Attribute("called")).
+		WithMessageAuthenticator().
+		Packet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, err := Parse(b, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsValidMessageAuthenticator(b, nil, secret, nil) {
+		t.Fatal("expecting valid Message-Authenticator")
+	}
+	if v := String(received.Get(typeUserName)); v != "bob" {
+		t.Fatalf("got User-Name %q", v)
+	}
+	if v, err := received.UserPassword(); err != nil || string(v) != "hunter2" {
+		t.Fatalf("got User-Password %q, %v", v, err)
+	}
+	if v, _ := IPAddr(received.Get(typeNASIPAddress)); !v.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Fatalf("got NAS-IP-Address %v", v)
+	}
+	if v := received.GetVendor(9, 1); string(v) != "shell:priv-lvl=15" {
+		t.Fatalf("got Cisco-AVPair %q", v)
+	}
+	if v := String(received.Get(30)); v != "called" {
+		t.Fatalf("got Called-Station-Id %q", v)
+	}
+}
+
+func TestBuilder_error(t *testing.T) {
+	_, err := NewRequest(CodeAccessRequest, []byte(`12345`)).
+		WithNASIP(net.ParseIP("::1")).
+		WithUserName("bob").
+		Packet()
+	if err == nil {
+		t.Fatal("expecting invalid NAS-IP-Address error")
+	}
+}
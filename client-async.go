@@ -0,0 +1,290 @@
+package radius
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of an asynchronous exchange.
+type Result struct {
+	// Packet is the response, if one was received.
+	Packet *Packet
+	// Err is the error that ended the exchange, if any.
+	Err error
+}
+
+// exchangeAsync runs an exchange with e on its own goroutine, for the
+// exchanges that cannot be completed by the read loop of a pooled connection.
+func exchangeAsync(ctx context.Context, e Exchanger, packet *Packet, addr string) <-chan Result {
+	results := make(chan Result, 1)
+	go func() {
+		response, err := e.Exchange(ctx, packet, addr)
+		results <- Result{Packet: response, Err: err}
+	}()
+	return results
+}
+
+// asyncExchange is an exchange over a pooled connection that is completed by
+// the connection's read loop, by retransmission timers, and by the
+// cancellation of its context, rather than by a goroutine waiting for its
+// response.
+type asyncExchange struct {
+	pool      *connPool
+	client    *Client
+	addr      string
+	closeIdle bool // close the connection once it has no exchange in progress
+	results   chan Result
+
+	// Set by start before the exchange is ready, and not modified
+	// afterwards.
+	ctx         context.Context
+	pc          *pooledConn
+	id          byte
+	request     Packet
+	wire        []byte
+	span        *exchangeSpan
+	sent        time.Time
+	retransmits io.Writer
+
+	packetErrorCount int // only used by the read loop
+
+	mu       sync.Mutex
+	ready    bool // the request is being sent, and responses are accepted
+	done     bool
+	err      error // of an exchange that was done before it was ready
+	schedule *retrySchedule
+	timer    *time.Timer
+	stop     func() // stops watching ctx
+}
+
+// exchangeAsync sends packet over a pooled connection to addr, in the same
+// way as exchange, and returns a channel on which the Result is delivered.
+// The caller is only blocked until the request has been written, which
+// includes dialing a connection to addr if none has a free Identifier.
+func (p *connPool) exchangeAsync(ctx context.Context, client *Client, packet *Packet, addr string, dial func(context.Context) (net.Conn, error), stream, closeIdle bool) <-chan Result {
+	e := &asyncExchange{
+		pool:      p,
+		client:    client,
+		addr:      addr,
+		closeIdle: closeIdle,
+		results:   make(chan Result, 1),
+	}
+	e.start(ctx, packet, dial, stream)
+	return e.results
+}
+
+func (e *asyncExchange) start(ctx context.Context, packet *Packet, dial func(context.Context) (net.Conn, error), stream bool) {
+	client := e.client
+	request := *client.prepare(packet)
+	pc, id, err := e.pool.acquire(ctx, e.addr, dial, maxPacketSize(request.MaxPacketSize), stream, e)
+	if err != nil {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		default:
+		}
+		e.results <- Result{Err: err}
+		return
+	}
+	e.ctx, e.pc, e.id = ctx, pc, id
+
+	withSecret, err := client.withSecret(ctx, &request, pc.conn.RemoteAddr())
+	if err != nil {
+		e.complete(nil, err)
+		return
+	}
+	e.request = *withSecret
+	e.request.Identifier = id
+	e.ctx, e.span = client.startSpan(ctx, &e.request, e.addr)
+	wire, err := e.request.Encode()
+	if err != nil {
+		e.complete(nil, err)
+		return
+	}
+	e.wire = wire
+	e.retransmits = e.span.writer(client.retransmitWriter(e.ctx, tapWriter(client.Tap, writerFunc(pc.write), pc.conn.RemoteAddr()), e.addr, e.request.Code))
+	e.sent = client.sent(e.ctx, e.addr, &e.request)
+
+	e.mu.Lock()
+	if e.done {
+		// The connection failed while the request was being prepared.
+		err := e.err
+		e.mu.Unlock()
+		e.complete(nil, err)
+		return
+	}
+	e.ready = true
+	if !stream {
+		e.startRetransmits()
+	}
+	e.stop = afterDone(e.ctx, func() {
+		e.finish(nil, e.ctx.Err())
+	})
+	e.mu.Unlock()
+
+	if _, err := pc.write(wire); err != nil {
+		e.pool.fail(e.addr, pc, err)
+		e.finish(nil, err)
+		return
+	}
+	tapWire(client.Tap, TapOutbound, pc.conn.RemoteAddr(), wire)
+}
+
+// startRetransmits schedules the first retransmission of the request. e.mu
+// must be held.
+func (e *asyncExchange) startRetransmits() {
+	var timeout time.Duration
+	switch {
+	case e.client.RetryPolicy != nil:
+		e.schedule, timeout = e.client.RetryPolicy.schedule(time.Now())
+	case e.client.Retry > 0:
+		timeout = e.client.Retry
+	default:
+		return
+	}
+	e.timer = time.AfterFunc(timeout, e.retransmit)
+}
+
+// retransmit is called by e.timer to retransmit the request.
+func (e *asyncExchange) retransmit() {
+	e.mu.Lock()
+	if e.done {
+		e.mu.Unlock()
+		return
+	}
+	timeout := e.client.Retry
+	if e.schedule != nil {
+		var err error
+		if timeout, err = e.schedule.next(time.Now()); err != nil {
+			e.mu.Unlock()
+			e.finish(nil, err)
+			return
+		}
+	}
+	e.mu.Unlock()
+
+	e.retransmits.Write(e.wire)
+
+	e.mu.Lock()
+	if !e.done {
+		e.timer.Reset(timeout)
+	}
+	e.mu.Unlock()
+}
+
+// receive implements responseHandler.
+func (e *asyncExchange) receive(incoming []byte) {
+	e.mu.Lock()
+	ready := e.ready && !e.done
+	e.mu.Unlock()
+	if !ready {
+		return
+	}
+
+	client := e.client
+	tapWire(client.Tap, TapInbound, e.pc.conn.RemoteAddr(), incoming)
+	received, err := client.verifyResponse(&e.request, e.wire, incoming)
+	if _, ok := err.(*NonAuthenticResponseError); ok && e.pc.isPreviousResponse(e.id, incoming, e.request.Secret) {
+		err = errStrayResponse
+	}
+	if err == errStrayResponse {
+		client.strayResponse(e.ctx, e.addr)
+		return
+	}
+	if _, ok := err.(*ProtocolError); ok {
+		e.finish(nil, err)
+		return
+	}
+	if err != nil {
+		client.packetError(e.ctx, e.addr, err)
+		e.packetErrorCount++
+		if client.MaxPacketErrors > 0 && e.packetErrorCount >= client.MaxPacketErrors {
+			e.finish(nil, err)
+		}
+		return
+	}
+	e.finish(received, nil)
+}
+
+// fail implements responseHandler.
+func (e *asyncExchange) fail(err error) {
+	e.finish(nil, err)
+}
+
+// finish ends the exchange with the given outcome, unless it has already
+// ended. An exchange that is not ready yet is completed by start instead.
+func (e *asyncExchange) finish(response *Packet, err error) {
+	e.mu.Lock()
+	if e.done {
+		e.mu.Unlock()
+		return
+	}
+	e.done = true
+	if !e.ready {
+		e.err = err
+		e.mu.Unlock()
+		return
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.mu.Unlock()
+	e.complete(response, err)
+}
+
+// complete releases the exchange's Identifier, and delivers its Result. It is
+// called once per exchange.
+func (e *asyncExchange) complete(response *Packet, err error) {
+	if e.stop != nil {
+		e.stop()
+	}
+	e.pc.release(e.id, e.wire)
+	if e.closeIdle {
+		e.pool.closeIdle(e.addr, e.pc)
+	}
+	e.span.end(response, err)
+	if !e.sent.IsZero() {
+		e.client.finished(e.ctx, e.addr, e.request.Code, e.sent, response, err)
+	}
+	e.results <- Result{Packet: response, Err: err}
+}
+
+// ExchangeAsync sends the packet to the given server in the same way as
+// Exchange, and returns a channel on which the Result is delivered once the
+// exchange has finished. The channel is buffered, so the result may be
+// ignored. Canceling ctx ends the exchange. ctx must be non-nil.
+//
+// Concurrent asynchronous exchanges with a server share sockets, each of which
+// carries up to 256 requests and is closed once it has no exchange in
+// progress. The responses are dispatched by a single goroutine per socket,
+// and retransmissions by timers, so no goroutine is kept per exchange. The
+// call returns once the request has been written.
+//
+// Exchanges passed through c.Middleware, or sent with a FallbackDelay, run on
+// a goroutine of their own instead.
+func (c *Client) ExchangeAsync(ctx context.Context, packet *Packet, addr string) <-chan Result {
+	if ctx == nil {
+		panic("nil context")
+	}
+	if len(c.Middleware) > 0 || c.FallbackDelay > 0 {
+		return exchangeAsync(ctx, c, packet, addr)
+	}
+	return c.async.exchangeAsync(ctx, c, packet, addr, c.udpDialer(addr), false, true)
+}
+
+// ExchangeAsync sends the packet to the given server in the same way as
+// Exchange, and returns a channel on which the Result is delivered, as
+// Client.ExchangeAsync does. The exchange uses the client's pooled sockets.
+func (p *PooledClient) ExchangeAsync(ctx context.Context, packet *Packet, addr string) <-chan Result {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client := p.client()
+	if len(client.Middleware) > 0 {
+		return exchangeAsync(ctx, p, packet, addr)
+	}
+	return p.pool.exchangeAsync(ctx, client, packet, addr, client.udpDialer(addr), false, false)
+}
@@ -0,0 +1,31 @@
+//go:build !go1.21
+
+package radius
+
+import (
+	"context"
+	"sync"
+)
+
+// afterDone arranges for f to be called, in its own goroutine, once ctx is
+// done. The returned function stops the call if it has not started.
+func afterDone(ctx context.Context, f func()) (stop func()) {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			f()
+		case <-stopped:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopped)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build go1.21
+
+package radius
+
+import (
+	"context"
+)
+
+// afterDone arranges for f to be called, in its own goroutine, once ctx is
+// done. The returned function stops the call if it has not started.
+func afterDone(ctx context.Context, f func()) (stop func()) {
+	stopFunc := context.AfterFunc(ctx, f)
+	return func() {
+		stopFunc()
+	}
+}
@@ -0,0 +1,188 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_ExchangeAsync(t *testing.T) {
+	secret := []byte(`12345`)
+
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.Get(typeUserName) != nil {
+			w.Write(r.Response(CodeAccessAccept))
+		}
+	}), StaticSecretSource(secret))
+	defer server.Close()
+
+	client := &Client{}
+
+	answered := New(CodeAccessRequest, secret)
+	answered.Add(typeUserName, Attribute("bob"))
+	accepted := client.ExchangeAsync(context.Background(), answered, server.Addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ignored := client.ExchangeAsync(ctx, New(CodeAccessRequest, secret), server.Addr)
+
+	select {
+	case result := <-accepted:
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if result.Packet.Code != CodeAccessAccept {
+			t.Fatalf("got code %v; expecting Access-Accept", result.Packet.Code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for result")
+	}
+
+	cancel()
+	select {
+	case result := <-ignored:
+		if result.Err != context.Canceled {
+			t.Fatalf("got %v; expecting context.Canceled", result.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for canceled result")
+	}
+}
+
+func TestClient_ExchangeAsync_noGoroutinePerCall(t *testing.T) {
+	secret := []byte(`12345`)
+
+	// A server that never answers.
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	client := &Client{}
+	before := runtime.NumGoroutine()
+
+	const n = 300
+	results := make([]<-chan Result, n)
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := range results {
+		results[i] = client.ExchangeAsync(ctx, New(CodeAccessRequest, secret), pc.LocalAddr().String())
+	}
+	// Allow for the read loop of each socket.
+	if after := runtime.NumGoroutine(); after-before > 10 {
+		t.Fatalf("%d goroutines started by %d exchanges", after-before, n)
+	}
+
+	cancel()
+	for _, ch := range results {
+		select {
+		case result := <-ch:
+			if result.Err != context.Canceled {
+				t.Fatalf("got %v; expecting context.Canceled", result.Err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for canceled result")
+		}
+	}
+
+	client.async.mu.Lock()
+	conns := len(client.async.conns)
+	client.async.mu.Unlock()
+	if conns != 0 {
+		t.Fatalf("%d addresses with open sockets; expecting idle sockets to be closed", conns)
+	}
+}
+
+func TestClient_ExchangeAsync_retransmit(t *testing.T) {
+	secret := []byte(`12345`)
+
+	var received int32
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if atomic.AddInt32(&received, 1) > 1 {
+			w.Write(r.Response(CodeAccessAccept))
+		}
+	}), StaticSecretSource(secret))
+	defer server.Close()
+
+	client := &Client{
+		RetryPolicy: &RetryPolicy{
+			InitialTimeout: 10 * time.Millisecond,
+			MaxRetries:     3,
+		},
+	}
+	select {
+	case result := <-client.ExchangeAsync(context.Background(), New(CodeAccessRequest, secret), server.Addr):
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for result")
+	}
+
+	// Without responses, the retry policy ends the exchange.
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	select {
+	case result := <-client.ExchangeAsync(context.Background(), New(CodeAccessRequest, secret), pc.LocalAddr().String()):
+		if exhausted, ok := result.Err.(*RetryExhaustedError); !ok || len(exhausted.Attempts) != 4 {
+			t.Fatalf("got %v; expecting RetryExhaustedError after 4 attempts", result.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for result")
+	}
+}
+
+func TestTCPClient_ExchangeAsync(t *testing.T) {
+	secret := []byte(`12345`)
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestStream(t, conn, secret)
+		}
+	}()
+
+	client := &TCPClient{
+		MessageAuthenticator: MessageAuthenticatorAdd,
+	}
+	defer client.Close()
+
+	names := []string{"a", "b", "c", "d", "e"}
+	results := make([]<-chan Result, len(names))
+	for i, name := range names {
+		packet := New(CodeAccessRequest, secret)
+		packet.Add(typeUserName, Attribute(name))
+		results[i] = client.ExchangeAsync(context.Background(), packet, l.Addr().String())
+	}
+	for i, ch := range results {
+		select {
+		case result := <-ch:
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			if got := string(result.Packet.Get(typeUserName)); got != names[i] {
+				t.Fatalf("got response for %q; expecting %q", got, names[i])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for result")
+		}
+	}
+
+	client.Close()
+	if result := <-client.ExchangeAsync(context.Background(), New(CodeAccessRequest, secret), l.Addr().String()); result.Err != ErrClientClosed {
+		t.Fatalf("got %v; expecting ErrClientClosed", result.Err)
+	}
+}
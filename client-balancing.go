@@ -0,0 +1,220 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BalanceStrategy determines how a BalancingClient distributes requests
+// across its servers.
+type BalanceStrategy int
+
+// BalanceStrategy values.
+const (
+	// RoundRobin sends requests to each server in turn.
+	RoundRobin BalanceStrategy = iota
+
+	// Weighted sends requests to each server in proportion to its Weight,
+	// interleaving them smoothly.
+	Weighted
+
+	// LeastOutstanding sends requests to the server with the fewest
+	// exchanges in progress.
+	LeastOutstanding
+)
+
+// BalancedServer is a RADIUS server used by a BalancingClient.
+type BalancedServer struct {
+	// Addr is the address of the server.
+	Addr string
+	// Secret is the secret shared with the server. If nil, the secret of the
+	// request packet is used.
+	Secret []byte
+	// Weight is the relative share of requests sent to the server when the
+	// Weighted strategy is used. Values less than 1 are treated as 1.
+	Weight int
+}
+
+// BalancingClient distributes requests across a pool of RADIUS servers.
+//
+// Servers are tracked in the same way as by FailoverClient: a server is
+// marked dead after MaxTimeouts consecutive timeouts, and is not selected
+// until Probation has elapsed. If a server does not respond, the request is
+// sent to another server that has not yet been tried.
+//
+// Requests are sent with the secret of the selected server, so passwords
+// should be set using Packet.SetUserPassword.
+type BalancingClient struct {
+	// Client is used to exchange packets with the servers. If nil,
+	// DefaultClient is used.
+	Client *Client
+
+	// Servers is the pool of servers.
+	Servers []BalancedServer
+
+	// Strategy determines how servers are selected.
+	Strategy BalanceStrategy
+
+	// Timeout is how long to wait for a response from a single server before
+	// trying another. If zero, the client's RetryPolicy must limit each
+	// exchange.
+	Timeout time.Duration
+
+	// MaxTimeouts is the number of consecutive timeouts after which a server
+	// is marked dead. Defaults to 3.
+	MaxTimeouts int
+
+	// Probation is how long a dead server is not selected. Defaults to 30
+	// seconds.
+	Probation time.Duration
+
+	// Prober, if non-nil, is consulted before a server is selected. Servers
+	// that did not respond to their last Status-Server probe are treated as
+	// dead.
+	Prober *Prober
+
+	health serverHealth
+
+	mu      sync.Mutex
+	next    int
+	current map[string]int // smooth weighted round-robin state
+}
+
+func (c *BalancingClient) maxTimeouts() int {
+	if c.MaxTimeouts > 0 {
+		return c.MaxTimeouts
+	}
+	return 3
+}
+
+func (c *BalancingClient) probation() time.Duration {
+	if c.Probation > 0 {
+		return c.Probation
+	}
+	return 30 * time.Second
+}
+
+// IsDead returns if the server with the given address is currently marked
+// dead, either by the client or by its Prober.
+func (c *BalancingClient) IsDead(addr string) bool {
+	return c.isDead(addr, time.Now())
+}
+
+func (c *BalancingClient) isDead(addr string, now time.Time) bool {
+	if c.Prober != nil && !c.Prober.Alive(addr) {
+		return true
+	}
+	return c.health.isDead(addr, c.probation(), now)
+}
+
+// pick selects the server for the next request among those that have not
+// been tried. Dead servers are only selected if every remaining server is
+// dead. false is returned if every server has been tried.
+func (c *BalancingClient) pick(tried map[string]bool) (BalancedServer, bool) {
+	now := time.Now()
+	var alive, dead []BalancedServer
+	for _, server := range c.Servers {
+		if tried[server.Addr] {
+			continue
+		}
+		if c.isDead(server.Addr, now) {
+			dead = append(dead, server)
+		} else {
+			alive = append(alive, server)
+		}
+	}
+	candidates := alive
+	if len(candidates) == 0 {
+		candidates = dead
+	}
+	if len(candidates) == 0 {
+		return BalancedServer{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.Strategy {
+	case Weighted:
+		if c.current == nil {
+			c.current = make(map[string]int)
+		}
+		var best, total int
+		for i, server := range candidates {
+			weight := server.Weight
+			if weight < 1 {
+				weight = 1
+			}
+			total += weight
+			c.current[server.Addr] += weight
+			if c.current[server.Addr] > c.current[candidates[best].Addr] {
+				best = i
+			}
+		}
+		c.current[candidates[best].Addr] -= total
+		return candidates[best], true
+	case LeastOutstanding:
+		best, bestOutstanding := 0, -1
+		for i := range candidates {
+			j := (c.next + i) % len(candidates)
+			if n := c.health.outstanding(candidates[j].Addr); bestOutstanding == -1 || n < bestOutstanding {
+				best, bestOutstanding = j, n
+			}
+		}
+		c.next++
+		return candidates[best], true
+	default:
+		server := candidates[c.next%len(candidates)]
+		c.next++
+		return server, true
+	}
+}
+
+// Exchange sends the packet to a server selected by the client's strategy and
+// waits for a response. If the server does not respond, the other servers are
+// tried in turn.
+//
+// The error of the last server that was tried is returned if none of them
+// responded.
+func (c *BalancingClient) Exchange(ctx context.Context, packet *Packet) (*Packet, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client := c.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	tried := make(map[string]bool)
+	var lastErr error
+	for {
+		server, ok := c.pick(tried)
+		if !ok {
+			break
+		}
+		tried[server.Addr] = true
+
+		request := *packet
+		if server.Secret != nil {
+			request.Secret = server.Secret
+		}
+
+		c.health.begin(server.Addr)
+		response, err := exchangeWithTimeout(ctx, client, &request, server.Addr, c.Timeout)
+		if ctx.Err() != nil {
+			c.health.end(server.Addr, context.Canceled, c.maxTimeouts(), time.Now())
+			return nil, ctx.Err()
+		}
+		c.health.end(server.Addr, err, c.maxTimeouts(), time.Now())
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return nil, errors.New("radius: no servers")
+	}
+	return nil, lastErr
+}
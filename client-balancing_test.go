@@ -0,0 +1,97 @@
+package radius
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBalancingClient_pick(t *testing.T) {
+	servers := []BalancedServer{
+		{Addr: "a", Weight: 3},
+		{Addr: "b", Weight: 1},
+	}
+
+	tests := []struct {
+		Strategy BalanceStrategy
+		Expected string
+	}{
+		{RoundRobin, "abababab"},
+		{Weighted, "aabaaaba"},
+	}
+	for _, tt := range tests {
+		client := &BalancingClient{
+			Servers:  servers,
+			Strategy: tt.Strategy,
+		}
+		var picked string
+		for i := 0; i < 8; i++ {
+			server, ok := client.pick(nil)
+			if !ok {
+				t.Fatal("expecting server")
+			}
+			picked += server.Addr
+		}
+		if picked != tt.Expected {
+			t.Errorf("strategy %d: got %q; expecting %q", tt.Strategy, picked, tt.Expected)
+		}
+	}
+
+	client := &BalancingClient{
+		Servers:  servers,
+		Strategy: LeastOutstanding,
+	}
+	client.health.begin("a")
+	for i := 0; i < 3; i++ {
+		if server, _ := client.pick(nil); server.Addr != "b" {
+			t.Fatalf("got %q; expecting server with fewest outstanding requests", server.Addr)
+		}
+	}
+
+	if server, _ := client.pick(map[string]bool{"b": true}); server.Addr != "a" {
+		t.Fatalf("got %q; expecting untried server", server.Addr)
+	}
+	if _, ok := client.pick(map[string]bool{"a": true, "b": true}); ok {
+		t.Fatal("expecting no server when all were tried")
+	}
+}
+
+func TestBalancingClient_Exchange(t *testing.T) {
+	secret := []byte(`12345`)
+
+	dead := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+	}), StaticSecretSource(secret))
+	defer dead.Close()
+	var aliveCount int32
+	alive := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		atomic.AddInt32(&aliveCount, 1)
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource(secret))
+	defer alive.Close()
+
+	client := &BalancingClient{
+		Client: &Client{},
+		Servers: []BalancedServer{
+			{Addr: dead.Addr},
+			{Addr: alive.Addr},
+		},
+		Timeout:     50 * time.Millisecond,
+		MaxTimeouts: 1,
+	}
+	for i := 0; i < 4; i++ {
+		resp, err := client.Exchange(context.Background(), New(CodeAccessRequest, secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != CodeAccessAccept {
+			t.Fatalf("got code %v; expecting Access-Accept", resp.Code)
+		}
+	}
+	if !client.IsDead(dead.Addr) {
+		t.Fatal("expecting unresponsive server to be dead")
+	}
+	if n := atomic.LoadInt32(&aliveCount); n != 4 {
+		t.Fatalf("got %d requests to live server; expecting 4", n)
+	}
+}
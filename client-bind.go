@@ -0,0 +1,85 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+)
+
+// SourceOptions control the local address from which a Client sends requests.
+type SourceOptions struct {
+	// IP, if non-nil, is the local IP address to send from.
+	IP net.IP
+
+	// PortMin and PortMax, if non-zero, are the (inclusive) range of local
+	// ports to send from. A free port in the range is selected at random.
+	PortMin, PortMax int
+
+	// Interface, if non-empty, is the name of the network interface to send
+	// from (SO_BINDTODEVICE), e.g. to egress through a particular VRF. It is
+	// only supported on Linux.
+	Interface string
+}
+
+// dial dials addr, applying c.Source to c.Dialer.
+func (c *Client) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.Source == nil {
+		return c.Dialer.DialContext(ctx, network, addr)
+	}
+	source := c.Source
+
+	dialer := c.Dialer
+	if source.Interface != "" {
+		control, err := bindToDevice(source.Interface)
+		if err != nil {
+			return nil, err
+		}
+		dialer.Control = control
+	}
+
+	if source.PortMin <= 0 && source.PortMax <= 0 {
+		if source.IP != nil {
+			dialer.LocalAddr = localAddr(network, source.IP, 0)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	portMin, portMax := source.PortMin, source.PortMax
+	if portMin <= 0 {
+		portMin = 1
+	}
+	if portMax <= 0 || portMax > 65535 {
+		portMax = 65535
+	}
+	if portMin > portMax {
+		return nil, errors.New("radius: invalid source port range")
+	}
+
+	// Try each port in the range once, starting at a random one.
+	n := portMax - portMin + 1
+	start := rand.Intn(n)
+	var lastErr error
+	for i := 0; i < n; i++ {
+		port := portMin + (start+i)%n
+		dialer.LocalAddr = localAddr(network, source.IP, port)
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// localAddr returns the local address for network with the given IP and port.
+func localAddr(network string, ip net.IP, port int) net.Addr {
+	if strings.HasPrefix(network, "tcp") {
+		return &net.TCPAddr{IP: ip, Port: port}
+	}
+	return &net.UDPAddr{IP: ip, Port: port}
+}
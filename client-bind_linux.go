@@ -0,0 +1,20 @@
+package radius
+
+import (
+	"syscall"
+)
+
+// bindToDevice returns a net.Dialer Control function that binds sockets to
+// the network interface with the given name.
+func bindToDevice(iface string) (func(network, address string, c syscall.RawConn) error, error) {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}, nil
+}
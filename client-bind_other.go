@@ -0,0 +1,14 @@
+//go:build !linux
+
+package radius
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindToDevice returns an error, as binding sockets to a network interface is
+// only supported on Linux.
+func bindToDevice(iface string) (func(network, address string, c syscall.RawConn) error, error) {
+	return nil, errors.New("radius: binding to a network interface is not supported on this platform")
+}
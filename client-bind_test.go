@@ -0,0 +1,52 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestClient_Exchange_source(t *testing.T) {
+	secret := []byte(`12345`)
+
+	remotePorts := make(chan int, 1)
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		remotePorts <- r.RemoteAddr.(*net.UDPAddr).Port
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource(secret))
+	defer server.Close()
+
+	// Occupy a port in the range, so that dial has to skip it.
+	busy, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	busyPort := busy.LocalAddr().(*net.UDPAddr).Port
+
+	client := Client{
+		Source: &SourceOptions{
+			IP:      net.IPv4(127, 0, 0, 1),
+			PortMin: busyPort,
+			PortMax: busyPort + 1,
+		},
+	}
+	if runtime.GOOS == "linux" {
+		client.Source.Interface = "lo"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = client.Exchange(ctx, New(CodeAccessRequest, secret), server.Addr)
+	if err != nil {
+		if client.Source.Interface != "" {
+			t.Skipf("cannot bind to interface: %v", err)
+		}
+		t.Fatal(err)
+	}
+	if port := <-remotePorts; port != busyPort+1 {
+		t.Fatalf("got source port %d; expecting %d", port, busyPort+1)
+	}
+}
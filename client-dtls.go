@@ -0,0 +1,109 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// DTLSPort is the port used by RADIUS over DTLS (RFC 7360 section 2.1).
+const DTLSPort = "2083"
+
+// DTLSSecret is the shared secret used by RADIUS over DTLS (RFC 7360 section
+// 2.1).
+var DTLSSecret = []byte("radius/dtls")
+
+// DTLSDialer establishes DTLS sessions for a DTLSClient.
+//
+// This package does not include a DTLS implementation. DialDTLS is expected to
+// be implemented using a third-party DTLS library, which handles the PSK or
+// certificate configuration, session resumption, and path MTU of the
+// session. The returned net.Conn must preserve datagram boundaries: each Write
+// sends a single record, and each Read returns a single record.
+type DTLSDialer interface {
+	DialDTLS(ctx context.Context, addr string) (net.Conn, error)
+}
+
+// DTLSClient is a RADIUS client that exchanges packets with servers over DTLS
+// (RFC 7360).
+//
+// Sessions are kept open and reused in the same way as the sockets of a
+// PooledClient. As with UDP, unanswered requests are retransmitted over the
+// session according to the Retry or RetryPolicy of Client.
+//
+// The Identifier of each request is allocated by the DTLSClient, replacing the
+// packet's Identifier. The packet passed to Exchange is not modified.
+type DTLSClient struct {
+	// Dialer establishes the DTLS sessions.
+	Dialer DTLSDialer
+
+	// Client configures how packets are retransmitted and verified. If nil,
+	// DefaultClient is used. Its Net and Dialer fields are not used.
+	Client *Client
+
+	// Secret is the shared secret used for all packets. If nil, DTLSSecret
+	// is used, as required by RFC 7360.
+	Secret []byte
+
+	pool connPool
+}
+
+// Exchange sends the packet to the server at addr and waits for a response.
+// If addr has no port, DTLSPort is used. ctx must be non-nil.
+func (c *DTLSClient) Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	if c.Dialer == nil {
+		return nil, errNilDTLSDialer
+	}
+	client, request, addr, dial := c.exchanger(packet, addr)
+	return c.pool.exchange(ctx, client, request, addr, dial, false)
+}
+
+// ExchangeAsync sends the packet to the server at addr in the same way as
+// Exchange, and returns a channel on which the Result is delivered, as
+// Client.ExchangeAsync does. ctx must be non-nil.
+func (c *DTLSClient) ExchangeAsync(ctx context.Context, packet *Packet, addr string) <-chan Result {
+	if ctx == nil {
+		panic("nil context")
+	}
+	if c.Dialer == nil {
+		results := make(chan Result, 1)
+		results <- Result{Err: errNilDTLSDialer}
+		return results
+	}
+	client, request, addr, dial := c.exchanger(packet, addr)
+	return c.pool.exchangeAsync(ctx, client, request, addr, dial, false, false)
+}
+
+var errNilDTLSDialer = errors.New("radius: nil DTLS Dialer")
+
+// exchanger returns the Client configuring the exchange of packet with addr,
+// the request to send, addr with the default port applied, and the function
+// dialing sessions to it.
+func (c *DTLSClient) exchanger(packet *Packet, addr string) (*Client, *Packet, string, func(context.Context) (net.Conn, error)) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DTLSPort)
+	}
+	client := c.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	request := *packet
+	request.Secret = c.Secret
+	if request.Secret == nil {
+		request.Secret = DTLSSecret
+	}
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return c.Dialer.DialDTLS(ctx, addr)
+	}
+	return client, &request, addr, dial
+}
+
+// Close closes all of the client's sessions. Exchanges in progress fail, and
+// future calls to Exchange return ErrClientClosed.
+func (c *DTLSClient) Close() error {
+	return c.pool.close()
+}
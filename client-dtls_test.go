@@ -0,0 +1,43 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// udpDTLSDialer is a DTLSDialer that dials plain UDP, for testing.
+type udpDTLSDialer struct{}
+
+func (udpDTLSDialer) DialDTLS(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "udp", addr)
+}
+
+func TestDTLSClient_Exchange(t *testing.T) {
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource(DTLSSecret))
+	defer server.Close()
+
+	client := &DTLSClient{
+		Dialer: udpDTLSDialer{},
+		Client: &Client{Retry: 100 * time.Millisecond},
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.Exchange(ctx, New(CodeAccessRequest, []byte(`ignored`)), server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessAccept {
+		t.Fatalf("got code %v; expecting Access-Accept", resp.Code)
+	}
+
+	if _, err := (&DTLSClient{}).Exchange(ctx, New(CodeAccessRequest, nil), server.Addr); err == nil {
+		t.Fatal("expecting nil Dialer error")
+	}
+}
@@ -0,0 +1,133 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// FailoverServer is a RADIUS server used by a FailoverClient.
+type FailoverServer struct {
+	// Addr is the address of the server.
+	Addr string
+	// Secret is the secret shared with the server. If nil, the secret of the
+	// request packet is used.
+	Secret []byte
+}
+
+// FailoverClient sends requests to the first available server of an ordered
+// list of servers, failing over to the next server when one does not respond.
+//
+// A server is marked dead after MaxTimeouts consecutive timeouts, and is not
+// used again until Probation has elapsed, after which the next request is
+// sent to it first again. If every server is dead, they are all tried in
+// order.
+//
+// Requests are sent with the secret of the selected server, so passwords
+// should be set using Packet.SetUserPassword, which encrypts them with the
+// secret the request is sent with.
+type FailoverClient struct {
+	// Client is used to exchange packets with the servers. If nil,
+	// DefaultClient is used.
+	Client *Client
+
+	// Servers is the list of servers, in order of preference.
+	Servers []FailoverServer
+
+	// Timeout is how long to wait for a response from a single server before
+	// failing over. If zero, the client's RetryPolicy must limit each
+	// exchange, or the context's deadline applies to the first server.
+	Timeout time.Duration
+
+	// MaxTimeouts is the number of consecutive timeouts after which a server
+	// is marked dead. Defaults to 3.
+	MaxTimeouts int
+
+	// Probation is how long a dead server is not used. Defaults to 30
+	// seconds.
+	Probation time.Duration
+
+	// Prober, if non-nil, is consulted before a server is selected. Servers
+	// that did not respond to their last Status-Server probe are treated as
+	// dead.
+	Prober *Prober
+
+	health serverHealth
+}
+
+func (c *FailoverClient) maxTimeouts() int {
+	if c.MaxTimeouts > 0 {
+		return c.MaxTimeouts
+	}
+	return 3
+}
+
+func (c *FailoverClient) probation() time.Duration {
+	if c.Probation > 0 {
+		return c.Probation
+	}
+	return 30 * time.Second
+}
+
+// IsDead returns if the server with the given address is currently marked
+// dead, either by the client or by its Prober.
+func (c *FailoverClient) IsDead(addr string) bool {
+	return c.isDead(addr, time.Now())
+}
+
+func (c *FailoverClient) isDead(addr string, now time.Time) bool {
+	if c.Prober != nil && !c.Prober.Alive(addr) {
+		return true
+	}
+	return c.health.isDead(addr, c.probation(), now)
+}
+
+// Exchange sends the packet to the first available server and waits for a
+// response, failing over to the following servers if it fails.
+//
+// The error of the last server that was tried is returned if none of them
+// responded.
+func (c *FailoverClient) Exchange(ctx context.Context, packet *Packet) (*Packet, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client := c.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	now := time.Now()
+	var servers, dead []FailoverServer
+	for _, server := range c.Servers {
+		if c.isDead(server.Addr, now) {
+			dead = append(dead, server)
+		} else {
+			servers = append(servers, server)
+		}
+	}
+	servers = append(servers, dead...)
+	if len(servers) == 0 {
+		return nil, errors.New("radius: no servers")
+	}
+
+	var lastErr error
+	for _, server := range servers {
+		request := *packet
+		if server.Secret != nil {
+			request.Secret = server.Secret
+		}
+
+		c.health.begin(server.Addr)
+		response, err := exchangeWithTimeout(ctx, client, &request, server.Addr, c.Timeout)
+		if ctx.Err() != nil {
+			c.health.end(server.Addr, context.Canceled, c.maxTimeouts(), time.Now())
+			return nil, ctx.Err()
+		}
+		c.health.end(server.Addr, err, c.maxTimeouts(), time.Now())
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
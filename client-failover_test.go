@@ -0,0 +1,93 @@
+package radius
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailoverClient_Exchange(t *testing.T) {
+	var primaryCount, backupCount int32
+	primary := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		atomic.AddInt32(&primaryCount, 1)
+	}), StaticSecretSource([]byte(`primary`)))
+	defer primary.Close()
+	backup := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		atomic.AddInt32(&backupCount, 1)
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource([]byte(`backup`)))
+	defer backup.Close()
+
+	client := &FailoverClient{
+		Client: &Client{},
+		Servers: []FailoverServer{
+			{Addr: primary.Addr, Secret: []byte(`primary`)},
+			{Addr: backup.Addr, Secret: []byte(`backup`)},
+		},
+		Timeout:     50 * time.Millisecond,
+		MaxTimeouts: 1,
+		Probation:   200 * time.Millisecond,
+	}
+
+	exchange := func() {
+		t.Helper()
+		resp, err := client.Exchange(context.Background(), New(CodeAccessRequest, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != CodeAccessAccept {
+			t.Fatalf("got code %v; expecting Access-Accept", resp.Code)
+		}
+	}
+
+	exchange()
+	if !client.IsDead(primary.Addr) {
+		t.Fatal("expecting primary to be dead")
+	}
+	if client.IsDead(backup.Addr) {
+		t.Fatal("expecting backup to be alive")
+	}
+
+	primaryBefore := atomic.LoadInt32(&primaryCount)
+	exchange()
+	if n := atomic.LoadInt32(&primaryCount); n != primaryBefore {
+		t.Fatal("expecting dead primary to be skipped")
+	}
+
+	time.Sleep(client.Probation)
+	if client.IsDead(primary.Addr) {
+		t.Fatal("expecting primary probation to have ended")
+	}
+	exchange()
+	if n := atomic.LoadInt32(&primaryCount); n == primaryBefore {
+		t.Fatal("expecting primary to be retried after probation")
+	}
+	if n := atomic.LoadInt32(&backupCount); n != 3 {
+		t.Fatalf("got %d backup requests; expecting 3", n)
+	}
+}
+
+func TestFailoverClient_Exchange_allFailed(t *testing.T) {
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+	}), StaticSecretSource([]byte(`12345`)))
+	defer server.Close()
+
+	client := &FailoverClient{
+		Client: &Client{},
+		Servers: []FailoverServer{
+			{Addr: server.Addr, Secret: []byte(`12345`)},
+		},
+		Timeout: 10 * time.Millisecond,
+	}
+	if _, err := client.Exchange(context.Background(), New(CodeAccessRequest, nil)); err != context.DeadlineExceeded {
+		t.Fatalf("got %v; expecting context.DeadlineExceeded", err)
+	}
+	if client.IsDead(server.Addr) {
+		t.Fatal("expecting server to be alive after one timeout")
+	}
+
+	if _, err := (&FailoverClient{}).Exchange(context.Background(), New(CodeAccessRequest, nil)); err == nil {
+		t.Fatal("expecting no servers error")
+	}
+}
@@ -0,0 +1,103 @@
+package radius
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// serverHealth tracks the liveness of the servers used by a multi-server
+// client. The zero value is ready to use.
+type serverHealth struct {
+	mu    sync.Mutex
+	state map[string]*healthState
+}
+
+type healthState struct {
+	timeouts    int
+	outstanding int
+	deadAt      time.Time
+}
+
+// stateLocked returns the state of the server with the given address.
+func (h *serverHealth) stateLocked(addr string) *healthState {
+	if h.state == nil {
+		h.state = make(map[string]*healthState)
+	}
+	state, ok := h.state[addr]
+	if !ok {
+		state = &healthState{}
+		h.state[addr] = state
+	}
+	return state
+}
+
+// isDead returns if the server with the given address was marked dead less
+// than probation ago.
+func (h *serverHealth) isDead(addr string, probation time.Duration, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state := h.stateLocked(addr)
+	return !state.deadAt.IsZero() && now.Sub(state.deadAt) < probation
+}
+
+// outstanding returns the number of exchanges in progress with the server with
+// the given address.
+func (h *serverHealth) outstanding(addr string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stateLocked(addr).outstanding
+}
+
+// begin records the start of an exchange with the server with the given
+// address.
+func (h *serverHealth) begin(addr string) {
+	h.mu.Lock()
+	h.stateLocked(addr).outstanding++
+	h.mu.Unlock()
+}
+
+// end records the result of an exchange with the server with the given
+// address. The server is marked dead after maxTimeouts consecutive timeouts,
+// and alive again after a response is received. Other errors, and exchanges
+// that were canceled by the caller, do not affect its liveness.
+func (h *serverHealth) end(addr string, err error, maxTimeouts int, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := h.stateLocked(addr)
+	state.outstanding--
+	_, protocolErr := err.(*ProtocolError)
+	switch {
+	case err == nil, protocolErr:
+		// The server answered, even if only with a Protocol-Error.
+		state.timeouts = 0
+		state.deadAt = time.Time{}
+	case isTimeout(err):
+		state.timeouts++
+		if state.timeouts >= maxTimeouts {
+			state.deadAt = now
+		}
+	}
+}
+
+// isTimeout returns if err indicates that a server did not respond to a
+// request.
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	_, ok := err.(*RetryExhaustedError)
+	return ok
+}
+
+// exchangeWithTimeout exchanges packet with the server at addr using client,
+// limiting the exchange to timeout if it is positive.
+func exchangeWithTimeout(ctx context.Context, client *Client, packet *Packet, addr string, timeout time.Duration) (*Packet, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return client.Exchange(ctx, packet, addr)
+}
@@ -0,0 +1,101 @@
+package radius
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// MetricsCollector receives measurements of the exchanges of a Client. Its
+// methods are called concurrently, and must not block.
+//
+// This package does not depend on a metrics library; an implementation
+// typically updates the counters and histograms of one, labeled by server
+// address and packet code. PrometheusClientMetrics exposes them in the
+// Prometheus text exposition format.
+type MetricsCollector interface {
+	// RequestSent is called when a request is first sent to addr.
+	RequestSent(addr string, code Code)
+	// Retransmitted is called each time a request is resent to addr.
+	Retransmitted(addr string, code Code)
+	// PacketError is called when a packet received from addr is discarded
+	// because it could not be parsed or verified.
+	PacketError(addr string, err error)
+	// ResponseReceived is called when the response to a request is received
+	// from addr. rtt is the time since the request was first sent.
+	ResponseReceived(addr string, request, response Code, rtt time.Duration)
+	// StrayResponse is called when a packet received from addr is discarded
+	// because it is not a response to an outstanding request, such as a late
+	// duplicate of a response that was already received.
+	StrayResponse(addr string)
+	// Timeout is called when no response to a request was received from addr
+	// before the exchange's context deadline or retry policy limits.
+	Timeout(addr string, code Code)
+}
+
+// sent records that a request was first sent to addr, and returns the time.
+func (c *Client) sent(ctx context.Context, addr string, packet *Packet) time.Time {
+	if c.Metrics != nil {
+		c.Metrics.RequestSent(addr, packet.Code)
+	}
+	logEvent(c.Logger, ctx, LogLevelDebug, "radius: sent request",
+		"server", addr,
+		"code", packet.Code.String(),
+		"identifier", packet.Identifier,
+		"attributes", redactedAttributes(packet.Attributes))
+	return time.Now()
+}
+
+// packetError records that a packet from addr was discarded.
+func (c *Client) packetError(ctx context.Context, addr string, err error) {
+	if c.Metrics != nil {
+		c.Metrics.PacketError(addr, err)
+	}
+	logEvent(c.Logger, ctx, LogLevelWarn, "radius: discarding response", "server", addr, "error", err)
+}
+
+// strayResponse records that a stray packet from addr was discarded.
+func (c *Client) strayResponse(ctx context.Context, addr string) {
+	if c.Metrics != nil {
+		c.Metrics.StrayResponse(addr)
+	}
+	logEvent(c.Logger, ctx, LogLevelDebug, "radius: discarding stray response", "server", addr)
+}
+
+// finished records the outcome of an exchange with addr.
+func (c *Client) finished(ctx context.Context, addr string, code Code, sent time.Time, response *Packet, err error) {
+	if protocolErr, ok := err.(*ProtocolError); ok {
+		response = protocolErr.Response
+	}
+	switch {
+	case response != nil:
+		if c.Metrics != nil {
+			c.Metrics.ResponseReceived(addr, code, response.Code, time.Since(sent))
+		}
+		logEvent(c.Logger, ctx, LogLevelDebug, "radius: received response",
+			"server", addr,
+			"code", response.Code.String(),
+			"identifier", response.Identifier,
+			"rtt", time.Since(sent),
+			"attributes", redactedAttributes(response.Attributes))
+	case isTimeout(err):
+		if c.Metrics != nil {
+			c.Metrics.Timeout(addr, code)
+		}
+		logEvent(c.Logger, ctx, LogLevelWarn, "radius: request timed out", "server", addr, "code", code.String(), "error", err)
+	}
+}
+
+// retransmitWriter returns w, wrapped to record retransmissions to addr.
+func (c *Client) retransmitWriter(ctx context.Context, w io.Writer, addr string, code Code) io.Writer {
+	if c.Metrics == nil && c.Logger == nil {
+		return w
+	}
+	return writerFunc(func(b []byte) (int, error) {
+		if c.Metrics != nil {
+			c.Metrics.Retransmitted(addr, code)
+		}
+		logEvent(c.Logger, ctx, LogLevelDebug, "radius: retransmitting request", "server", addr, "code", code.String())
+		return w.Write(b)
+	})
+}
@@ -0,0 +1,101 @@
+package radius
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	mu                              sync.Mutex
+	sent, retransmits, packetErrors int
+	timeouts, stray                 int
+	responses                       []Code
+}
+
+func (m *testMetrics) RequestSent(addr string, code Code) {
+	m.mu.Lock()
+	m.sent++
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) Retransmitted(addr string, code Code) {
+	m.mu.Lock()
+	m.retransmits++
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) PacketError(addr string, err error) {
+	m.mu.Lock()
+	m.packetErrors++
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) ResponseReceived(addr string, request, response Code, rtt time.Duration) {
+	m.mu.Lock()
+	m.responses = append(m.responses, response)
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) StrayResponse(addr string) {
+	m.mu.Lock()
+	m.stray++
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) Timeout(addr string, code Code) {
+	m.mu.Lock()
+	m.timeouts++
+	m.mu.Unlock()
+}
+
+func TestClient_Exchange_metrics(t *testing.T) {
+	secret := []byte(`12345`)
+
+	var mu sync.Mutex
+	attempts := make(map[byte]int)
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.Get(typeUserName) == nil {
+			return
+		}
+		mu.Lock()
+		attempts[r.Identifier]++
+		n := attempts[r.Identifier]
+		mu.Unlock()
+		if n == 2 {
+			bad := r.Response(CodeAccessAccept)
+			bad.Secret = []byte(`wrong`)
+			w.Write(bad)
+			w.Write(r.Response(CodeAccessReject))
+		}
+	}), StaticSecretSource(secret))
+	defer server.Close()
+
+	metrics := &testMetrics{}
+	client := Client{
+		Retry:   20 * time.Millisecond,
+		Metrics: metrics,
+	}
+
+	request := New(CodeAccessRequest, secret)
+	request.Add(typeUserName, Attribute("bob"))
+	if _, err := client.Exchange(context.Background(), request, server.Addr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Exchange(ctx, New(CodeAccessRequest, secret), server.Addr); err != context.DeadlineExceeded {
+		t.Fatalf("got %v; expecting context.DeadlineExceeded", err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if metrics.sent != 2 || metrics.retransmits < 2 || metrics.packetErrors != 1 || metrics.timeouts != 1 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+	if len(metrics.responses) != 1 || metrics.responses[0] != CodeAccessReject {
+		t.Fatalf("unexpected responses %v", metrics.responses)
+	}
+}
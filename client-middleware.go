@@ -0,0 +1,35 @@
+package radius
+
+import (
+	"context"
+)
+
+// Exchanger is implemented by the RADIUS clients. Exchange sends the packet to
+// the server at addr and waits for a response.
+type Exchanger interface {
+	Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error)
+}
+
+// ExchangerFunc allows a function to implement Exchanger.
+type ExchangerFunc func(ctx context.Context, packet *Packet, addr string) (*Packet, error)
+
+// Exchange calls f(ctx, packet, addr).
+func (f ExchangerFunc) Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	return f(ctx, packet, addr)
+}
+
+// ClientMiddleware wraps an Exchanger with additional behavior, such as adding
+// attributes to requests, or logging exchanges.
+//
+// Middleware that modifies the request should do so on a copy of the packet,
+// as the caller's packet must not be modified.
+type ClientMiddleware func(next Exchanger) Exchanger
+
+// Chain returns an Exchanger that passes exchanges through each middleware in
+// turn before they reach e. The first middleware is the outermost.
+func Chain(e Exchanger, middleware ...ClientMiddleware) Exchanger {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		e = middleware[i](e)
+	}
+	return e
+}
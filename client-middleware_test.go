@@ -0,0 +1,56 @@
+package radius
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClient_Exchange_middleware(t *testing.T) {
+	secret := []byte(`12345`)
+
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		response := r.Response(CodeAccessAccept)
+		response.Add(typeNASIdentifier, r.Get(typeNASIdentifier))
+		w.Write(response)
+	})
+	server := NewTestServer(handler, StaticSecretSource(secret))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) ClientMiddleware {
+		return func(next Exchanger) Exchanger {
+			return ExchangerFunc(func(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+				order = append(order, name)
+				return next.Exchange(ctx, packet, addr)
+			})
+		}
+	}
+	nasIdentifier := func(next Exchanger) Exchanger {
+		return ExchangerFunc(func(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+			request := *packet
+			request.Attributes = append(Attributes(nil), packet.Attributes...)
+			request.Set(typeNASIdentifier, Attribute("nas1"))
+			return next.Exchange(ctx, &request, addr)
+		})
+	}
+
+	client := Client{
+		Retry:      50 * time.Millisecond,
+		Middleware: []ClientMiddleware{trace("outer"), nasIdentifier, trace("inner")},
+	}
+	packet := New(CodeAccessRequest, secret)
+	response, err := client.Exchange(context.Background(), packet, server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(response.Get(typeNASIdentifier)); got != "nas1" {
+		t.Fatalf("got NAS-Identifier %q; expecting %q", got, "nas1")
+	}
+	if packet.Get(typeNASIdentifier) != nil {
+		t.Fatal("request packet was modified")
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Fatalf("got middleware order %v", order)
+	}
+}
@@ -0,0 +1,151 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// endpointCache remembers, for each server address, the resolved endpoint that
+// last responded.
+type endpointCache struct {
+	mu        sync.Mutex
+	preferred map[string]string
+}
+
+func (e *endpointCache) get(addr string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.preferred[addr]
+}
+
+func (e *endpointCache) set(addr, endpoint string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.preferred == nil {
+		e.preferred = make(map[string]string)
+	}
+	e.preferred[addr] = endpoint
+}
+
+// resolve returns the endpoints of addr, in the order in which they should be
+// tried. If the host of addr is a name, the endpoint that last responded comes
+// first, followed by the other addresses alternating between IPv6 and IPv4
+// (RFC 8305 section 4).
+func (c *Client) resolve(ctx context.Context, addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return []string{addr}, nil
+	}
+	resolver := c.Dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return sortEndpoints(ips, port, c.endpoints.get(addr)), nil
+}
+
+// sortEndpoints returns the endpoints formed from ips and port, interleaving
+// IPv6 and IPv4 addresses starting with the family of the first address.
+// preferred, if it is one of the endpoints, is moved to the front.
+func sortEndpoints(ips []net.IPAddr, port, preferred string) []string {
+	var v4, v6 []string
+	for _, ip := range ips {
+		endpoint := net.JoinHostPort(ip.String(), port)
+		if ip.IP.To4() != nil {
+			v4 = append(v4, endpoint)
+		} else {
+			v6 = append(v6, endpoint)
+		}
+	}
+	first, second := v6, v4
+	if len(ips) > 0 && ips[0].IP.To4() != nil {
+		first, second = v4, v6
+	}
+
+	endpoints := make([]string, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			endpoints = append(endpoints, first[i])
+		}
+		if i < len(second) {
+			endpoints = append(endpoints, second[i])
+		}
+	}
+	for i, endpoint := range endpoints {
+		if endpoint == preferred {
+			copy(endpoints[1:i+1], endpoints[:i])
+			endpoints[0] = preferred
+			break
+		}
+	}
+	return endpoints
+}
+
+// exchangeFallback sends packet to each of the endpoints of addr in turn,
+// starting the next attempt when c.FallbackDelay passes or an attempt fails,
+// and returns the first response received. The endpoint that responded is
+// remembered and tried first by later exchanges with addr.
+func (c *Client) exchangeFallback(ctx context.Context, packet *Packet, addr string, endpoints []string) (*Packet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		endpoint string
+		response *Packet
+		err      error
+	}
+	results := make(chan result, len(endpoints))
+	next := 0
+	start := func() {
+		endpoint := endpoints[next]
+		next++
+		go func() {
+			response, err := c.exchangeOne(ctx, packet, endpoint)
+			results <- result{endpoint, response, err}
+		}()
+	}
+
+	start()
+	running := 1
+	timer := time.NewTimer(c.FallbackDelay)
+	defer timer.Stop()
+
+	var lastErr error
+	for running > 0 {
+		select {
+		case r := <-results:
+			running--
+			if r.err == nil {
+				c.endpoints.set(addr, r.endpoint)
+				return r.response, nil
+			}
+			lastErr = r.err
+			if next < len(endpoints) && ctx.Err() == nil {
+				start()
+				running++
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(c.FallbackDelay)
+			}
+		case <-timer.C:
+			if next < len(endpoints) {
+				start()
+				running++
+				timer.Reset(c.FallbackDelay)
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,63 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSortEndpoints(t *testing.T) {
+	ips := []net.IPAddr{
+		{IP: net.ParseIP("2001:db8::1")},
+		{IP: net.ParseIP("2001:db8::2")},
+		{IP: net.ParseIP("192.0.2.1")},
+		{IP: net.ParseIP("192.0.2.2")},
+	}
+	got := sortEndpoints(ips, "1812", "")
+	expected := []string{"[2001:db8::1]:1812", "192.0.2.1:1812", "[2001:db8::2]:1812", "192.0.2.2:1812"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v; expecting %v", got, expected)
+	}
+
+	got = sortEndpoints(ips, "1812", "[2001:db8::2]:1812")
+	expected = []string{"[2001:db8::2]:1812", "[2001:db8::1]:1812", "192.0.2.1:1812", "192.0.2.2:1812"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v; expecting %v", got, expected)
+	}
+}
+
+func TestClient_exchangeFallback(t *testing.T) {
+	secret := []byte(`12345`)
+
+	// a server that never responds
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource(secret))
+	defer server.Close()
+
+	client := &Client{
+		Retry:         20 * time.Millisecond,
+		FallbackDelay: 50 * time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	endpoints := []string{silent.LocalAddr().String(), server.Addr}
+	response, err := client.exchangeFallback(ctx, New(CodeAccessRequest, secret), "radius.example.com:1812", endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != CodeAccessAccept {
+		t.Fatalf("got %v; expecting Access-Accept", response.Code)
+	}
+	if got := client.endpoints.get("radius.example.com:1812"); got != server.Addr {
+		t.Fatalf("got preferred endpoint %q; expecting %q", got, server.Addr)
+	}
+}
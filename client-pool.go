@@ -0,0 +1,449 @@
+package radius
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// ErrClientClosed is returned by the Exchange methods of clients that keep
+// long-lived connections after their Close method has been called.
+var ErrClientClosed = errors.New("radius: client closed")
+
+// PooledClient exchanges packets over long-lived sockets, instead of dialing a
+// new socket for every exchange as Client does.
+//
+// Each socket has its own Identifier space, so a socket can have at most 256
+// outstanding requests. When every socket to a server is full, a new socket
+// (and therefore a new source port) is opened. Sockets are kept open until
+// they fail or Close is called.
+//
+// The Identifier of each request is allocated by the PooledClient, replacing
+// the packet's Identifier. The packet passed to Exchange is not modified.
+type PooledClient struct {
+	// Client configures how packets are sent, retransmitted, and verified.
+	// If nil, DefaultClient is used.
+	Client *Client
+
+	pool connPool
+}
+
+// Exchange sends the packet to the given server and waits for a response, in
+// the same way as Client.Exchange. ctx must be non-nil.
+func (p *PooledClient) Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client := p.client()
+	dial := client.udpDialer(addr)
+	exchange := ExchangerFunc(func(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+		return p.pool.exchange(ctx, client, packet, addr, dial, false)
+	})
+	return Chain(exchange, client.Middleware...).Exchange(ctx, packet, addr)
+}
+
+func (p *PooledClient) client() *Client {
+	if p.Client == nil {
+		return DefaultClient
+	}
+	return p.Client
+}
+
+// udpDialer returns a function that dials a socket to addr on c.Net.
+func (c *Client) udpDialer(addr string) func(context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		connNet := c.Net
+		if connNet == "" {
+			connNet = "udp"
+		}
+		return c.dial(ctx, connNet, addr)
+	}
+}
+
+// Close closes all of the client's sockets. Exchanges in progress fail, and
+// future calls to Exchange return ErrClientClosed.
+func (p *PooledClient) Close() error {
+	return p.pool.close()
+}
+
+// connPool multiplexes exchanges over long-lived connections, keyed by
+// server address. The connections are either datagram connections, or streams
+// over which packets are framed by their Length field and never retransmitted
+// (RFC 6613 section 2.6).
+type connPool struct {
+	mu      sync.Mutex
+	conns   map[string][]*pooledConn
+	dialing map[string]chan struct{} // closed when the dial in progress to an address completes
+	closed  bool
+}
+
+// responseHandler receives the packets read with the Identifier of an exchange
+// in progress on a pooled connection.
+type responseHandler interface {
+	// receive is called by the connection's read loop with each packet, which
+	// it may retain. It must not block.
+	receive(incoming []byte)
+	// fail is called when the connection fails or is closed.
+	fail(err error)
+}
+
+// responseChan is the responseHandler of an exchange waiting for its
+// responses. It is buffered so that the read loop never blocks on a slow
+// exchange; further responses are dropped.
+type responseChan chan []byte
+
+func (ch responseChan) receive(incoming []byte) {
+	select {
+	case ch <- incoming:
+	default:
+	}
+}
+
+// fail does nothing, as waiting exchanges also watch pooledConn.done.
+func (ch responseChan) fail(err error) {}
+
+type pooledConn struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	pending  map[byte]responseHandler
+	previous map[byte][]byte // last completed request with each Identifier
+	next     byte
+	err      error
+	done     chan struct{} // closed when the connection fails or is closed
+}
+
+// acquire returns a connection to addr, dialing one if needed, and allocates
+// an Identifier on it for h.
+//
+// The pool is not locked while dialing, so that a slow handshake with one
+// server does not delay the exchanges with others. Concurrent exchanges with
+// the same address wait for the dial in progress rather than starting their
+// own.
+func (p *connPool) acquire(ctx context.Context, addr string, dial func(context.Context) (net.Conn, error), packetSize int, stream bool, h responseHandler) (*pooledConn, byte, error) {
+	p.mu.Lock()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, 0, ErrClientClosed
+		}
+		for _, pc := range p.conns[addr] {
+			if id, ok := pc.allocate(h); ok {
+				p.mu.Unlock()
+				return pc, id, nil
+			}
+		}
+		wait, ok := p.dialing[addr]
+		if !ok {
+			break
+		}
+		p.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+		p.mu.Lock()
+	}
+	if p.dialing == nil {
+		p.dialing = make(map[string]chan struct{})
+	}
+	done := make(chan struct{})
+	p.dialing[addr] = done
+	p.mu.Unlock()
+
+	conn, err := dial(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.dialing, addr)
+	close(done)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.closed {
+		conn.Close()
+		return nil, 0, ErrClientClosed
+	}
+	pc := &pooledConn{
+		conn:    conn,
+		pending: make(map[byte]responseHandler),
+		done:    make(chan struct{}),
+	}
+	if p.conns == nil {
+		p.conns = make(map[string][]*pooledConn)
+	}
+	p.conns[addr] = append(p.conns[addr], pc)
+	go p.readLoop(addr, pc, packetSize, stream)
+
+	id, _ := pc.allocate(h)
+	return pc, id, nil
+}
+
+// allocate allocates an unused Identifier on pc for h. false is returned if
+// all 256 Identifiers are in use, or if the connection has failed.
+func (pc *pooledConn) allocate(h responseHandler) (byte, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.err != nil || len(pc.pending) == 256 {
+		return 0, false
+	}
+	for {
+		id := pc.next
+		pc.next++
+		if _, used := pc.pending[id]; !used {
+			pc.pending[id] = h
+			return id, true
+		}
+	}
+}
+
+// release frees the Identifier id on pc, which was used to send wire.
+func (pc *pooledConn) release(id byte, wire []byte) {
+	pc.mu.Lock()
+	delete(pc.pending, id)
+	if wire != nil {
+		if pc.previous == nil {
+			pc.previous = make(map[byte][]byte)
+		}
+		pc.previous[id] = wire
+	}
+	pc.mu.Unlock()
+}
+
+// isPreviousResponse returns if incoming is an authentic response to the
+// previous request sent with Identifier id, i.e. a late duplicate of a response
+// that was already received.
+func (pc *pooledConn) isPreviousResponse(id byte, incoming, secret []byte) bool {
+	pc.mu.Lock()
+	previous := pc.previous[id]
+	pc.mu.Unlock()
+	return previous != nil && IsAuthenticResponse(incoming, previous, secret)
+}
+
+// write writes b to the connection. Writes are serialized so that packets are
+// never interleaved on stream connections.
+func (pc *pooledConn) write(b []byte) (int, error) {
+	pc.writeMu.Lock()
+	defer pc.writeMu.Unlock()
+	return pc.conn.Write(b)
+}
+
+// fail closes pc because of err, failing the exchanges in progress on it.
+func (p *connPool) fail(addr string, pc *pooledConn, err error) {
+	pc.mu.Lock()
+	if pc.err != nil {
+		pc.mu.Unlock()
+		return
+	}
+	pc.err = err
+	handlers := make([]responseHandler, 0, len(pc.pending))
+	for _, h := range pc.pending {
+		handlers = append(handlers, h)
+	}
+	pc.mu.Unlock()
+	for _, h := range handlers {
+		h.fail(err)
+	}
+	p.remove(addr, pc)
+}
+
+// errConnIdle is the error of pooled connections closed by closeIdle.
+var errConnIdle = errors.New("radius: idle connection closed")
+
+// closeIdle closes pc if it has no exchange in progress.
+func (p *connPool) closeIdle(addr string, pc *pooledConn) {
+	pc.mu.Lock()
+	if pc.err != nil || len(pc.pending) > 0 {
+		pc.mu.Unlock()
+		return
+	}
+	pc.err = errConnIdle
+	pc.mu.Unlock()
+	p.remove(addr, pc)
+}
+
+// remove closes pc, which has failed, and removes it from the pool.
+func (p *connPool) remove(addr string, pc *pooledConn) {
+	close(pc.done)
+	pc.conn.Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.conns[addr]
+	for i, c := range conns {
+		if c == pc {
+			p.conns[addr] = append(conns[:i:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(p.conns[addr]) == 0 {
+		delete(p.conns, addr)
+	}
+}
+
+// readPacket reads the next packet from a stream connection into buff. The
+// connection cannot be used any further if an error is returned.
+func readPacket(r io.Reader, buff []byte) (int, error) {
+	if _, err := io.ReadFull(r, buff[:4]); err != nil {
+		return 0, err
+	}
+	length := int(binary.BigEndian.Uint16(buff[2:4]))
+	if length < 20 || length > len(buff) {
+		return 0, errors.New("radius: invalid packet length")
+	}
+	if _, err := io.ReadFull(r, buff[4:length]); err != nil {
+		return 0, err
+	}
+	return length, nil
+}
+
+// readLoop dispatches the packets received on pc to the exchanges waiting for
+// them, until the connection fails.
+func (p *connPool) readLoop(addr string, pc *pooledConn, packetSize int, stream bool) {
+	buff := make([]byte, packetSize)
+	for {
+		var n int
+		var err error
+		if stream {
+			n, err = readPacket(pc.conn, buff)
+		} else {
+			n, err = pc.conn.Read(buff)
+		}
+		if err != nil {
+			p.fail(addr, pc, err)
+			return
+		}
+		if n < 20 {
+			continue
+		}
+
+		pc.mu.Lock()
+		h := pc.pending[buff[1]]
+		pc.mu.Unlock()
+		if h != nil {
+			h.receive(append([]byte(nil), buff[:n]...))
+		}
+	}
+}
+
+// exchange sends packet over a pooled connection to addr and waits for a
+// response. client configures how the packet is sent and verified.
+func (p *connPool) exchange(ctx context.Context, client *Client, packet *Packet, addr string, dial func(context.Context) (net.Conn, error), stream bool) (response *Packet, err error) {
+	request := *client.prepare(packet)
+	responses := make(responseChan, 4)
+	pc, id, err := p.acquire(ctx, addr, dial, maxPacketSize(request.MaxPacketSize), stream, responses)
+	if err != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		return nil, err
+	}
+	var wire []byte
+	defer func() {
+		pc.release(id, wire)
+	}()
+
+	withSecret, err := client.withSecret(ctx, &request, pc.conn.RemoteAddr())
+	if err != nil {
+		return nil, err
+	}
+	request = *withSecret
+	request.Identifier = id
+	ctx, span := client.startSpan(ctx, &request, addr)
+	defer func() {
+		span.end(response, err)
+	}()
+	wire, err = request.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pc.write(wire); err != nil {
+		p.fail(addr, pc, err)
+		return nil, err
+	}
+	tapWire(client.Tap, TapOutbound, pc.conn.RemoteAddr(), wire)
+	sent := client.sent(ctx, addr, &request)
+	defer func() {
+		client.finished(ctx, addr, request.Code, sent, response, err)
+	}()
+
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	exhausted := make(chan error, 1)
+	if !stream {
+		go client.retransmit(ctx, cancel, span.writer(client.retransmitWriter(ctx, tapWriter(client.Tap, writerFunc(pc.write), pc.conn.RemoteAddr()), addr, request.Code)), wire, exhausted)
+	}
+
+	var packetErrorCount int
+	for {
+		select {
+		case incoming := <-responses:
+			tapWire(client.Tap, TapInbound, pc.conn.RemoteAddr(), incoming)
+			received, err := client.verifyResponse(&request, wire, incoming)
+			if _, ok := err.(*NonAuthenticResponseError); ok && pc.isPreviousResponse(id, incoming, request.Secret) {
+				err = errStrayResponse
+			}
+			if err == errStrayResponse {
+				client.strayResponse(ctx, addr)
+				continue
+			}
+			if _, ok := err.(*ProtocolError); ok {
+				return nil, err
+			}
+			if err != nil {
+				client.packetError(ctx, addr, err)
+				packetErrorCount++
+				if client.MaxPacketErrors > 0 && packetErrorCount >= client.MaxPacketErrors {
+					return nil, err
+				}
+				continue
+			}
+			return received, nil
+		case err := <-exhausted:
+			return nil, err
+		case <-ctx.Done():
+			select {
+			case err := <-exhausted:
+				return nil, err
+			default:
+			}
+			return nil, ctx.Err()
+		case <-pc.done:
+			return nil, pc.err
+		}
+	}
+}
+
+// close closes all of the pool's connections.
+func (p *connPool) close() error {
+	p.mu.Lock()
+	p.closed = true
+	var conns []*pooledConn
+	for _, c := range p.conns {
+		conns = append(conns, c...)
+	}
+	p.mu.Unlock()
+
+	for _, pc := range conns {
+		pc.conn.Close()
+	}
+	return nil
+}
+
+// writerFunc allows a function to implement io.Writer.
+type writerFunc func(b []byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
@@ -0,0 +1,123 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPooledClient_Exchange(t *testing.T) {
+	secret := []byte(`12345`)
+
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		time.Sleep(50 * time.Millisecond)
+		response := r.Response(CodeAccessAccept)
+		response.Add(typeUserName, r.Get(typeUserName))
+		w.Write(response)
+	})
+	server := NewTestServer(handler, StaticSecretSource(secret))
+	defer server.Close()
+
+	client := &PooledClient{
+		Client: &Client{
+			Retry: 250 * time.Millisecond,
+		},
+	}
+	defer client.Close()
+
+	const requests = 300
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			packet := New(CodeAccessRequest, secret)
+			packet.Add(typeUserName, Attribute(name))
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := client.Exchange(ctx, packet, server.Addr)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got := string(resp.Get(typeUserName)); got != name {
+				t.Errorf("got response for %q; expecting %q", got, name)
+			}
+		}("user" + strconv.Itoa(i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	client.pool.mu.Lock()
+	conns := len(client.pool.conns[server.Addr])
+	client.pool.mu.Unlock()
+	if conns < 2 {
+		t.Fatalf("got %d sockets; expecting at least 2 for %d concurrent requests", conns, requests)
+	}
+
+	client.Close()
+	if _, err := client.Exchange(context.Background(), New(CodeAccessRequest, secret), server.Addr); err != ErrClientClosed {
+		t.Fatalf("got %v; expecting ErrClientClosed", err)
+	}
+}
+
+func TestConnPool_acquireSlowDial(t *testing.T) {
+	var p connPool
+	defer p.close()
+
+	release := make(chan struct{})
+	slowDial := func(ctx context.Context) (net.Conn, error) {
+		<-release
+		c, _ := net.Pipe()
+		return c, nil
+	}
+	fastDial := func(ctx context.Context) (net.Conn, error) {
+		c, _ := net.Pipe()
+		return c, nil
+	}
+
+	slow := make(chan error, 1)
+	go func() {
+		_, _, err := p.acquire(context.Background(), "slow", slowDial, MaxPacketLength, true, make(responseChan, 1))
+		slow <- err
+	}()
+	for {
+		p.mu.Lock()
+		_, dialing := p.dialing["slow"]
+		p.mu.Unlock()
+		if dialing {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := p.acquire(ctx, "fast", fastDial, MaxPacketLength, true, make(responseChan, 1)); err != nil {
+		t.Fatalf("acquire blocked by a slow dial to another address: %v", err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	if _, _, err := p.acquire(waitCtx, "slow", fastDial, MaxPacketLength, true, make(responseChan, 1)); err != context.DeadlineExceeded {
+		t.Fatalf("got %v; expecting to wait for the dial in progress", err)
+	}
+
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	conns := len(p.conns["slow"])
+	p.mu.Unlock()
+	if conns != 1 {
+		t.Fatalf("got %d connections to slow; expecting 1", conns)
+	}
+}
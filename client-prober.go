@@ -0,0 +1,117 @@
+package radius
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ProbeTarget is a server that is probed by a Prober.
+type ProbeTarget struct {
+	// Addr is the address of the server.
+	Addr string
+	// Secret is the secret shared with the server.
+	Secret []byte
+}
+
+// Prober periodically sends Status-Server requests (RFC 5997) to servers and
+// tracks which of them respond.
+//
+// A Prober can be set on a FailoverClient or a BalancingClient, which then
+// skip the servers that it considers dead before a user request times out.
+type Prober struct {
+	// Client is used to send the Status-Server requests. If nil,
+	// DefaultClient is used.
+	Client *Client
+
+	// Targets is the list of servers to probe.
+	Targets []ProbeTarget
+
+	// Interval is the time between probes of each server. Defaults to 30
+	// seconds.
+	Interval time.Duration
+
+	// Timeout is how long to wait for a response to a probe. Defaults to 5
+	// seconds.
+	Timeout time.Duration
+
+	// OnChange, if non-nil, is called when the liveness of a server changes,
+	// including after its first probe.
+	OnChange func(addr string, alive bool)
+
+	mu    sync.Mutex
+	alive map[string]bool
+}
+
+// Alive returns if the server with the given address responded to its last
+// probe. Servers that have not been probed yet are considered alive.
+func (p *Prober) Alive(addr string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	alive, ok := p.alive[addr]
+	return !ok || alive
+}
+
+// Run probes the targets every Interval until ctx is canceled. The first
+// probes are sent immediately. ctx.Err() is returned.
+func (p *Prober) Run(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.ProbeAll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ProbeAll probes each target once, concurrently, and waits for the probes to
+// finish.
+func (p *Prober) ProbeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, target := range p.Targets {
+		wg.Add(1)
+		go func(target ProbeTarget) {
+			defer wg.Done()
+			p.probe(ctx, target)
+		}(target)
+	}
+	wg.Wait()
+}
+
+// probe sends a Status-Server request to target and records whether it
+// responded.
+func (p *Prober) probe(ctx context.Context, target ProbeTarget) {
+	client := p.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	_, err := exchangeWithTimeout(ctx, client, New(CodeStatusServer, target.Secret), target.Addr, timeout)
+	if ctx.Err() != nil {
+		return
+	}
+	alive := err == nil
+
+	p.mu.Lock()
+	if p.alive == nil {
+		p.alive = make(map[string]bool)
+	}
+	previous, probed := p.alive[target.Addr]
+	p.alive[target.Addr] = alive
+	p.mu.Unlock()
+
+	if (!probed || previous != alive) && p.OnChange != nil {
+		p.OnChange(target.Addr, alive)
+	}
+}
@@ -0,0 +1,66 @@
+package radius
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProber(t *testing.T) {
+	secret := []byte(`12345`)
+
+	alive := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource(secret))
+	defer alive.Close()
+	dead := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+	}), StaticSecretSource(secret))
+	defer dead.Close()
+
+	var mu sync.Mutex
+	changes := make(map[string]bool)
+	prober := &Prober{
+		Client: &Client{},
+		Targets: []ProbeTarget{
+			{Addr: alive.Addr, Secret: secret},
+			{Addr: dead.Addr, Secret: secret},
+		},
+		Timeout: 50 * time.Millisecond,
+		OnChange: func(addr string, alive bool) {
+			mu.Lock()
+			changes[addr] = alive
+			mu.Unlock()
+		},
+	}
+	if !prober.Alive(dead.Addr) {
+		t.Fatal("expecting unprobed server to be alive")
+	}
+
+	prober.ProbeAll(context.Background())
+	if !prober.Alive(alive.Addr) || prober.Alive(dead.Addr) {
+		t.Fatal("unexpected liveness after probing")
+	}
+	mu.Lock()
+	if len(changes) != 2 || !changes[alive.Addr] || changes[dead.Addr] {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	mu.Unlock()
+
+	client := &FailoverClient{
+		Client: &Client{},
+		Servers: []FailoverServer{
+			{Addr: dead.Addr, Secret: secret},
+			{Addr: alive.Addr, Secret: secret},
+		},
+		Prober: prober,
+	}
+	if !client.IsDead(dead.Addr) {
+		t.Fatal("expecting probed server to be dead")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.Exchange(ctx, New(CodeAccessRequest, nil)); err != nil {
+		t.Fatalf("got %v; expecting dead server to be skipped", err)
+	}
+}
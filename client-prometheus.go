@@ -0,0 +1,134 @@
+package radius
+
+import (
+	"bufio"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PrometheusClientMetrics is a MetricsCollector that keeps counters and
+// histograms of the measurements, and exposes them in the Prometheus text
+// exposition format, in the same way as PrometheusServerMetrics.
+//
+// The following metrics are exposed:
+//
+//	radius_client_requests_sent_total{server, code}
+//	radius_client_retransmits_total{server, code}
+//	radius_client_timeouts_total{server, code}
+//	radius_client_responses_received_total{server, code}
+//	radius_client_packet_errors_total{server}
+//	radius_client_stray_responses_total{server}
+//	radius_client_rtt_seconds{server, code} (histogram)
+//
+// The code of responses_received_total is the code of the response; the codes
+// of the other metrics are those of the requests.
+//
+// The zero value is ready to use.
+type PrometheusClientMetrics struct {
+	// Buckets are the upper bounds, in seconds, of the buckets of the
+	// round-trip time histogram, in increasing order. If nil,
+	// DefaultDurationBuckets is used. It must not be modified once the
+	// metrics are in use.
+	Buckets []float64
+
+	mu             sync.Mutex
+	sent           map[prometheusKey]uint64
+	retransmits    map[prometheusKey]uint64
+	timeouts       map[prometheusKey]uint64
+	responses      map[prometheusKey]uint64
+	packetErrors   map[string]uint64
+	strayResponses map[string]uint64
+	rtts           map[prometheusKey]*prometheusHistogram
+}
+
+var _ MetricsCollector = (*PrometheusClientMetrics)(nil)
+
+func (m *PrometheusClientMetrics) initLocked() {
+	if m.sent == nil {
+		m.sent = make(map[prometheusKey]uint64)
+		m.retransmits = make(map[prometheusKey]uint64)
+		m.timeouts = make(map[prometheusKey]uint64)
+		m.responses = make(map[prometheusKey]uint64)
+		m.packetErrors = make(map[string]uint64)
+		m.strayResponses = make(map[string]uint64)
+		m.rtts = make(map[prometheusKey]*prometheusHistogram)
+	}
+}
+
+func (m *PrometheusClientMetrics) buckets() []float64 {
+	if m.Buckets != nil {
+		return m.Buckets
+	}
+	return DefaultDurationBuckets
+}
+
+// RequestSent implements MetricsCollector.
+func (m *PrometheusClientMetrics) RequestSent(addr string, code Code) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.sent[prometheusKey{addr, code}]++
+}
+
+// Retransmitted implements MetricsCollector.
+func (m *PrometheusClientMetrics) Retransmitted(addr string, code Code) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.retransmits[prometheusKey{addr, code}]++
+}
+
+// PacketError implements MetricsCollector.
+func (m *PrometheusClientMetrics) PacketError(addr string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.packetErrors[addr]++
+}
+
+// ResponseReceived implements MetricsCollector.
+func (m *PrometheusClientMetrics) ResponseReceived(addr string, request, response Code, rtt time.Duration) {
+	buckets := m.buckets()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.responses[prometheusKey{addr, response}]++
+	observe(m.rtts, prometheusKey{addr, request}, buckets, rtt.Seconds())
+}
+
+// StrayResponse implements MetricsCollector.
+func (m *PrometheusClientMetrics) StrayResponse(addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.strayResponses[addr]++
+}
+
+// Timeout implements MetricsCollector.
+func (m *PrometheusClientMetrics) Timeout(addr string, code Code) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.timeouts[prometheusKey{addr, code}]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *PrometheusClientMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b := bufio.NewWriter(w)
+	defer b.Flush()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+
+	writeCodeCounters(b, "radius_client_requests_sent_total", "Requests sent by the client, excluding retransmissions.", "server", m.sent)
+	writeCodeCounters(b, "radius_client_retransmits_total", "Retransmissions of unanswered requests.", "server", m.retransmits)
+	writeCodeCounters(b, "radius_client_timeouts_total", "Requests that were never answered.", "server", m.timeouts)
+	writeCodeCounters(b, "radius_client_responses_received_total", "Responses received by the client.", "server", m.responses)
+	writeLabeledValues(b, "radius_client_packet_errors_total", "Received packets discarded because they could not be parsed or verified.", "counter", "server", uintValues(m.packetErrors))
+	writeLabeledValues(b, "radius_client_stray_responses_total", "Received packets discarded because they did not answer an outstanding request.", "counter", "server", uintValues(m.strayResponses))
+	writeHistograms(b, "radius_client_rtt_seconds", "Time from the first transmission of requests until their response.", "server", m.buckets(), m.rtts)
+}
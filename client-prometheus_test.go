@@ -0,0 +1,61 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusClientMetrics(t *testing.T) {
+	secret := []byte(`12345`)
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource(secret))
+	defer server.Close()
+
+	silent, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	metrics := &PrometheusClientMetrics{}
+	client := &Client{
+		RetryPolicy: &RetryPolicy{
+			InitialTimeout: 5 * time.Millisecond,
+			MaxRetries:     1,
+		},
+		Metrics: metrics,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Exchange(ctx, New(CodeAccessRequest, secret), server.Addr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Exchange(ctx, New(CodeAccountingRequest, secret), silent.LocalAddr().String()); !isTimeout(err) {
+		t.Fatalf("got %v; expecting timeout", err)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	answered := `server="` + server.Addr + `"`
+	unanswered := `server="` + silent.LocalAddr().String() + `"`
+	for _, line := range []string{
+		`radius_client_requests_sent_total{` + answered + `,code="Access-Request"} 1`,
+		`radius_client_requests_sent_total{` + unanswered + `,code="Accounting-Request"} 1`,
+		`radius_client_retransmits_total{` + unanswered + `,code="Accounting-Request"} 1`,
+		`radius_client_timeouts_total{` + unanswered + `,code="Accounting-Request"} 1`,
+		`radius_client_responses_received_total{` + answered + `,code="Access-Accept"} 1`,
+		`radius_client_rtt_seconds_count{` + answered + `,code="Access-Request"} 1`,
+		`radius_client_rtt_seconds_bucket{` + answered + `,code="Access-Request",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// RadSecPort is the port used by RADIUS over TLS (RFC 6614 section 2.1).
+const RadSecPort = "2083"
+
+// RadSecSecret is the shared secret used by RADIUS over TLS (RFC 6614 section
+// 2.3).
+var RadSecSecret = []byte("radsec")
+
+// RadSecClient is a RADIUS client that exchanges packets with servers over TLS
+// (RadSec, RFC 6614).
+//
+// Connections are kept open and reused, with multiple outstanding requests
+// pipelined on each. A connection that fails is closed, and a new one is
+// dialed by the next exchange. Requests are never retransmitted over TLS; the
+// context passed to Exchange should have a deadline.
+//
+// The Identifier of each request is allocated by the RadSecClient, replacing
+// the packet's Identifier. The packet passed to Exchange is not modified.
+type RadSecClient struct {
+	// Dialer to use when making the outgoing connections.
+	Dialer net.Dialer
+
+	// TLSConfig configures the TLS connections. If its ServerName is empty,
+	// the host of the server's address is used.
+	TLSConfig *tls.Config
+
+	// Secret is the shared secret used for all packets. If nil, RadSecSecret
+	// is used, as required by RFC 6614.
+	Secret []byte
+
+	// MaxPacketErrors controls how many packet parsing and validation errors
+	// the client will ignore before returning the error from Exchange.
+	MaxPacketErrors int
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of
+	// requests sent and responses accepted by the client, up to
+	// MaxExtendedPacketLength.
+	MaxPacketSize int
+
+	// Metrics, if non-nil, receives measurements of the client's exchanges.
+	Metrics MetricsCollector
+
+	pool connPool
+}
+
+// Exchange sends the packet to the server at addr and waits for a response.
+// If addr has no port, RadSecPort is used. ctx must be non-nil.
+//
+// The packet is sent with the client's secret. Message-Authenticator
+// attributes are added to, and required in responses to, Access-Request and
+// Status-Server packets.
+func (c *RadSecClient) Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client, request, addr, dial := c.exchanger(packet, addr)
+	return c.pool.exchange(ctx, client, request, addr, dial, true)
+}
+
+// ExchangeAsync sends the packet to the server at addr in the same way as
+// Exchange, and returns a channel on which the Result is delivered, as
+// Client.ExchangeAsync does. ctx must be non-nil.
+func (c *RadSecClient) ExchangeAsync(ctx context.Context, packet *Packet, addr string) <-chan Result {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client, request, addr, dial := c.exchanger(packet, addr)
+	return c.pool.exchangeAsync(ctx, client, request, addr, dial, true, false)
+}
+
+// exchanger returns the Client configuring the exchange of packet with addr,
+// the request to send, addr with the default port applied, and the function
+// dialing connections to it.
+func (c *RadSecClient) exchanger(packet *Packet, addr string) (*Client, *Packet, string, func(context.Context) (net.Conn, error)) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, RadSecPort)
+	}
+
+	request := *packet
+	request.Secret = c.Secret
+	if request.Secret == nil {
+		request.Secret = RadSecSecret
+	}
+	client := &Client{
+		MaxPacketErrors:      c.MaxPacketErrors,
+		MessageAuthenticator: MessageAuthenticatorRequire,
+		MaxPacketSize:        c.MaxPacketSize,
+		Metrics:              c.Metrics,
+	}
+
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return dialTLS(ctx, &c.Dialer, addr, c.TLSConfig)
+	}
+	return client, &request, addr, dial
+}
+
+// Close closes all of the client's connections. Exchanges in progress fail,
+// and future calls to Exchange return ErrClientClosed.
+func (c *RadSecClient) Close() error {
+	return c.pool.close()
+}
+
+// dialTLS dials a TLS connection to addr and completes its handshake.
+func dialTLS(ctx context.Context, dialer *net.Dialer, addr string, config *tls.Config) (net.Conn, error) {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, config)
+	if deadline, ok := ctx.Deadline(); ok {
+		tlsConn.SetDeadline(deadline)
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
@@ -0,0 +1,105 @@
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serveTestStream answers each request received on conn with an
+// Access-Accept, after verifying its Message-Authenticator.
+func serveTestStream(t *testing.T, conn net.Conn, secret []byte) {
+	defer conn.Close()
+	var writeMu sync.Mutex
+	buff := make([]byte, MaxPacketLength)
+	for {
+		n, err := readPacket(conn, buff)
+		if err != nil {
+			return
+		}
+		wire := append([]byte(nil), buff[:n]...)
+		go func() {
+			if err := MessageAuthenticatorRequire.Verify(wire, nil, secret, nil); err != nil {
+				t.Errorf("request verification failed: %v", err)
+				return
+			}
+			request, err := Parse(wire, secret)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			// Answer out of order to exercise pipelining.
+			time.Sleep(time.Duration(request.Identifier%3) * 10 * time.Millisecond)
+			response := request.Response(CodeAccessAccept)
+			response.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+			response.Add(typeUserName, request.Get(typeUserName))
+			b, err := response.Encode()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			writeMu.Lock()
+			conn.Write(b)
+			writeMu.Unlock()
+		}()
+	}
+}
+
+func TestRadSecClient_Exchange(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfigs()
+	l, err := tls.Listen("tcp", "localhost:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var accepted int
+	var acceptedMu sync.Mutex
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			acceptedMu.Lock()
+			accepted++
+			acceptedMu.Unlock()
+			go serveTestStream(t, conn, RadSecSecret)
+		}
+	}()
+
+	client := &RadSecClient{
+		TLSConfig: clientConfig,
+	}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			packet := New(CodeAccessRequest, []byte(`ignored`))
+			packet.Add(typeUserName, Attribute(name))
+			resp, err := client.Exchange(ctx, packet, l.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got := string(resp.Get(typeUserName)); got != name {
+				t.Errorf("got response for %q; expecting %q", got, name)
+			}
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+
+	acceptedMu.Lock()
+	defer acceptedMu.Unlock()
+	if accepted != 1 {
+		t.Fatalf("got %d connections; expecting 1 reused connection", accepted)
+	}
+}
@@ -0,0 +1,88 @@
+package radius
+
+import (
+	"context"
+	"net"
+)
+
+// TCPClient is a RADIUS client that exchanges packets with servers over TCP
+// (RFC 6613).
+//
+// Connections are kept open and reused, with multiple outstanding requests
+// pipelined on each. Packets are framed by their Length field. A connection
+// that fails, or that receives a malformed packet, is closed, and a new one
+// is dialed by the next exchange. Requests are never retransmitted over TCP;
+// the context passed to Exchange should have a deadline.
+//
+// The Identifier of each request is allocated by the TCPClient, replacing the
+// packet's Identifier. The packet passed to Exchange is not modified.
+type TCPClient struct {
+	// Dialer to use when making the outgoing connections.
+	Dialer net.Dialer
+
+	// MaxPacketErrors controls how many packet parsing and validation errors
+	// the client will ignore before returning the error from Exchange.
+	MaxPacketErrors int
+
+	// InsecureSkipVerify controls whether the client should skip verifying
+	// response packets received.
+	InsecureSkipVerify bool
+
+	// MessageAuthenticator controls whether a Message-Authenticator attribute
+	// is added to requests, and how the Message-Authenticator of responses is
+	// verified, in the same way as Client.MessageAuthenticator.
+	MessageAuthenticator MessageAuthenticatorPolicy
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of
+	// requests sent and responses accepted by the client, up to
+	// MaxExtendedPacketLength.
+	MaxPacketSize int
+
+	// Metrics, if non-nil, receives measurements of the client's exchanges.
+	Metrics MetricsCollector
+
+	pool connPool
+}
+
+// Exchange sends the packet to the server at addr and waits for a response.
+// ctx must be non-nil.
+func (c *TCPClient) Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client, dial := c.exchanger(addr)
+	return c.pool.exchange(ctx, client, packet, addr, dial, true)
+}
+
+// ExchangeAsync sends the packet to the server at addr in the same way as
+// Exchange, and returns a channel on which the Result is delivered, as
+// Client.ExchangeAsync does. ctx must be non-nil.
+func (c *TCPClient) ExchangeAsync(ctx context.Context, packet *Packet, addr string) <-chan Result {
+	if ctx == nil {
+		panic("nil context")
+	}
+	client, dial := c.exchanger(addr)
+	return c.pool.exchangeAsync(ctx, client, packet, addr, dial, true, false)
+}
+
+// exchanger returns the Client configuring the exchanges with addr, and the
+// function dialing connections to it.
+func (c *TCPClient) exchanger(addr string) (*Client, func(context.Context) (net.Conn, error)) {
+	client := &Client{
+		MaxPacketErrors:      c.MaxPacketErrors,
+		InsecureSkipVerify:   c.InsecureSkipVerify,
+		MessageAuthenticator: c.MessageAuthenticator,
+		MaxPacketSize:        c.MaxPacketSize,
+		Metrics:              c.Metrics,
+	}
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return c.Dialer.DialContext(ctx, "tcp", addr)
+	}
+	return client, dial
+}
+
+// Close closes all of the client's connections. Exchanges in progress fail,
+// and future calls to Exchange return ErrClientClosed.
+func (c *TCPClient) Close() error {
+	return c.pool.close()
+}
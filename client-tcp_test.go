@@ -0,0 +1,134 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTCPClient_Exchange(t *testing.T) {
+	secret := []byte(`12345`)
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go serveTestStream(t, conn, secret)
+		}
+	}()
+
+	client := &TCPClient{
+		MessageAuthenticator: MessageAuthenticatorAdd,
+	}
+	defer client.Close()
+
+	exchange := func(name string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		packet := New(CodeAccessRequest, secret)
+		packet.Add(typeUserName, Attribute(name))
+		resp, err := client.Exchange(ctx, packet, l.Addr().String())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if got := string(resp.Get(typeUserName)); got != name {
+			t.Errorf("got response for %q; expecting %q", got, name)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			exchange(name)
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+
+	// Break the connection; the next exchange reconnects.
+	(<-conns).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client.pool.mu.Lock()
+		n := len(client.pool.conns)
+		client.pool.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expecting failed connection to be removed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	exchange("reconnected")
+	select {
+	case <-conns:
+	default:
+		t.Fatal("expecting a new connection")
+	}
+}
+
+func TestTCPClient_Exchange_slowDial(t *testing.T) {
+	secret := []byte(`12345`)
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestStream(t, conn, secret)
+		}
+	}()
+
+	// Connections to slowAddr hang in Control until release is closed, as
+	// with a server that does not answer the handshake.
+	const slowAddr = "127.0.0.1:1"
+	release := make(chan struct{})
+	defer close(release)
+	client := &TCPClient{
+		Dialer: net.Dialer{
+			Control: func(network, address string, c syscall.RawConn) error {
+				if address == slowAddr {
+					<-release
+				}
+				return nil
+			},
+		},
+		MessageAuthenticator: MessageAuthenticatorAdd,
+	}
+	defer client.Close()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client.Exchange(ctx, New(CodeAccessRequest, secret), slowAddr)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.Exchange(ctx, New(CodeAccessRequest, secret), l.Addr().String()); err != nil {
+		t.Fatalf("exchange blocked by a slow dial to another server: %v", err)
+	}
+}
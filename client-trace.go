@@ -0,0 +1,79 @@
+package radius
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// Tracer creates a span for each exchange of a Client, e.g. to integrate with
+// OpenTelemetry. Its methods are called concurrently.
+//
+// This package does not depend on a tracing library; an implementation
+// typically starts a span as a child of the span in ctx, and records the
+// request's code and identifier and the server address as its attributes.
+type Tracer interface {
+	// StartExchange is called when a request is about to be sent to addr.
+	// The returned context, which should be derived from ctx, is used for
+	// the rest of the exchange.
+	StartExchange(ctx context.Context, request *Packet, addr string) (context.Context, Span)
+}
+
+// Span is the span of a single exchange, created by a Tracer.
+type Span interface {
+	// End is called once the exchange has finished.
+	End(outcome SpanOutcome)
+}
+
+// SpanOutcome describes how an exchange finished.
+type SpanOutcome struct {
+	// Response is the response that was received, or nil.
+	Response *Packet
+	// Err is the error that ended the exchange, or nil.
+	Err error
+	// Retransmits is the number of times the request was resent.
+	Retransmits int
+}
+
+// exchangeSpan wraps the Span of an exchange. A nil *exchangeSpan does
+// nothing.
+type exchangeSpan struct {
+	span        Span
+	retransmits int32
+}
+
+// startSpan starts a span for the exchange of request with addr, if c.Tracer
+// is set.
+func (c *Client) startSpan(ctx context.Context, request *Packet, addr string) (context.Context, *exchangeSpan) {
+	if c.Tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := c.Tracer.StartExchange(ctx, request, addr)
+	if span == nil {
+		return ctx, nil
+	}
+	return ctx, &exchangeSpan{span: span}
+}
+
+// writer returns w, wrapped to count retransmissions.
+func (s *exchangeSpan) writer(w io.Writer) io.Writer {
+	if s == nil {
+		return w
+	}
+	return writerFunc(func(b []byte) (int, error) {
+		atomic.AddInt32(&s.retransmits, 1)
+		return w.Write(b)
+	})
+}
+
+// end ends the span.
+func (s *exchangeSpan) end(response *Packet, err error) {
+	if s == nil {
+		return
+	}
+	s.span.End(SpanOutcome{
+		Response:    response,
+		Err:         err,
+		Retransmits: int(atomic.LoadInt32(&s.retransmits)),
+	})
+}
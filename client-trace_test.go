@@ -0,0 +1,75 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testTraceKey struct{}
+
+type testTracer struct {
+	spans chan *testSpan
+}
+
+type testSpan struct {
+	code    Code
+	addr    string
+	outcome SpanOutcome
+	ended   chan struct{}
+}
+
+func (t *testTracer) StartExchange(ctx context.Context, request *Packet, addr string) (context.Context, Span) {
+	span := &testSpan{code: request.Code, addr: addr, ended: make(chan struct{})}
+	t.spans <- span
+	return context.WithValue(ctx, testTraceKey{}, span), span
+}
+
+func (s *testSpan) End(outcome SpanOutcome) {
+	s.outcome = outcome
+	close(s.ended)
+}
+
+func TestClient_Exchange_tracer(t *testing.T) {
+	secret := []byte(`12345`)
+
+	var attempts int32
+	server := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return
+		}
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource(secret))
+	defer server.Close()
+
+	var traced bool
+	tracer := &testTracer{spans: make(chan *testSpan, 1)}
+	client := Client{
+		Retry:  20 * time.Millisecond,
+		Tracer: tracer,
+		SecretSource: SecretSourceFunc(func(ctx context.Context, remoteAddr net.Addr) ([]byte, error) {
+			traced = ctx.Value(testTraceKey{}) != nil
+			return secret, nil
+		}),
+	}
+	if _, err := client.Exchange(context.Background(), New(CodeAccessRequest, nil), server.Addr); err != nil {
+		t.Fatal(err)
+	}
+
+	span := <-tracer.spans
+	<-span.ended
+	if span.code != CodeAccessRequest || span.addr != server.Addr {
+		t.Fatalf("got span for %v to %s", span.code, span.addr)
+	}
+	if span.outcome.Err != nil || span.outcome.Response == nil || span.outcome.Response.Code != CodeAccessAccept {
+		t.Fatalf("unexpected outcome %+v", span.outcome)
+	}
+	if span.outcome.Retransmits != 2 {
+		t.Fatalf("got %d retransmits; expecting 2", span.outcome.Retransmits)
+	}
+	if !traced {
+		t.Fatal("span context was not propagated")
+	}
+}
@@ -2,6 +2,8 @@ package radius
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net"
 	"time"
 )
@@ -14,19 +16,82 @@ type Client struct {
 	// Dialer to use when making the outgoing connections.
 	Dialer net.Dialer
 
+	// Source, if non-nil, controls the local address of the outgoing
+	// connections, overriding Dialer's LocalAddr and Control.
+	Source *SourceOptions
+
+	// FallbackDelay, if positive, enables dialing of servers whose host name
+	// resolves to multiple addresses. The request is sent to the first
+	// address, and to each of the next ones in turn whenever FallbackDelay
+	// passes without a response or an attempt fails, in the manner of Happy
+	// Eyeballs (RFC 8305). The first response received is returned, and the
+	// address that sent it is tried first by later exchanges with the server.
+	FallbackDelay time.Duration
+
 	// Interval on which to resend packet (zero or negative value means no
 	// retry).
 	Retry time.Duration
 
+	// RetryPolicy, if non-nil, controls when the packet is resent instead of
+	// Retry. If its limits are reached before a response is received,
+	// Exchange returns a *RetryExhaustedError.
+	RetryPolicy *RetryPolicy
+
 	// MaxPacketErrors controls how many packet parsing and validation errors
 	// the client will ignore before returning the error from Exchange.
 	//
 	// If zero, Exchange will drop all packet parsing errors.
+	//
+	// Packets whose Identifier does not match the request, and late
+	// duplicates of responses already received by a PooledClient, are
+	// silently discarded and are not counted as packet errors.
 	MaxPacketErrors int
 
 	// InsecureSkipVerify controls whether the client should skip verifying
 	// response packets received.
 	InsecureSkipVerify bool
+
+	// MessageAuthenticator controls whether a Message-Authenticator attribute
+	// is added to requests, and how the Message-Authenticator of responses is
+	// verified. The stricter of this policy and the request packet's
+	// MessageAuthenticatorPolicy is used. Responses that fail verification
+	// are counted as packet errors, and a *MessageAuthenticatorError is
+	// returned if MaxPacketErrors is reached.
+	MessageAuthenticator MessageAuthenticatorPolicy
+
+	// SecretSource, if non-nil, supplies the secret for requests whose
+	// Secret is empty, based on the address of the server they are sent to.
+	// This allows a single client to be used with servers that have
+	// different secrets. A request's own Secret always takes precedence.
+	SecretSource SecretSource
+
+	// Middleware is applied, outermost first, to every exchange sent by the
+	// client.
+	Middleware []ClientMiddleware
+
+	// Metrics, if non-nil, receives measurements of the client's exchanges.
+	Metrics MetricsCollector
+
+	// Logger, if non-nil, receives structured events for requests sent,
+	// retransmissions, responses received or discarded, and timeouts.
+	Logger Logger
+
+	// Tap, if non-nil, receives a copy of every packet sent or received by
+	// the client, including retransmissions.
+	Tap Tap
+
+	// Tracer, if non-nil, creates a span for each exchange.
+	Tracer Tracer
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of
+	// requests sent and responses accepted by the client, up to
+	// MaxExtendedPacketLength. It applies to requests that do not set their
+	// own MaxPacketSize. If zero, MaxPacketLength is used; larger packets
+	// should only be used over transports that support them, such as TCP.
+	MaxPacketSize int
+
+	endpoints endpointCache
+	async     connPool // sockets shared by asynchronous exchanges
 }
 
 // DefaultClient is the RADIUS client used by the Exchange function.
@@ -42,23 +107,47 @@ func Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error)
 }
 
 // Exchange sends the packet to the given server and waits for a response. ctx
-// must be non-nil.
+// must be non-nil. If the server answers with a Protocol-Error, a
+// *ProtocolError is returned.
+//
+// The exchange is passed through c.Middleware, if any.
 func (c *Client) Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
 	if ctx == nil {
 		panic("nil context")
 	}
+	if len(c.Middleware) == 0 {
+		return c.exchange(ctx, packet, addr)
+	}
+	return Chain(ExchangerFunc(c.exchange), c.Middleware...).Exchange(ctx, packet, addr)
+}
 
-	wire, err := packet.Encode()
-	if err != nil {
-		return nil, err
+func (c *Client) exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	if c.FallbackDelay > 0 {
+		endpoints, err := c.resolve(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		if len(endpoints) > 1 {
+			return c.exchangeFallback(ctx, packet, addr, endpoints)
+		}
 	}
+	return c.exchangeOne(ctx, packet, addr)
+}
+
+// exchangeOne exchanges packet with a single server endpoint.
+func (c *Client) exchangeOne(ctx context.Context, packet *Packet, addr string) (response *Packet, err error) {
+	packet = c.prepare(packet)
+	ctx, span := c.startSpan(ctx, packet, addr)
+	defer func() {
+		span.end(response, err)
+	}()
 
 	connNet := c.Net
 	if connNet == "" {
 		connNet = "udp"
 	}
 
-	conn, err := c.Dialer.DialContext(ctx, connNet, addr)
+	conn, err := c.dial(ctx, connNet, addr)
 	if err != nil {
 		select {
 		case <-ctx.Done():
@@ -69,37 +158,43 @@ func (c *Client) Exchange(ctx context.Context, packet *Packet, addr string) (*Pa
 	}
 	defer conn.Close()
 
+	packet, err = c.withSecret(ctx, packet, conn.RemoteAddr())
+	if err != nil {
+		return nil, err
+	}
+	wire, err := packet.Encode()
+	if err != nil {
+		return nil, err
+	}
+
 	conn.Write(wire)
+	tapWire(c.Tap, TapOutbound, conn.RemoteAddr(), wire)
+	sent := c.sent(ctx, addr, packet)
+	defer func() {
+		c.finished(ctx, addr, packet.Code, sent, response, err)
+	}()
 
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
 
-	var retryTimer <-chan time.Time
-	if c.Retry > 0 {
-		retry := time.NewTicker(c.Retry)
-		defer retry.Stop()
-		retryTimer = retry.C
-	}
-
+	exhausted := make(chan error, 1)
 	go func() {
 		defer conn.Close()
-		for {
-			select {
-			case <-retryTimer:
-				conn.Write(wire)
-			case <-ctx.Done():
-				return
-			}
-		}
+		c.retransmit(ctx, cancel, span.writer(c.retransmitWriter(ctx, tapWriter(c.Tap, conn, conn.RemoteAddr()), addr, packet.Code)), wire, exhausted)
 	}()
 
 	var packetErrorCount int
 
-	var incoming [MaxPacketLength]byte
+	incoming := make([]byte, maxPacketSize(packet.MaxPacketSize))
 	for {
 		n, err := conn.Read(incoming[:])
 		if err != nil {
+			select {
+			case err := <-exhausted:
+				return nil, err
+			default:
+			}
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
@@ -107,24 +202,121 @@ func (c *Client) Exchange(ctx context.Context, packet *Packet, addr string) (*Pa
 			}
 			return nil, err
 		}
+		tapWire(c.Tap, TapInbound, conn.RemoteAddr(), incoming[:n])
 
-		received, err := Parse(incoming[:n], packet.Secret)
+		received, err := c.verifyResponse(packet, wire, incoming[:n])
+		if err == errStrayResponse {
+			c.strayResponse(ctx, addr)
+			continue
+		}
+		if _, ok := err.(*ProtocolError); ok {
+			return nil, err
+		}
 		if err != nil {
+			c.packetError(ctx, addr, err)
 			packetErrorCount++
 			if c.MaxPacketErrors > 0 && packetErrorCount >= c.MaxPacketErrors {
 				return nil, err
 			}
 			continue
 		}
+		return received, nil
+	}
+}
 
-		if !c.InsecureSkipVerify && !IsAuthenticResponse(incoming[:n], wire, packet.Secret) {
-			packetErrorCount++
-			if c.MaxPacketErrors > 0 && packetErrorCount >= c.MaxPacketErrors {
-				return nil, &NonAuthenticResponseError{}
-			}
-			continue
+// prepare returns packet, or a copy of it with the client's
+// MessageAuthenticator policy and MaxPacketSize applied.
+func (c *Client) prepare(packet *Packet) *Packet {
+	if c.MessageAuthenticator > packet.MessageAuthenticatorPolicy || (packet.MaxPacketSize <= 0 && c.MaxPacketSize > 0) {
+		withPolicy := *packet
+		if c.MessageAuthenticator > packet.MessageAuthenticatorPolicy {
+			withPolicy.MessageAuthenticatorPolicy = c.MessageAuthenticator
+		}
+		if packet.MaxPacketSize <= 0 {
+			withPolicy.MaxPacketSize = c.MaxPacketSize
 		}
+		packet = &withPolicy
+	}
+	return packet
+}
 
-		return received, nil
+// withSecret returns packet, or a copy of it with the secret supplied by
+// c.SecretSource for remoteAddr if the packet has no secret.
+func (c *Client) withSecret(ctx context.Context, packet *Packet, remoteAddr net.Addr) (*Packet, error) {
+	if len(packet.Secret) > 0 || c.SecretSource == nil {
+		return packet, nil
+	}
+	secret, err := c.SecretSource.RADIUSSecret(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("radius: empty secret returned from secret source")
+	}
+	withSecret := *packet
+	withSecret.Secret = secret
+	return &withSecret, nil
+}
+
+// retransmit writes wire to w according to c.RetryPolicy or c.Retry until ctx
+// is done. If the retry policy's limits are reached first, the error is sent
+// on exhausted and cancel is called.
+func (c *Client) retransmit(ctx context.Context, cancel context.CancelFunc, w io.Writer, wire []byte, exhausted chan<- error) {
+	if c.RetryPolicy != nil {
+		policy := *c.RetryPolicy
+		policy.retransmit(ctx, cancel, w, wire, exhausted)
+		return
+	}
+	if c.Retry <= 0 {
+		<-ctx.Done()
+		return
+	}
+	retry := time.NewTicker(c.Retry)
+	defer retry.Stop()
+	for {
+		select {
+		case <-retry.C:
+			w.Write(wire)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// errStrayResponse is returned by verifyResponse for packets that are not a
+// response to the request, which are discarded without counting as packet
+// errors.
+var errStrayResponse = errors.New("radius: stray response")
+
+// verifyResponse parses incoming, a response to packet that was sent encoded
+// as wire, and verifies it unless c.InsecureSkipVerify is set.
+func (c *Client) verifyResponse(packet *Packet, wire, incoming []byte) (*Packet, error) {
+	if len(incoming) >= 20 && incoming[1] != wire[1] {
+		return nil, errStrayResponse
+	}
+
+	received, err := ParseWith(incoming, packet.Secret, ParseOptions{MaxPacketSize: packet.MaxPacketSize})
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.InsecureSkipVerify {
+		if !IsAuthenticResponse(incoming, wire, packet.Secret) {
+			return nil, &NonAuthenticResponseError{}
+		}
+		if err := packet.effectiveMessageAuthenticatorPolicy().Verify(incoming, wire[4:20], packet.Secret, packet.AuthAlgorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	if !packet.Code.isValidReply(received.Code) {
+		return nil, &UnexpectedResponseCodeError{
+			Request:  packet.Code,
+			Response: received.Code,
+		}
+	}
+	if received.Code == CodeProtocolError {
+		return nil, newProtocolError(received)
 	}
+	return received, nil
 }
@@ -2,6 +2,7 @@ package radius
 
 import (
 	"context"
+	"net"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -144,6 +145,39 @@ func TestClient_Exchange_nonauthenticPacket(t *testing.T) {
 	}
 }
 
+func TestClient_Exchange_strayResponse(t *testing.T) {
+	secret := []byte(`12345`)
+
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		stray := r.Response(CodeAccessReject)
+		stray.Identifier++
+		w.Write(stray)
+		w.Write(r.Response(CodeAccessAccept))
+	})
+	server := NewTestServer(handler, StaticSecretSource(secret))
+	defer server.Close()
+
+	metrics := &testMetrics{}
+	client := Client{
+		Retry:           time.Millisecond * 50,
+		MaxPacketErrors: 1,
+		Metrics:         metrics,
+	}
+	resp, err := client.Exchange(context.Background(), New(CodeAccessRequest, secret), server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessAccept {
+		t.Fatalf("got code %v; expecting %v", resp.Code, CodeAccessAccept)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if metrics.stray < 1 || metrics.packetErrors != 0 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
 func TestClient_Exchange_nilContext(t *testing.T) {
 	defer func() {
 		err := recover()
@@ -160,3 +194,199 @@ func TestClient_Exchange_nilContext(t *testing.T) {
 	//lint:ignore SA1012 This test is specifically checking for a nil context
 	Exchange(nil, req, "")
 }
+
+func TestClient_Exchange_unexpectedCode(t *testing.T) {
+	secret := []byte(`12345`)
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccountingResponse))
+	})
+	server := NewTestServer(handler, StaticSecretSource(secret))
+	defer server.Close()
+
+	req := New(CodeAccessRequest, secret)
+
+	client := Client{
+		Retry:           time.Millisecond * 5,
+		MaxPacketErrors: 1,
+	}
+	resp, err := client.Exchange(context.Background(), req, server.Addr)
+	if resp != nil {
+		t.Fatalf("got non-nil response (%v); expected nil", resp)
+	}
+	codeErr, ok := err.(*UnexpectedResponseCodeError)
+	if !ok {
+		t.Fatalf("got err = %v; expected *UnexpectedResponseCodeError", err)
+	}
+	if codeErr.Request != CodeAccessRequest || codeErr.Response != CodeAccountingResponse {
+		t.Fatalf("got %+v; expected Access-Request and Accounting-Response", codeErr)
+	}
+}
+
+func TestClient_Exchange_messageAuthenticator(t *testing.T) {
+	secret := []byte(`12345`)
+	var addMessageAuthenticator int32
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		if _, ok := r.Lookup(typeMessageAuthenticator); !ok {
+			w.Write(r.Response(CodeAccessReject))
+			return
+		}
+		resp := r.Response(CodeAccessAccept)
+		if atomic.LoadInt32(&addMessageAuthenticator) == 1 {
+			resp.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+		}
+		w.Write(resp)
+	})
+	server := NewTestServer(handler, StaticSecretSource(secret))
+	defer server.Close()
+
+	client := Client{
+		Retry:                time.Millisecond * 5,
+		MaxPacketErrors:      1,
+		MessageAuthenticator: MessageAuthenticatorRequire,
+	}
+
+	req := New(CodeAccessRequest, secret)
+	_, err := client.Exchange(context.Background(), req, server.Addr)
+	if maErr, ok := err.(*MessageAuthenticatorError); !ok || !maErr.Missing {
+		t.Fatalf("got err = %v; expected missing *MessageAuthenticatorError", err)
+	}
+	if len(req.Attributes) != 0 {
+		t.Fatal("expecting request packet to be left unchanged")
+	}
+
+	atomic.StoreInt32(&addMessageAuthenticator, 1)
+	resp, err := client.Exchange(context.Background(), req, server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessAccept {
+		t.Fatalf("got %v; expecting Access-Accept", resp.Code)
+	}
+}
+
+func TestClient_Exchange_retryPolicy(t *testing.T) {
+	secret := []byte(`12345`)
+
+	var received int32
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		atomic.AddInt32(&received, 1)
+	})
+	server := NewTestServer(handler, StaticSecretSource(secret))
+	defer server.Close()
+
+	client := Client{
+		RetryPolicy: &RetryPolicy{
+			InitialTimeout: 5 * time.Millisecond,
+			Multiplier:     2,
+			MaxRetries:     3,
+		},
+	}
+	resp, err := client.Exchange(context.Background(), New(CodeAccessRequest, secret), server.Addr)
+	if resp != nil {
+		t.Fatalf("got response %v; expected nil", resp)
+	}
+	exhaustedErr, ok := err.(*RetryExhaustedError)
+	if !ok {
+		t.Fatalf("got err %v; expected *RetryExhaustedError", err)
+	}
+	if n := len(exhaustedErr.Attempts); n != 4 {
+		t.Fatalf("got %d attempts; expected 4", n)
+	}
+	for i, attempt := range exhaustedErr.Attempts {
+		if expected := (5 * time.Millisecond) << uint(i); attempt.Timeout != expected {
+			t.Errorf("attempt %d: got timeout %v; expected %v", i, attempt.Timeout, expected)
+		}
+	}
+	if exhaustedErr.Elapsed < 75*time.Millisecond {
+		t.Fatalf("got elapsed %v; expected at least 75ms", exhaustedErr.Elapsed)
+	}
+	if n := atomic.LoadInt32(&received); n < 1 {
+		t.Fatalf("server received %d requests; expected at least 1", n)
+	}
+}
+
+func TestRetryPolicy_next(t *testing.T) {
+	policy := RetryPolicy{
+		Multiplier: 2,
+		MaxTimeout: 3 * time.Second,
+	}
+	if d := policy.next(time.Second); d != 2*time.Second {
+		t.Fatalf("got %v; expected 2s", d)
+	}
+	if d := policy.next(2 * time.Second); d != 3*time.Second {
+		t.Fatalf("got %v; expected 3s", d)
+	}
+
+	policy.Jitter = 0.1
+	for i := 0; i < 100; i++ {
+		if d := policy.jitter(time.Second); d < 900*time.Millisecond || d > 1100*time.Millisecond {
+			t.Fatalf("got jittered timeout %v; expected 0.9s-1.1s", d)
+		}
+	}
+}
+
+func TestRetryPolicy_zeroValue(t *testing.T) {
+	policy := (RetryPolicy{}).withDefaults()
+	if policy.InitialTimeout != DefaultRetryPolicy.InitialTimeout || policy.MaxRetries != DefaultRetryPolicy.MaxRetries || policy.MaxElapsed != DefaultRetryPolicy.MaxElapsed {
+		t.Fatalf("zero fields not defaulted: %+v", policy)
+	}
+
+	var writes int32
+	w := writerFunc(func(b []byte) (int, error) {
+		atomic.AddInt32(&writes, 1)
+		return len(b), nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var zero RetryPolicy
+	zero.retransmit(ctx, cancel, w, []byte{1}, make(chan error, 1))
+	if n := atomic.LoadInt32(&writes); n != 0 {
+		t.Fatalf("zero RetryPolicy retransmitted %d times in 100ms; expected none", n)
+	}
+
+	wild := (RetryPolicy{Jitter: 5}).withDefaults()
+	for i := 0; i < 100; i++ {
+		if d := wild.jitter(10 * time.Millisecond); d < MinRetryTimeout || d > 20*time.Millisecond {
+			t.Fatalf("got jittered timeout %v; expected 1ms-20ms", d)
+		}
+	}
+}
+
+func TestClient_Exchange_secretSource(t *testing.T) {
+	first := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccessAccept))
+	}), StaticSecretSource([]byte(`first`)))
+	defer first.Close()
+	second := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccessReject))
+	}), StaticSecretSource([]byte(`second`)))
+	defer second.Close()
+
+	secrets := map[string][]byte{
+		first.Addr:  []byte(`first`),
+		second.Addr: []byte(`second`),
+	}
+	client := Client{
+		Retry: time.Millisecond * 50,
+		SecretSource: SecretSourceFunc(func(ctx context.Context, remoteAddr net.Addr) ([]byte, error) {
+			return secrets[remoteAddr.String()], nil
+		}),
+	}
+
+	for addr, code := range map[string]Code{first.Addr: CodeAccessAccept, second.Addr: CodeAccessReject} {
+		resp, err := client.Exchange(context.Background(), New(CodeAccessRequest, nil), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != code {
+			t.Fatalf("got code %v from %s; expecting %v", resp.Code, addr, code)
+		}
+	}
+
+	client.SecretSource = SecretSourceFunc(func(ctx context.Context, remoteAddr net.Addr) ([]byte, error) {
+		return nil, nil
+	})
+	if _, err := client.Exchange(context.Background(), New(CodeAccessRequest, nil), first.Addr); err == nil {
+		t.Fatal("expecting empty secret error")
+	}
+}
@@ -26,6 +26,32 @@ const (
 	CodeReserved           Code = 255
 )
 
+// Additional IANA-assigned RADIUS packet codes. Most are experimental or
+// vendor-defined, and are listed so that they can be identified.
+const (
+	CodeAccountingStatus                Code = 6
+	CodePasswordRequest                 Code = 7
+	CodePasswordAck                     Code = 8
+	CodePasswordReject                  Code = 9
+	CodeAccountingMessage               Code = 10
+	CodeResourceFreeRequest             Code = 21
+	CodeResourceFreeResponse            Code = 22
+	CodeResourceQueryRequest            Code = 23
+	CodeResourceQueryResponse           Code = 24
+	CodeAlternateResourceReclaimRequest Code = 25
+	CodeNASRebootRequest                Code = 26
+	CodeNASRebootResponse               Code = 27
+	CodeNextPasscode                    Code = 29
+	CodeNewPin                          Code = 30
+	CodeTerminateSession                Code = 31
+	CodePasswordExpired                 Code = 32
+	CodeEventRequest                    Code = 33
+	CodeEventResponse                   Code = 34
+	CodeIPAddressAllocate               Code = 50
+	CodeIPAddressRelease                Code = 51
+	CodeProtocolError                   Code = 52
+)
+
 // String returns a string representation of the code.
 func (c Code) String() string {
 	switch c {
@@ -59,6 +85,114 @@ func (c Code) String() string {
 		return `CoA-NAK`
 	case CodeReserved:
 		return `Reserved`
+	case CodeAccountingStatus:
+		return `Accounting-Status`
+	case CodePasswordRequest:
+		return `Password-Request`
+	case CodePasswordAck:
+		return `Password-Ack`
+	case CodePasswordReject:
+		return `Password-Reject`
+	case CodeAccountingMessage:
+		return `Accounting-Message`
+	case CodeResourceFreeRequest:
+		return `Resource-Free-Request`
+	case CodeResourceFreeResponse:
+		return `Resource-Free-Response`
+	case CodeResourceQueryRequest:
+		return `Resource-Query-Request`
+	case CodeResourceQueryResponse:
+		return `Resource-Query-Response`
+	case CodeAlternateResourceReclaimRequest:
+		return `Alternate-Resource-Reclaim-Request`
+	case CodeNASRebootRequest:
+		return `NAS-Reboot-Request`
+	case CodeNASRebootResponse:
+		return `NAS-Reboot-Response`
+	case CodeNextPasscode:
+		return `Next-Passcode`
+	case CodeNewPin:
+		return `New-Pin`
+	case CodeTerminateSession:
+		return `Terminate-Session`
+	case CodePasswordExpired:
+		return `Password-Expired`
+	case CodeEventRequest:
+		return `Event-Request`
+	case CodeEventResponse:
+		return `Event-Response`
+	case CodeIPAddressAllocate:
+		return `IP-Address-Allocate`
+	case CodeIPAddressRelease:
+		return `IP-Address-Release`
+	case CodeProtocolError:
+		return `Protocol-Error`
 	}
 	return "Code(" + strconv.Itoa(int(c)) + ")"
 }
+
+// IsRequest returns true if the code is used by a RADIUS client to initiate an
+// exchange.
+func (c Code) IsRequest() bool {
+	switch c {
+	case CodeAccessRequest, CodeAccountingRequest, CodeStatusServer, CodeStatusClient, CodeDisconnectRequest, CodeCoARequest,
+		CodePasswordRequest, CodeResourceFreeRequest, CodeResourceQueryRequest, CodeAlternateResourceReclaimRequest,
+		CodeNASRebootRequest, CodeEventRequest, CodeIPAddressAllocate, CodeIPAddressRelease:
+		return true
+	}
+	return false
+}
+
+// ValidReplies returns the codes that a server may use when replying to a
+// request with code c. nil is returned if c is not a request code, or if no
+// replies are defined for it.
+func (c Code) ValidReplies() []Code {
+	switch c {
+	case CodeAccessRequest:
+		return []Code{CodeAccessAccept, CodeAccessReject, CodeAccessChallenge}
+	case CodeAccountingRequest:
+		return []Code{CodeAccountingResponse}
+	case CodeStatusServer:
+		return []Code{CodeAccessAccept, CodeAccountingResponse}
+	case CodeDisconnectRequest:
+		return []Code{CodeDisconnectACK, CodeDisconnectNAK}
+	case CodeCoARequest:
+		return []Code{CodeCoAACK, CodeCoANAK}
+	case CodePasswordRequest:
+		return []Code{CodePasswordAck, CodePasswordReject}
+	case CodeResourceFreeRequest:
+		return []Code{CodeResourceFreeResponse}
+	case CodeResourceQueryRequest:
+		return []Code{CodeResourceQueryResponse}
+	case CodeNASRebootRequest:
+		return []Code{CodeNASRebootResponse}
+	case CodeEventRequest:
+		return []Code{CodeEventResponse}
+	}
+	return nil
+}
+
+// ExpectedResponses is an alias of ValidReplies.
+func (c Code) ExpectedResponses() []Code {
+	return c.ValidReplies()
+}
+
+// isValidReply returns true if reply is an acceptable response code to a
+// request with code c. Requests without defined replies accept any code.
+func (c Code) isValidReply(reply Code) bool {
+	if reply == CodeProtocolError {
+		// RFC 7930 section 4: a Protocol-Error may be sent in response to
+		// any request. Clients return it as a *ProtocolError.
+		return true
+	}
+	replies := c.ValidReplies()
+	if replies == nil {
+		return true
+	}
+	for _, r := range replies {
+		if r == reply {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package radius
+
+import (
+	"testing"
+)
+
+func TestCode_ValidReplies(t *testing.T) {
+	tests := []struct {
+		Code    Code
+		Request bool
+		Replies []Code
+	}{
+		{CodeAccessRequest, true, []Code{CodeAccessAccept, CodeAccessReject, CodeAccessChallenge}},
+		{CodeAccountingRequest, true, []Code{CodeAccountingResponse}},
+		{CodeCoARequest, true, []Code{CodeCoAACK, CodeCoANAK}},
+		{CodeDisconnectRequest, true, []Code{CodeDisconnectACK, CodeDisconnectNAK}},
+		{CodeAccessAccept, false, nil},
+		{CodeCoAACK, false, nil},
+		{CodeEventRequest, true, []Code{CodeEventResponse}},
+		{CodeProtocolError, false, nil},
+	}
+
+	for _, tt := range tests {
+		if got := tt.Code.IsRequest(); got != tt.Request {
+			t.Errorf("%s: IsRequest() = %v; expected %v", tt.Code, got, tt.Request)
+		}
+		replies := tt.Code.ValidReplies()
+		if len(replies) != len(tt.Replies) {
+			t.Errorf("%s: ValidReplies() = %v; expected %v", tt.Code, replies, tt.Replies)
+			continue
+		}
+		for i := range replies {
+			if replies[i] != tt.Replies[i] {
+				t.Errorf("%s: ValidReplies() = %v; expected %v", tt.Code, replies, tt.Replies)
+				break
+			}
+		}
+	}
+}
+
+func TestCode_String(t *testing.T) {
+	tests := map[Code]string{
+		CodeAccessRequest:                   "Access-Request",
+		CodeAlternateResourceReclaimRequest: "Alternate-Resource-Reclaim-Request",
+		CodeIPAddressAllocate:               "IP-Address-Allocate",
+		CodeProtocolError:                   "Protocol-Error",
+		Code(100):                           "Code(100)",
+	}
+	for code, expected := range tests {
+		if s := code.String(); s != expected {
+			t.Errorf("got %q; expecting %q", s, expected)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package debug
+
+import (
+	"encoding/binary"
+	"io"
+
+	"layeh.com/radius"
+)
+
+// RecordExchange returns req and resp in a format that can be read back with
+// ReplayExchange. Each packet is stored in wire format, as returned by
+// MarshalBinary, prefixed by its big-endian 16-bit length.
+//
+// Recordings can be concatenated to store multiple exchanges in a single file.
+func RecordExchange(req, resp *radius.Packet) ([]byte, error) {
+	reqWire, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	respWire, err := resp.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, 2+len(reqWire)+2+len(respWire))
+	b = appendRecord(b, reqWire)
+	b = appendRecord(b, respWire)
+	return b, nil
+}
+
+func appendRecord(b, wire []byte) []byte {
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(wire)))
+	b = append(b, length[:]...)
+	return append(b, wire...)
+}
+
+// ReplayExchange reads the next exchange recorded by RecordExchange from r,
+// and parses the packets using secret.
+//
+// io.EOF is returned if r contains no more exchanges, and io.ErrUnexpectedEOF
+// if an exchange is truncated.
+func ReplayExchange(r io.Reader, secret []byte) (req, resp *radius.Packet, err error) {
+	reqWire, err := readRecord(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	respWire, err := readRecord(r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req, err = radius.Parse(reqWire, secret); err != nil {
+		return nil, nil, err
+	}
+	if resp, err = radius.Parse(respWire, secret); err != nil {
+		return nil, nil, err
+	}
+	return req, resp, nil
+}
+
+func readRecord(r io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	wire := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(r, wire); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return wire, nil
+}
@@ -0,0 +1,51 @@
+package debug_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"layeh.com/radius"
+	"layeh.com/radius/debug"
+	. "layeh.com/radius/rfc2865"
+)
+
+func TestRecordExchange(t *testing.T) {
+	var recording []byte
+	for _, username := range []string{"alice", "bob"} {
+		req := radius.New(radius.CodeAccessRequest, secret)
+		UserName_SetString(req, username)
+		resp := req.Response(radius.CodeAccessAccept)
+		ReplyMessage_SetString(resp, "hello "+username)
+
+		b, err := debug.RecordExchange(req, resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recording = append(recording, b...)
+	}
+
+	r := bytes.NewReader(recording)
+	for _, username := range []string{"alice", "bob"} {
+		req, resp, err := debug.ReplayExchange(r, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.Code != radius.CodeAccessRequest || UserName_GetString(req) != username {
+			t.Fatalf("unexpected request %s", debug.DumpString(&debug.Config{Dictionary: debug.IncludedDictionary}, req))
+		}
+		if resp.Code != radius.CodeAccessAccept || ReplyMessage_GetString(resp) != "hello "+username {
+			t.Fatalf("unexpected response %s", debug.DumpString(&debug.Config{Dictionary: debug.IncludedDictionary}, resp))
+		}
+		if resp.Identifier != req.Identifier {
+			t.Fatal("expecting response identifier to match request")
+		}
+	}
+	if _, _, err := debug.ReplayExchange(r, secret); err != io.EOF {
+		t.Fatalf("got err %v; expecting io.EOF", err)
+	}
+
+	if _, _, err := debug.ReplayExchange(bytes.NewReader(recording[:30]), secret); err != io.ErrUnexpectedEOF {
+		t.Fatalf("got err %v; expecting io.ErrUnexpectedEOF", err)
+	}
+}
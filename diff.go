@@ -0,0 +1,103 @@
+package radius
+
+import (
+	"bytes"
+)
+
+// DiffKind is the kind of difference between two sets of attributes.
+type DiffKind int
+
+// Attribute difference kinds.
+const (
+	// DiffAdded is an attribute that is present only in the other set.
+	DiffAdded DiffKind = iota + 1
+	// DiffRemoved is an attribute that is present only in the original set.
+	DiffRemoved
+	// DiffChanged is an attribute whose value differs between the two sets.
+	DiffChanged
+)
+
+// String returns a string representation of the kind.
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// AttributeDiff describes a single difference between two sets of attributes.
+type AttributeDiff struct {
+	Kind DiffKind
+	Type Type
+	// Index is the occurrence of the attribute among attributes of the same
+	// type (0 for the first attribute of Type).
+	Index int
+	// Old is the value in the original set; nil if Kind is DiffAdded.
+	Old Attribute
+	// New is the value in the other set; nil if Kind is DiffRemoved.
+	New Attribute
+}
+
+// Equal returns true if a and other contain the same attributes in the same
+// wire order.
+func (a *Attributes) Equal(other *Attributes) bool {
+	if len(*a) != len(*other) {
+		return false
+	}
+	for i, avp := range *a {
+		o := (*other)[i]
+		if avp.Type != o.Type || !bytes.Equal(avp.Attribute, o.Attribute) {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff returns the differences between a and other.
+//
+// Attributes are compared by type and by their occurrence among attributes of
+// the same type; the relative order of attributes of different types is
+// ignored, as it is not significant (RFC 2865 section 5). An empty result
+// means the two sets are equivalent. Use Equal to also compare wire order.
+//
+// Differences are grouped by type, in the order in which each type first
+// appears in a and then other.
+func (a *Attributes) Diff(other *Attributes) []AttributeDiff {
+	var types []Type
+	oldValues := make(map[Type][]Attribute)
+	newValues := make(map[Type][]Attribute)
+	for _, avp := range *a {
+		if _, ok := oldValues[avp.Type]; !ok {
+			types = append(types, avp.Type)
+		}
+		oldValues[avp.Type] = append(oldValues[avp.Type], avp.Attribute)
+	}
+	for _, avp := range *other {
+		_, inOld := oldValues[avp.Type]
+		if _, ok := newValues[avp.Type]; !ok && !inOld {
+			types = append(types, avp.Type)
+		}
+		newValues[avp.Type] = append(newValues[avp.Type], avp.Attribute)
+	}
+
+	var diffs []AttributeDiff
+	for _, typ := range types {
+		oldList, newList := oldValues[typ], newValues[typ]
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			switch {
+			case i >= len(oldList):
+				diffs = append(diffs, AttributeDiff{Kind: DiffAdded, Type: typ, Index: i, New: newList[i]})
+			case i >= len(newList):
+				diffs = append(diffs, AttributeDiff{Kind: DiffRemoved, Type: typ, Index: i, Old: oldList[i]})
+			case !bytes.Equal(oldList[i], newList[i]):
+				diffs = append(diffs, AttributeDiff{Kind: DiffChanged, Type: typ, Index: i, Old: oldList[i], New: newList[i]})
+			}
+		}
+	}
+	return diffs
+}
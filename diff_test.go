@@ -0,0 +1,55 @@
+package radius
+
+import (
+	"testing"
+)
+
+func TestAttributes_Equal(t *testing.T) {
+	var a, b Attributes
+	a.Add(1, Attribute(`bob`))
+	a.Add(4, Attribute{10, 0, 0, 1})
+	b.Add(1, Attribute(`bob`))
+	b.Add(4, Attribute{10, 0, 0, 1})
+
+	if !a.Equal(&b) {
+		t.Fatal("expecting attributes to be equal")
+	}
+
+	b[0], b[1] = b[1], b[0]
+	if a.Equal(&b) {
+		t.Fatal("expecting reordered attributes not to be equal")
+	}
+	if diff := a.Diff(&b); len(diff) != 0 {
+		t.Fatalf("expecting no differences, got %+v", diff)
+	}
+}
+
+func TestAttributes_Diff(t *testing.T) {
+	var a, b Attributes
+	a.Add(1, Attribute(`bob@example.com`))
+	a.Add(33, Attribute(`ps1`))
+	a.Add(33, Attribute(`ps2`))
+	a.Add(18, Attribute(`hello`))
+
+	b.Add(1, Attribute(`bob`))
+	b.Add(33, Attribute(`ps1`))
+	b.Add(25, Attribute(`class`))
+
+	expected := []AttributeDiff{
+		{Kind: DiffChanged, Type: 1, Index: 0, Old: Attribute(`bob@example.com`), New: Attribute(`bob`)},
+		{Kind: DiffRemoved, Type: 33, Index: 1, Old: Attribute(`ps2`)},
+		{Kind: DiffRemoved, Type: 18, Index: 0, Old: Attribute(`hello`)},
+		{Kind: DiffAdded, Type: 25, Index: 0, New: Attribute(`class`)},
+	}
+
+	diffs := a.Diff(&b)
+	if len(diffs) != len(expected) {
+		t.Fatalf("got %d differences; expecting %d: %+v", len(diffs), len(expected), diffs)
+	}
+	for i, e := range expected {
+		d := diffs[i]
+		if d.Kind != e.Kind || d.Type != e.Type || d.Index != e.Index || string(d.Old) != string(e.Old) || string(d.New) != string(e.New) {
+			t.Errorf("difference %d: got %+v; expecting %+v", i, d, e)
+		}
+	}
+}
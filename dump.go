@@ -0,0 +1,166 @@
+package radius
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"layeh.com/radius/dictionary"
+)
+
+// Dump writes a human-readable representation of a to w, one attribute per
+// line, in the style of FreeRADIUS:
+//
+//	User-Name = "bob"
+//	NAS-IP-Address = 10.0.0.1
+//
+// Attribute names and values are resolved using the dictionary registered
+// with RegisterDictionary. Attributes that are unknown, or whose value cannot
+// be decoded, are printed using their type number and hex-encoded value
+// (e.g. "#200 = 0x00ff"). Encrypted attributes are never decrypted.
+func (a Attributes) Dump(w io.Writer) {
+	a.dump(w, "")
+}
+
+// String returns the output of Dump without the trailing newline.
+func (a Attributes) String() string {
+	var b bytes.Buffer
+	a.Dump(&b)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Dump writes a human-readable representation of p to w. The first line
+// contains the packet code and identifier, followed by the packet's
+// attributes as written by Attributes.Dump, indented by two spaces:
+//
+//	Access-Request Id 33
+//	  User-Name = "bob"
+func (p *Packet) Dump(w io.Writer) {
+	io.WriteString(w, p.Code.String())
+	io.WriteString(w, " Id ")
+	io.WriteString(w, strconv.Itoa(int(p.Identifier)))
+	io.WriteString(w, "\n")
+	p.Attributes.dump(w, "  ")
+}
+
+// String returns the output of Dump without the trailing newline.
+func (p *Packet) String() string {
+	var b bytes.Buffer
+	p.Dump(&b)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (a Attributes) dump(w io.Writer, indent string) {
+	d := RegisteredDictionary()
+	for _, avp := range a {
+		name, value := formatAVP(d, avp)
+		io.WriteString(w, indent)
+		io.WriteString(w, name)
+		io.WriteString(w, " = ")
+		io.WriteString(w, value)
+		io.WriteString(w, "\n")
+	}
+}
+
+func formatAVP(d *dictionary.Dictionary, avp *AVP) (name, value string) {
+	var dictAttr *dictionary.Attribute
+	if d != nil {
+		dictAttr = dictionary.AttributeByOID(d.Attributes, dictionary.OID{int(avp.Type)})
+	}
+	if dictAttr == nil {
+		return "#" + strconv.Itoa(int(avp.Type)), "0x" + hex.EncodeToString(avp.Attribute)
+	}
+
+	name = dictAttr.Name
+	attr := avp.Attribute
+	if dictAttr.HasTag() && len(attr) > 0 {
+		switch dictAttr.Type {
+		case dictionary.AttributeInteger:
+			if len(attr) == 4 && attr[0] != 0 {
+				name += ":" + strconv.Itoa(int(attr[0]))
+			}
+			attr = append(Attribute{0x00}, attr[1:]...)
+		case dictionary.AttributeString:
+			if attr[0] <= 0x1F {
+				if attr[0] != 0 {
+					name += ":" + strconv.Itoa(int(attr[0]))
+				}
+				attr = attr[1:]
+			}
+		}
+	}
+
+	if !dictAttr.FlagEncrypt.Valid {
+		value = formatValue(d, dictAttr, attr)
+	}
+	if value == "" {
+		value = "0x" + hex.EncodeToString(attr)
+	}
+	return name, value
+}
+
+func formatValue(d *dictionary.Dictionary, dictAttr *dictionary.Attribute, attr Attribute) string {
+	switch dictAttr.Type {
+	case dictionary.AttributeString:
+		return strconv.Quote(string(attr))
+
+	case dictionary.AttributeDate:
+		if len(attr) == 4 {
+			t := time.Unix(int64(binary.BigEndian.Uint32(attr)), 0).UTC()
+			return t.Format(time.RFC3339)
+		}
+
+	case dictionary.AttributeInteger:
+		if len(attr) == 4 {
+			intVal := uint64(binary.BigEndian.Uint32(attr))
+			var matchedNames []string
+			for _, value := range dictionary.ValuesByAttribute(d.Values, dictAttr.Name) {
+				if value.Number == intVal {
+					matchedNames = append(matchedNames, value.Name)
+				}
+			}
+			if len(matchedNames) > 0 {
+				sort.Stable(sort.StringSlice(matchedNames))
+				return strings.Join(matchedNames, " / ")
+			}
+			return strconv.FormatUint(intVal, 10)
+		}
+
+	case dictionary.AttributeInteger64:
+		if len(attr) == 8 {
+			return strconv.FormatUint(binary.BigEndian.Uint64(attr), 10)
+		}
+
+	case dictionary.AttributeShort:
+		if len(attr) == 2 {
+			return strconv.Itoa(int(binary.BigEndian.Uint16(attr)))
+		}
+
+	case dictionary.AttributeByte:
+		if len(attr) == 1 {
+			return strconv.Itoa(int(attr[0]))
+		}
+
+	case dictionary.AttributeIPAddr:
+		if len(attr) == net.IPv4len {
+			return net.IP(attr).String()
+		}
+
+	case dictionary.AttributeIPv6Addr:
+		if len(attr) == net.IPv6len {
+			return net.IP(attr).String()
+		}
+
+	case dictionary.AttributeIFID:
+		if len(attr) == 8 {
+			return net.HardwareAddr(attr).String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package radius
+
+import (
+	"net"
+	"testing"
+
+	"layeh.com/radius/dictionary"
+)
+
+func TestPacket_String(t *testing.T) {
+	RegisterDictionary(&dictionary.Dictionary{
+		Attributes: []*dictionary.Attribute{
+			{Name: "User-Name", OID: dictionary.OID{1}, Type: dictionary.AttributeString},
+			{Name: "User-Password", OID: dictionary.OID{2}, Type: dictionary.AttributeOctets, FlagEncrypt: dictionary.IntFlag{Int: dictionary.EncryptUserPassword, Valid: true}},
+			{Name: "NAS-IP-Address", OID: dictionary.OID{4}, Type: dictionary.AttributeIPAddr},
+			{Name: "Service-Type", OID: dictionary.OID{6}, Type: dictionary.AttributeInteger},
+			{Name: "Tunnel-Type", OID: dictionary.OID{64}, Type: dictionary.AttributeInteger, FlagHasTag: dictionary.BoolFlag{Bool: true, Valid: true}},
+		},
+		Values: []*dictionary.Value{
+			{Attribute: "Service-Type", Name: "Login-User", Number: 1},
+		},
+	})
+	defer RegisterDictionary(nil)
+
+	p := &Packet{
+		Code:       CodeAccessRequest,
+		Identifier: 33,
+	}
+	p.Add(1, Attribute(`bob`))
+	p.Add(2, Attribute{0x01, 0x02})
+	p.Add(4, Attribute(net.IPv4(10, 0, 0, 1).To4()))
+	p.Add(6, NewInteger(1))
+	p.Add(6, NewInteger(99))
+	p.Add(64, Attribute{0x01, 0x00, 0x00, 0x03})
+	p.Add(4, Attribute{0x01})
+	p.Add(200, Attribute{0x00, 0xff})
+
+	expected := `Access-Request Id 33
+  User-Name = "bob"
+  User-Password = 0x0102
+  NAS-IP-Address = 10.0.0.1
+  Service-Type = Login-User
+  Service-Type = 99
+  Tunnel-Type:1 = 3
+  NAS-IP-Address = 0x01
+  #200 = 0x00ff`
+	if s := p.String(); s != expected {
+		t.Fatalf("got:\n%s\nexpecting:\n%s", s, expected)
+	}
+
+	if s := p.Attributes[:1].String(); s != `User-Name = "bob"` {
+		t.Fatalf("got %q", s)
+	}
+}
+
+func TestAttributes_String_noDictionary(t *testing.T) {
+	var a Attributes
+	a.Add(1, Attribute(`bob`))
+	a.Add(4, nil)
+	if s, expected := a.String(), "#1 = 0x626f62\n#4 = 0x"; s != expected {
+		t.Fatalf("got %q; expecting %q", s, expected)
+	}
+}
@@ -0,0 +1,134 @@
+package radius
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+// AttributeOrder is the order in which attributes are written by EncodeWith.
+//
+// In the sorted orders, a Message-Authenticator attribute is written first,
+// before the attributes of lower types. This is where Encode adds one when
+// the packet's MessageAuthenticatorPolicy requires it.
+type AttributeOrder int
+
+// AttributeOrder values.
+const (
+	// WireOrder writes attributes in the order in which they appear in the
+	// packet's Attributes. This is the order used by Encode.
+	WireOrder AttributeOrder = iota
+
+	// SortedByType writes attributes in ascending order of their type.
+	// Attributes of the same type keep their relative order.
+	SortedByType
+
+	// Canonical writes attributes in ascending order of their type, and
+	// attributes of the same type in ascending order of their value. Packets
+	// with the same attributes in a different order encode identically.
+	//
+	// Attributes whose relative order is significant keep it, as in
+	// SortedByType: EAP-Message, Proxy-State, and the types set using
+	// SetConcatType. The fragments of values split by OversizedSplit also
+	// stay in order.
+	Canonical
+)
+
+// OversizedPolicy determines how EncodeWith handles attribute values that are
+// longer than 253 bytes, and whose type was not set using SetConcatType.
+type OversizedPolicy int
+
+// OversizedPolicy values.
+const (
+	// OversizedError fails encoding. This is the behavior of Encode.
+	OversizedError OversizedPolicy = iota
+
+	// OversizedSkip omits the attribute from the encoded packet.
+	OversizedSkip
+
+	// OversizedSplit encodes the value as consecutive attributes of the same
+	// type, each holding at most 253 bytes of it (RFC 2865 section 5.26,
+	// e.g. EAP-Message).
+	OversizedSplit
+)
+
+// EncodeOptions are options for Packet.EncodeWith. The zero value encodes a
+// packet in the same way as Encode.
+type EncodeOptions struct {
+	// Order is the order in which attributes are written.
+	Order AttributeOrder
+
+	// Oversized determines how attribute values longer than 253 bytes are
+	// handled.
+	Oversized OversizedPolicy
+}
+
+// EncodeWith encodes the RADIUS packet to wire format like Encode, using the
+// given options. p is not modified.
+func (p *Packet) EncodeWith(opts EncodeOptions) ([]byte, error) {
+	attrs, err := p.Attributes.withEncodeOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	c := *p
+	c.Attributes = attrs
+	return c.encode(nil)
+}
+
+// withEncodeOptions returns the attributes of a as they are written according
+// to opts. The returned slice shares values with a.
+func (a Attributes) withEncodeOptions(opts EncodeOptions) (Attributes, error) {
+	// Attributes are sorted before oversized values are split, so that
+	// their fragments are never reordered.
+	sorted := append(Attributes(nil), a...)
+	switch opts.Order {
+	case SortedByType:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Type < sorted[j].Type
+		})
+	case Canonical:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if sorted[i].Type != sorted[j].Type {
+				return sorted[i].Type < sorted[j].Type
+			}
+			if isOrderedType(sorted[i].Type) {
+				return false
+			}
+			return bytes.Compare(sorted[i].Attribute, sorted[j].Attribute) < 0
+		})
+	}
+	if opts.Order != WireOrder {
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Type == typeMessageAuthenticator && sorted[j].Type != typeMessageAuthenticator
+		})
+	}
+
+	attrs := make(Attributes, 0, len(sorted))
+	for _, avp := range sorted {
+		if len(avp.Attribute) <= 253 || isConcatType(avp.Type) {
+			attrs = append(attrs, avp)
+			continue
+		}
+		switch opts.Oversized {
+		case OversizedSkip:
+		case OversizedSplit:
+			for value := avp.Attribute; len(value) > 0; {
+				n := len(value)
+				if n > 253 {
+					n = 253
+				}
+				attrs = append(attrs, &AVP{Type: avp.Type, Attribute: value[:n:n]})
+				value = value[n:]
+			}
+		default:
+			return nil, errors.New("radius: attribute too large")
+		}
+	}
+	return attrs, nil
+}
+
+// isOrderedType returns if the relative order of the attributes of type t is
+// significant.
+func isOrderedType(t Type) bool {
+	return t == typeEAPMessage || t == typeProxyState || isConcatType(t)
+}
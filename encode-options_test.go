@@ -0,0 +1,106 @@
+package radius
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPacket_EncodeWith(t *testing.T) {
+	p := &Packet{
+		Code:   CodeAccessRequest,
+		Secret: []byte(`12345`),
+	}
+	p.Add(18, Attribute("b"))
+	p.Add(1, Attribute("bob"))
+	p.Add(18, Attribute("a"))
+
+	tests := []struct {
+		Order    AttributeOrder
+		Expected []byte
+	}{
+		{WireOrder, []byte{18, 3, 'b', 1, 5, 'b', 'o', 'b', 18, 3, 'a'}},
+		{SortedByType, []byte{1, 5, 'b', 'o', 'b', 18, 3, 'b', 18, 3, 'a'}},
+		{Canonical, []byte{1, 5, 'b', 'o', 'b', 18, 3, 'a', 18, 3, 'b'}},
+	}
+	for _, tt := range tests {
+		b, err := p.EncodeWith(EncodeOptions{Order: tt.Order})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b[20:], tt.Expected) {
+			t.Errorf("order %d: got %x; expecting %x", tt.Order, b[20:], tt.Expected)
+		}
+	}
+
+	if a := p.Get(18); string(a) != "b" {
+		t.Fatal("expecting packet attributes to be unmodified")
+	}
+}
+
+func TestPacket_EncodeWith_oversized(t *testing.T) {
+	p := &Packet{
+		Code:   CodeAccessRequest,
+		Secret: []byte(`12345`),
+	}
+	p.Add(1, Attribute("bob"))
+	p.Add(79, bytes.Repeat([]byte{'x'}, 300))
+
+	if _, err := p.EncodeWith(EncodeOptions{}); err == nil {
+		t.Fatal("expecting attribute too large error")
+	}
+
+	b, err := p.EncodeWith(EncodeOptions{Oversized: OversizedSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{1, 5, 'b', 'o', 'b'}; !bytes.Equal(b[20:], expected) {
+		t.Fatalf("got %x; expecting %x", b[20:], expected)
+	}
+
+	b, err = p.EncodeWith(EncodeOptions{Oversized: OversizedSplit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := ParseAttributes(b[20:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 3 || len(attrs[1].Attribute) != 253 || len(attrs[2].Attribute) != 47 {
+		t.Fatalf("unexpected split attributes %v", attrs)
+	}
+}
+
+func TestPacket_EncodeWith_canonicalSplit(t *testing.T) {
+	p := &Packet{
+		Code:   CodeAccessRequest,
+		Secret: []byte(`12345`),
+	}
+	// Fragments whose values sort in the reverse order of the value.
+	value := append(bytes.Repeat([]byte{'z'}, 253), bytes.Repeat([]byte{'a'}, 100)...)
+	p.Add(26, value)
+	p.Add(1, Attribute("bob"))
+	p.Add(typeMessageAuthenticator, make(Attribute, 16))
+
+	for _, order := range []AttributeOrder{SortedByType, Canonical} {
+		b, err := p.EncodeWith(EncodeOptions{Order: order, Oversized: OversizedSplit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := Parse(b, p.Secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Attributes[0].Type != typeMessageAuthenticator {
+			t.Fatalf("order %d: got %v first; expecting Message-Authenticator", order, decoded.Attributes[0].Type)
+		}
+		var joined []byte
+		for _, avp := range decoded.Attributes {
+			if avp.Type == 26 {
+				joined = append(joined, avp.Attribute...)
+			}
+		}
+		if !bytes.Equal(joined, value) {
+			t.Fatalf("order %d: split value was reordered", order)
+		}
+	}
+}
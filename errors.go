@@ -1,5 +1,9 @@
 package radius
 
+import (
+	"strconv"
+)
+
 // NonAuthenticResponseError is returned when a client was expecting
 // a valid response but did not receive one.
 type NonAuthenticResponseError struct {
@@ -8,3 +12,84 @@ type NonAuthenticResponseError struct {
 func (e *NonAuthenticResponseError) Error() string {
 	return `radius: non-authentic response`
 }
+
+// UnexpectedResponseCodeError is returned when a client receives a response
+// whose code is not a valid reply to the request that was sent.
+type UnexpectedResponseCodeError struct {
+	Request  Code
+	Response Code
+}
+
+func (e *UnexpectedResponseCodeError) Error() string {
+	return `radius: unexpected ` + e.Response.String() + ` response to ` + e.Request.String()
+}
+
+// ProtocolError is returned by Client.Exchange when the server answers the
+// request with a Protocol-Error (RFC 7930), e.g. because it failed to process
+// it.
+type ProtocolError struct {
+	// Response is the Protocol-Error packet.
+	Response *Packet
+	// Cause is the Error-Cause (RFC 5176) of the response, or 0 if it did
+	// not contain one.
+	Cause uint32
+}
+
+func newProtocolError(response *Packet) *ProtocolError {
+	cause, _ := Integer(response.Get(typeErrorCause))
+	return &ProtocolError{
+		Response: response,
+		Cause:    cause,
+	}
+}
+
+func (e *ProtocolError) Error() string {
+	msg := `radius: received Protocol-Error`
+	if e.Cause != 0 {
+		msg += ` with Error-Cause ` + strconv.FormatUint(uint64(e.Cause), 10)
+	}
+	return msg
+}
+
+// MessageAuthenticatorError is returned when a packet's Message-Authenticator
+// attribute is missing or invalid, as determined by a
+// MessageAuthenticatorPolicy.
+type MessageAuthenticatorError struct {
+	// Missing is true if the attribute was required but not present.
+	Missing bool
+}
+
+func (e *MessageAuthenticatorError) Error() string {
+	if e.Missing {
+		return `radius: missing Message-Authenticator`
+	}
+	return `radius: invalid Message-Authenticator`
+}
+
+// InvalidAttributeLengthError is returned when an attribute value is not of a
+// permitted length.
+type InvalidAttributeLengthError struct {
+	Type     Type
+	Length   int
+	Min, Max int
+}
+
+func (e *InvalidAttributeLengthError) Error() string {
+	expecting := strconv.Itoa(e.Min)
+	if e.Max != e.Min {
+		expecting += "-" + strconv.Itoa(e.Max)
+	}
+	return `radius: attribute ` + strconv.Itoa(int(e.Type)) + ` has invalid length ` + strconv.Itoa(e.Length) + ` (expecting ` + expecting + `)`
+}
+
+// LimitExceededError is returned when parsing exceeds a limit set in
+// ParseOptions.
+type LimitExceededError struct {
+	// Limit describes the limit that was exceeded (e.g. "attribute count").
+	Limit string
+	Max   int
+}
+
+func (e *LimitExceededError) Error() string {
+	return `radius: ` + e.Limit + ` limit of ` + strconv.Itoa(e.Max) + ` exceeded`
+}
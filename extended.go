@@ -0,0 +1,138 @@
+package radius
+
+import (
+	"errors"
+)
+
+// ExtendedType is the composite key of an RFC 6929 extended attribute: an
+// Extended-Type-1 through -4 (241-244) or Long-Extended-Type-1 or -2 (245-246)
+// attribute type, along with the Extended-Type octet that is carried at the
+// start of the attribute value.
+type ExtendedType struct {
+	Type     Type
+	Extended byte
+}
+
+const (
+	typeExtendedFirst     Type = 241
+	typeLongExtendedFirst Type = 245
+	typeExtendedLast      Type = 246
+
+	// longExtendedMore is the "More" flag of a Long Extended attribute.
+	longExtendedMore = 0x80
+)
+
+func (k ExtendedType) valid() bool {
+	return k.Type >= typeExtendedFirst && k.Type <= typeExtendedLast
+}
+
+func (k ExtendedType) long() bool {
+	return k.Type >= typeLongExtendedFirst && k.Type <= typeExtendedLast
+}
+
+// AddExtended appends the given extended attribute value to a. Values of Long
+// Extended types are split into multiple attributes if they do not fit into
+// a single one, with the More flag set on all but the last fragment. An error
+// is returned if key is not an extended type, or if the value of a (short)
+// Extended type is longer than 252 bytes.
+func (a *Attributes) AddExtended(key ExtendedType, value Attribute) error {
+	if !key.valid() {
+		return errors.New("radius: not an extended attribute type")
+	}
+
+	if !key.long() {
+		if len(value) > 252 {
+			return errors.New("radius: extended attribute too large")
+		}
+		a.Add(key.Type, append(Attribute{key.Extended}, value...))
+		return nil
+	}
+
+	for {
+		n, flags := len(value), byte(0)
+		if n > 251 {
+			n, flags = 251, longExtendedMore
+		}
+		fragment := make(Attribute, 2+n)
+		fragment[0] = key.Extended
+		fragment[1] = flags
+		copy(fragment[2:], value[:n])
+		a.Add(key.Type, fragment)
+		value = value[n:]
+		if len(value) == 0 {
+			return nil
+		}
+	}
+}
+
+// GetExtended returns the value of the first extended attribute with the
+// given key. nil is returned if no such attribute exists in a.
+func (a *Attributes) GetExtended(key ExtendedType) Attribute {
+	attr, _ := a.LookupExtended(key)
+	return attr
+}
+
+// LookupExtended returns the value of the first extended attribute with the
+// given key, without its Extended-Type (and, for Long Extended types, flags)
+// octets. Fragmented Long Extended values are reassembled from consecutive
+// attributes. Attributes that are too short, or Long Extended fragments that
+// are not followed by their continuation, are ignored.
+//
+// nil and false is returned if no such attribute exists in a.
+func (a *Attributes) LookupExtended(key ExtendedType) (Attribute, bool) {
+	if !key.valid() {
+		return nil, false
+	}
+	for i := 0; i < len(*a); i++ {
+		if !a.isExtended(i, key) {
+			continue
+		}
+		attr := (*a)[i].Attribute
+		if !key.long() {
+			return attr[1:], true
+		}
+
+		value := append(Attribute(nil), attr[2:]...)
+		for attr[1]&longExtendedMore != 0 {
+			if i+1 == len(*a) || !a.isExtended(i+1, key) {
+				break
+			}
+			i++
+			attr = (*a)[i].Attribute
+			value = append(value, attr[2:]...)
+		}
+		if attr[1]&longExtendedMore == 0 {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// DelExtended removes all extended attributes with the given key from a,
+// including every fragment of Long Extended values.
+func (a *Attributes) DelExtended(key ExtendedType) {
+	if !key.valid() {
+		return
+	}
+	for i := 0; i < len(*a); {
+		if a.isExtended(i, key) {
+			*a = append((*a)[:i], (*a)[i+1:]...)
+		} else {
+			i++
+		}
+	}
+}
+
+// isExtended returns true if the attribute at index i of a is a well-formed
+// extended attribute with the given key.
+func (a *Attributes) isExtended(i int, key ExtendedType) bool {
+	avp := (*a)[i]
+	if avp.Type != key.Type {
+		return false
+	}
+	minLength := 1
+	if key.long() {
+		minLength = 2
+	}
+	return len(avp.Attribute) >= minLength && avp.Attribute[0] == key.Extended
+}
@@ -0,0 +1,73 @@
+package radius
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAttributes_extended(t *testing.T) {
+	var a Attributes
+	key := ExtendedType{Type: 241, Extended: 1}
+	if err := a.AddExtended(key, []byte(`short`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddExtended(key, make([]byte, 253)); err == nil {
+		t.Fatal("expecting error for oversized value")
+	}
+	if err := a.AddExtended(ExtendedType{Type: 26, Extended: 1}, nil); err == nil {
+		t.Fatal("expecting error for non-extended type")
+	}
+	if !bytes.Equal(a[0].Attribute, []byte("\x01short")) {
+		t.Fatalf("got %x", a[0].Attribute)
+	}
+	if v := a.GetExtended(key); string(v) != `short` {
+		t.Fatalf("got %q; expecting short", v)
+	}
+	if _, ok := a.LookupExtended(ExtendedType{Type: 241, Extended: 2}); ok {
+		t.Fatal("expecting different extended type lookup to fail")
+	}
+
+	long := ExtendedType{Type: 245, Extended: 3}
+	value := bytes.Repeat([]byte{0xAB}, 600)
+	if err := a.AddExtended(long, value); err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 4 {
+		t.Fatalf("got %d attributes; expecting 4", len(a))
+	}
+	for i, flags := range []byte{0x80, 0x80, 0x00} {
+		if a[1+i].Attribute[1] != flags {
+			t.Fatalf("fragment %d: got flags %x; expecting %x", i, a[1+i].Attribute[1], flags)
+		}
+	}
+	if v := a.GetExtended(long); !bytes.Equal(v, value) {
+		t.Fatalf("got %d bytes; expecting reassembled value", len(v))
+	}
+
+	encoded := make([]byte, 2048)
+	n, err := AttributesEncodedLen(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.encodeTo(encoded)
+	parsed, err := ParseAttributes(encoded[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := parsed.GetExtended(long); !bytes.Equal(v, value) {
+		t.Fatalf("got %d bytes after parsing; expecting reassembled value", len(v))
+	}
+
+	// Incomplete fragment
+	var b Attributes
+	b.Add(245, []byte("\x03\x80abc"))
+	b.Add(1, []byte(`user`))
+	if _, ok := b.LookupExtended(long); ok {
+		t.Fatal("expecting incomplete fragment to be ignored")
+	}
+
+	a.DelExtended(long)
+	if len(a) != 1 {
+		t.Fatalf("got %d attributes; expecting 1", len(a))
+	}
+}
@@ -0,0 +1,48 @@
+package radius
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// Fingerprint identifies a RADIUS request for duplicate detection. Two
+// requests have the same Fingerprint if, and only if (barring SHA-256
+// collisions), they were sent from the same source with the same Code,
+// Identifier, and Request Authenticator, which RFC 5080 section 2.2.2
+// recommends as the key for detecting retransmissions.
+//
+// Fingerprint is comparable, and can be used as a map key.
+type Fingerprint [sha256.Size]byte
+
+// String returns the fingerprint in hexadecimal.
+func (f Fingerprint) String() string {
+	return hex.EncodeToString(f[:])
+}
+
+// Fingerprint returns the fingerprint of p as sent from source, which
+// identifies the sender (e.g. the string form of its address, including the
+// source port).
+func (p *Packet) Fingerprint(source string) Fingerprint {
+	hash := sha256.New()
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(source)))
+	hash.Write(length[:])
+	hash.Write([]byte(source))
+	hash.Write([]byte{byte(p.Code), p.Identifier})
+	hash.Write(p.Authenticator[:])
+
+	var f Fingerprint
+	hash.Sum(f[:0])
+	return f
+}
+
+// Fingerprint returns the fingerprint of the request's packet, using its
+// RemoteAddr as the source.
+func (r *Request) Fingerprint() Fingerprint {
+	var source string
+	if r.RemoteAddr != nil {
+		source = r.RemoteAddr.Network() + ":" + r.RemoteAddr.String()
+	}
+	return r.Packet.Fingerprint(source)
+}
@@ -0,0 +1,48 @@
+package radius
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPacket_Fingerprint(t *testing.T) {
+	p := New(CodeAccessRequest, []byte(`12345`))
+	f := p.Fingerprint("10.0.0.1:1645")
+
+	if f != p.Clone().Fingerprint("10.0.0.1:1645") {
+		t.Fatal("expecting equal fingerprints for the same request")
+	}
+	if f == p.Fingerprint("10.0.0.1:1646") {
+		t.Fatal("expecting different fingerprint for a different source")
+	}
+
+	q := p.Clone()
+	q.Identifier++
+	if f == q.Fingerprint("10.0.0.1:1645") {
+		t.Fatal("expecting different fingerprint for a different identifier")
+	}
+	q = p.Clone()
+	q.Authenticator[0]++
+	if f == q.Fingerprint("10.0.0.1:1645") {
+		t.Fatal("expecting different fingerprint for a different authenticator")
+	}
+
+	// Attributes are not part of the fingerprint
+	q = p.Clone()
+	q.Add(typeUserName, Attribute("bob"))
+	if f != q.Fingerprint("10.0.0.1:1645") {
+		t.Fatal("expecting equal fingerprints for the same request")
+	}
+
+	if len(f.String()) != 64 {
+		t.Fatalf("unexpected fingerprint string %q", f.String())
+	}
+
+	r := &Request{
+		RemoteAddr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1645},
+		Packet:     p,
+	}
+	if r.Fingerprint() != p.Fingerprint("udp:10.0.0.1:1645") {
+		t.Fatal("unexpected request fingerprint")
+	}
+}
@@ -1,6 +1,16 @@
 package radius
 
-import "net"
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"time"
+)
 
 type TestServer struct {
 	Addr string
@@ -41,3 +51,49 @@ func NewTestServer(handler Handler, secretSource SecretSource) *TestServer {
 func (s *TestServer) Close() error {
 	return s.l.Close()
 }
+
+// newTestTLSConfigs returns TLS configurations for a server with a
+// self-signed certificate for localhost, and for a client that trusts it.
+func newTestTLSConfigs() (server, client *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	certificate := tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        cert,
+	}
+	server = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    pool,
+	}
+	client = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      pool,
+	}
+	return server, client
+}
@@ -0,0 +1,112 @@
+package radius
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+type jsonAVP struct {
+	Type  *int   `json:"type,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value"`
+}
+
+type jsonPacket struct {
+	Code          Code       `json:"code"`
+	Identifier    byte       `json:"identifier"`
+	Authenticator string     `json:"authenticator"`
+	Attributes    Attributes `json:"attributes"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Attributes are encoded as a list of objects that contain the attribute type,
+// the attribute name (if a dictionary has been registered with
+// RegisterDictionary and it contains the attribute), and the hex-encoded wire
+// value:
+//
+//	[{"type":1,"name":"User-Name","value":"626f62"}]
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	avps := make([]jsonAVP, len(a))
+	for i, avp := range a {
+		typ := int(avp.Type)
+		avps[i].Type = &typ
+		if attr := registeredAttribute(avp.Type); attr != nil {
+			avps[i].Name = attr.Name
+		}
+		avps[i].Value = hex.EncodeToString(avp.Attribute)
+	}
+	return json.Marshal(avps)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts the format produced
+// by MarshalJSON. If an attribute has no type, its name is resolved using the
+// dictionary registered with RegisterDictionary.
+func (a *Attributes) UnmarshalJSON(b []byte) error {
+	var avps []jsonAVP
+	if err := json.Unmarshal(b, &avps); err != nil {
+		return err
+	}
+
+	attrs := make(Attributes, 0, len(avps))
+	for i, avp := range avps {
+		var typ Type
+		switch {
+		case avp.Type != nil:
+			typ = Type(*avp.Type)
+		case avp.Name != "":
+			attr := registeredAttributeByName(avp.Name)
+			if attr == nil {
+				return errors.New("radius: unknown attribute name " + strconv.Quote(avp.Name))
+			}
+			typ = Type(attr.OID[0])
+		default:
+			return errors.New("radius: attribute " + strconv.Itoa(i) + " has no type or name")
+		}
+		value, err := hex.DecodeString(avp.Value)
+		if err != nil {
+			return errors.New("radius: attribute " + strconv.Itoa(i) + " has invalid value: " + err.Error())
+		}
+		attrs = append(attrs, &AVP{
+			Type:      typ,
+			Attribute: value,
+		})
+	}
+	*a = attrs
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The packet's code, identifier,
+// authenticator, and attributes are encoded; the secret is never included.
+func (p Packet) MarshalJSON() ([]byte, error) {
+	jp := jsonPacket{
+		Code:          p.Code,
+		Identifier:    p.Identifier,
+		Authenticator: hex.EncodeToString(p.Authenticator[:]),
+		Attributes:    p.Attributes,
+	}
+	if jp.Attributes == nil {
+		jp.Attributes = Attributes{}
+	}
+	return json.Marshal(jp)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts the format produced
+// by MarshalJSON. The packet's Secret is left unchanged.
+func (p *Packet) UnmarshalJSON(b []byte) error {
+	var jp jsonPacket
+	if err := json.Unmarshal(b, &jp); err != nil {
+		return err
+	}
+	authenticator, err := hex.DecodeString(jp.Authenticator)
+	if err != nil || len(authenticator) != len(p.Authenticator) {
+		return errors.New("radius: invalid authenticator")
+	}
+	p.Code = jp.Code
+	p.Identifier = jp.Identifier
+	copy(p.Authenticator[:], authenticator)
+	p.Attributes = jp.Attributes
+	return nil
+}
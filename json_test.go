@@ -0,0 +1,92 @@
+package radius
+
+import (
+	"encoding/json"
+	"testing"
+
+	"layeh.com/radius/dictionary"
+)
+
+func TestAttributes_JSON(t *testing.T) {
+	var a Attributes
+	a.Add(1, Attribute(`bob`))
+	a.Add(200, Attribute{0x00, 0xff})
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"type":1,"value":"626f62"},{"type":200,"value":"00ff"}]`; string(b) != expected {
+		t.Fatalf("got %s; expecting %s", b, expected)
+	}
+
+	var decoded Attributes
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(&a) {
+		t.Fatalf("got %v; expecting %v", decoded, a)
+	}
+}
+
+func TestAttributes_JSON_dictionary(t *testing.T) {
+	RegisterDictionary(&dictionary.Dictionary{
+		Attributes: []*dictionary.Attribute{
+			{Name: "User-Name", OID: dictionary.OID{1}, Type: dictionary.AttributeString},
+		},
+	})
+	defer RegisterDictionary(nil)
+
+	var a Attributes
+	a.Add(1, Attribute(`bob`))
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"type":1,"name":"User-Name","value":"626f62"}]`; string(b) != expected {
+		t.Fatalf("got %s; expecting %s", b, expected)
+	}
+
+	var decoded Attributes
+	if err := json.Unmarshal([]byte(`[{"name":"User-Name","value":"626f62"}]`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(&a) {
+		t.Fatalf("got %v; expecting %v", decoded, a)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"name":"Unknown","value":""}]`), &decoded); err == nil {
+		t.Fatal("expecting unknown name error")
+	}
+}
+
+func TestPacket_JSON(t *testing.T) {
+	p := New(CodeAccessRequest, []byte(`12345`))
+	p.Add(1, Attribute(`bob`))
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := &Packet{
+		Secret: []byte(`12345`),
+	}
+	if err := json.Unmarshal(b, q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Code != p.Code || q.Identifier != p.Identifier || q.Authenticator != p.Authenticator || !q.Attributes.Equal(&p.Attributes) {
+		t.Fatalf("got %+v; expecting %+v", q, p)
+	}
+
+	pWire, _ := p.Encode()
+	qWire, _ := q.Encode()
+	if string(pWire) != string(qWire) {
+		t.Fatal("expecting round-tripped packet to encode identically")
+	}
+
+	var value Packet = *p
+	if b2, err := json.Marshal(value); err != nil || string(b2) != string(b) {
+		t.Fatalf("got %s, %v; expecting %s", b2, err, b)
+	}
+}
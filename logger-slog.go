@@ -0,0 +1,25 @@
+//go:build go1.21
+
+package radius
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger returns a Logger that passes events to l.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	s.l.Log(ctx, slog.Level(level), msg, keyvals...)
+}
+
+func (a redactedAttributes) LogValue() slog.Value {
+	return slog.StringValue(a.String())
+}
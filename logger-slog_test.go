@@ -0,0 +1,36 @@
+//go:build go1.21
+
+package radius
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.Log(context.Background(), LogLevelWarn, "radius: test", "remote", "192.0.2.1:1812")
+
+	if out := b.String(); !strings.Contains(out, "level=WARN") || !strings.Contains(out, `msg="radius: test"`) || !strings.Contains(out, "remote=192.0.2.1:1812") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestNewSlogLogger_redactedAttributes(t *testing.T) {
+	var a Attributes
+	a.Add(typeUserName, Attribute("tim"))
+	a.Add(typeUserPassword, Attribute("hunter2"))
+
+	var b bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&b, nil)))
+	logger.Log(context.Background(), LogLevelInfo, "radius: test", "attributes", redactedAttributes(a))
+
+	if out := b.String(); strings.Contains(out, "hunter2") || !strings.Contains(out, fmt.Sprintf("%#x", "<redacted>")) {
+		t.Fatalf("attributes not redacted: %s", out)
+	}
+}
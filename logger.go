@@ -0,0 +1,87 @@
+package radius
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// LogLevel is the severity of an event passed to a Logger. Its values are
+// those of the levels of the log/slog package.
+type LogLevel int
+
+// LogLevel values.
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+// Logger receives structured events from clients and servers, such as
+// received packets, parse errors, secret lookup failures, retransmissions,
+// and shutdowns. keyvals are alternating keys and values, as accepted by
+// slog.Logger.Log. Its methods are called concurrently.
+//
+// Packet attributes in events are redacted with Attributes.Redacted, so that
+// passwords are never logged. They are passed as a fmt.Stringer (and, on Go
+// 1.21 and later, a slog.LogValuer) that formats them only when called; a
+// Logger that keeps events after Log returns must format them first. On Go
+// 1.21 and later, NewSlogLogger adapts a *slog.Logger.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// logEvent passes an event to logger, if it is non-nil. false is returned if
+// logger is nil, so that the caller can fall back to its ErrorLog.
+func logEvent(logger Logger, ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) bool {
+	if logger == nil {
+		return false
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logger.Log(ctx, level, msg, keyvals...)
+	return true
+}
+
+// errorLogf passes an error message to logger at LogLevelError, or logs it
+// to errorLog if logger is nil.
+func errorLogf(logger Logger, errorLog *log.Logger, format string, args ...interface{}) {
+	if logger != nil {
+		logger.Log(context.Background(), LogLevelError, fmt.Sprintf(format, args...))
+		return
+	}
+	serverLogf(errorLog, format, args...)
+}
+
+// typeTunnelPassword is the RFC 2868 Tunnel-Password attribute type.
+const typeTunnelPassword Type = 69
+
+// Attribute types whose values are redacted by Attributes.Redacted.
+var redactedTypes = map[Type]bool{
+	typeUserPassword:   true,
+	typeCHAPPassword:   true,
+	typeTunnelPassword: true,
+}
+
+// Redacted returns a copy of a in which the values of sensitive attributes
+// (User-Password, CHAP-Password, and Tunnel-Password) are replaced by the
+// string "<redacted>". a is not modified.
+func (a Attributes) Redacted() Attributes {
+	return a.Map(func(key Type, value Attribute) (Type, Attribute, bool) {
+		if redactedTypes[key] {
+			return key, Attribute("<redacted>"), true
+		}
+		return key, value, true
+	})
+}
+
+// redactedAttributes formats attributes as per Attributes.Redacted when an
+// event is emitted, rather than when it is logged, so that events dropped by
+// the Logger (e.g. because of their level) cost no copy of the attributes.
+type redactedAttributes Attributes
+
+func (a redactedAttributes) String() string {
+	return Attributes(a).Redacted().String()
+}
@@ -0,0 +1,143 @@
+package radius
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testLogEvent struct {
+	level   LogLevel
+	msg     string
+	keyvals []interface{}
+}
+
+func (e testLogEvent) value(key string) interface{} {
+	for i := 0; i+1 < len(e.keyvals); i += 2 {
+		if e.keyvals[i] == key {
+			return e.keyvals[i+1]
+		}
+	}
+	return nil
+}
+
+type testLogger struct {
+	mu     sync.Mutex
+	events []testLogEvent
+}
+
+func (l *testLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, testLogEvent{level, msg, keyvals})
+}
+
+func (l *testLogger) find(msg string) (testLogEvent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.events {
+		if e.msg == msg {
+			return e, true
+		}
+	}
+	return testLogEvent{}, false
+}
+
+func TestAttributesRedacted(t *testing.T) {
+	var a Attributes
+	a.Add(typeUserName, Attribute("tim"))
+	a.Add(typeUserPassword, Attribute("hunter2"))
+	a.Add(typeCHAPPassword, Attribute("\x01chap-secret"))
+
+	redacted := a.Redacted()
+	for _, typ := range []Type{typeUserPassword, typeCHAPPassword} {
+		if value := string(redacted.Get(typ)); value != "<redacted>" {
+			t.Fatalf("attribute %d not redacted: %q", typ, value)
+		}
+	}
+	if value := string(redacted.Get(typeUserName)); value != "tim" {
+		t.Fatalf("User-Name = %q; expecting %q", value, "tim")
+	}
+	if string(a.Get(typeUserPassword)) != "hunter2" {
+		t.Fatal("Redacted modified the original attributes")
+	}
+}
+
+func TestPacketServer_Logger(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverLog := &testLogger{}
+	server := PacketServer{
+		SecretSource: StaticSecretSource([]byte(`12345`)),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		Logger: serverLog,
+	}
+	go server.Serve(pc)
+
+	clientLog := &testLogger{}
+	client := &Client{Logger: clientLog}
+	packet := New(CodeAccessRequest, []byte(`12345`))
+	packet.Add(typeUserName, Attribute("tim"))
+	packet.SetUserPassword([]byte("hunter2"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Exchange(ctx, packet, pc.LocalAddr().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	wire, err := New(CodeAccountingRequest, []byte(`wrong`)).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(wire)
+
+	received, ok := serverLog.find("radius: received packet")
+	if !ok {
+		t.Fatal("missing received packet event")
+	}
+	if received.level != LogLevelDebug || received.value("code") != "Access-Request" {
+		t.Fatalf("unexpected event: %+v", received)
+	}
+	if attrs := fmt.Sprint(received.value("attributes")); !strings.Contains(attrs, fmt.Sprintf("%#x", "<redacted>")) {
+		t.Fatalf("User-Password not redacted: %s", attrs)
+	}
+	for _, msg := range []string{"radius: sent request", "radius: received response"} {
+		e, ok := clientLog.find(msg)
+		if !ok {
+			t.Fatalf("missing client event %q", msg)
+		}
+		if attrs := fmt.Sprint(e.value("attributes")); strings.Contains(attrs, "hunter2") {
+			t.Fatalf("password logged in %q: %s", msg, attrs)
+		}
+	}
+
+	var failed testLogEvent
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if failed, ok = serverLog.find("radius: packet validation failed"); ok {
+			break
+		}
+	}
+	if !ok || failed.level != LogLevelWarn || failed.value("error") != errBadSecret {
+		t.Fatalf("unexpected validation failure event: %+v", failed)
+	}
+
+	server.Shutdown(context.Background())
+	if e, ok := serverLog.find("radius: server shutting down"); !ok || e.level != LogLevelInfo {
+		t.Fatalf("missing shutdown event: %+v", e)
+	}
+}
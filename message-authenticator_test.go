@@ -0,0 +1,153 @@
+package radius
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestIsValidMessageAuthenticator_rfc(t *testing.T) {
+	// Status-Server from RFC 5997 section 6
+	secret := []byte(`xyzzy5461`)
+	request := []byte{
+		0x0c, 0xda, 0x00, 0x26, 0x8a, 0x54, 0xf4, 0x68, 0x6f, 0xb3, 0x94, 0xc5, 0x28, 0x66, 0xe3, 0x02,
+		0x18, 0x5d, 0x06, 0x23, 0x50, 0x12, 0x5a, 0x66, 0x5e, 0x2e, 0x1e, 0x84, 0x11, 0xf3, 0xe2, 0x43,
+		0x82, 0x20, 0x97, 0xc8, 0x4f, 0xa3,
+	}
+	if !IsValidMessageAuthenticator(request, nil, secret, nil) {
+		t.Fatal("expecting valid Message-Authenticator")
+	}
+	if IsValidMessageAuthenticator(request, nil, []byte(`wrong`), nil) {
+		t.Fatal("expecting invalid Message-Authenticator with wrong secret")
+	}
+}
+
+func TestPacket_Encode_messageAuthenticator(t *testing.T) {
+	secret := []byte(`12345`)
+
+	for _, code := range []Code{CodeAccessRequest, CodeAccountingRequest, CodeCoARequest} {
+		request := New(code, secret)
+		request.Add(1, Attribute(`bob`))
+		request.Add(typeMessageAuthenticator, make(Attribute, 16))
+		requestWire, err := request.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsValidMessageAuthenticator(requestWire, nil, secret, nil) {
+			t.Fatalf("%s: expecting valid Message-Authenticator", code)
+		}
+		if code != CodeAccessRequest && !IsAuthenticRequest(requestWire, secret) {
+			t.Fatalf("%s: expecting authentic request", code)
+		}
+
+		received, err := Parse(requestWire, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := received.Response(CodeAccessAccept)
+		response.Add(typeMessageAuthenticator, make(Attribute, 16))
+		responseWire, err := response.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsValidMessageAuthenticator(responseWire, requestWire[4:20], secret, nil) {
+			t.Fatalf("%s: expecting valid response Message-Authenticator", code)
+		}
+		if !IsAuthenticResponse(responseWire, requestWire, secret) {
+			t.Fatalf("%s: expecting authentic response", code)
+		}
+	}
+}
+
+func TestPacket_Encode_authAlgorithm(t *testing.T) {
+	secret := []byte(`12345`)
+	alg := NewHMACAuthAlgorithm(sha256.New)
+
+	request := New(CodeAccessRequest, secret)
+	request.AuthAlgorithm = alg
+	request.Add(typeMessageAuthenticator, make(Attribute, 16))
+	wire, err := request.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsValidMessageAuthenticator(wire, nil, secret, alg) {
+		t.Fatal("expecting valid Message-Authenticator with custom algorithm")
+	}
+	if IsValidMessageAuthenticator(wire, nil, secret, nil) {
+		t.Fatal("expecting HMAC-MD5 verification to fail")
+	}
+	if request.Response(CodeAccessAccept).AuthAlgorithm == nil {
+		t.Fatal("expecting response to inherit AuthAlgorithm")
+	}
+}
+
+func TestIsValidMessageAuthenticator_missing(t *testing.T) {
+	secret := []byte(`12345`)
+	request := New(CodeAccessRequest, secret)
+	request.Add(1, Attribute(`bob`))
+	wire, err := request.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsValidMessageAuthenticator(wire, nil, secret, nil) {
+		t.Fatal("expecting missing Message-Authenticator to be invalid")
+	}
+}
+
+func TestMessageAuthenticatorPolicy_Verify(t *testing.T) {
+	secret := []byte(`12345`)
+
+	p := New(CodeAccessRequest, secret)
+	p.Add(1, Attribute(`bob`))
+	plain, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+	signed, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed[20] != byte(typeMessageAuthenticator) {
+		t.Fatal("expecting Message-Authenticator to be added as the first attribute")
+	}
+	if len(p.Attributes) != 1 {
+		t.Fatal("expecting Encode to not modify the packet's attributes")
+	}
+	tampered := append([]byte(nil), signed...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		Policy  MessageAuthenticatorPolicy
+		Packet  []byte
+		Missing bool
+		Err     bool
+	}{
+		{MessageAuthenticatorIgnore, plain, false, false},
+		{MessageAuthenticatorIgnore, tampered, false, false},
+		{MessageAuthenticatorAdd, plain, false, false},
+		{MessageAuthenticatorAdd, signed, false, false},
+		{MessageAuthenticatorAdd, tampered, false, true},
+		{MessageAuthenticatorRequire, plain, true, true},
+		{MessageAuthenticatorRequire, signed, false, false},
+		{MessageAuthenticatorRequire, tampered, false, true},
+	}
+	for i, tt := range tests {
+		err := tt.Policy.Verify(tt.Packet, nil, secret, nil)
+		if (err != nil) != tt.Err {
+			t.Fatalf("%d: got err = %v; expecting error = %v", i, err, tt.Err)
+		}
+		if err == nil {
+			continue
+		}
+		if maErr, ok := err.(*MessageAuthenticatorError); !ok || maErr.Missing != tt.Missing {
+			t.Fatalf("%d: got err = %v; expecting *MessageAuthenticatorError (missing = %v)", i, err, tt.Missing)
+		}
+	}
+
+	acct := New(CodeAccountingRequest, secret)
+	b, _ := acct.Encode()
+	if err := MessageAuthenticatorRequire.Verify(b, nil, secret, nil); err != nil {
+		t.Fatalf("got err = %v; expecting Accounting-Request without Message-Authenticator to be accepted", err)
+	}
+}
@@ -0,0 +1,119 @@
+package radius
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"layeh.com/radius/dictionary"
+)
+
+// namedAttribute is an attribute resolved from a dictionary name.
+type namedAttribute struct {
+	*dictionary.Attribute
+	// vendor is non-nil for vendor-specific sub-attributes
+	vendor *dictionary.Vendor
+}
+
+// resolveName resolves the given attribute name using the dictionary
+// registered with RegisterDictionary. Vendor-specific attributes are named
+// "Vendor-Name:Attribute-Name".
+func resolveName(name string) (*namedAttribute, error) {
+	d := RegisteredDictionary()
+	if d == nil {
+		return nil, errors.New("radius: no dictionary registered")
+	}
+
+	vendorName, attrName := "", name
+	if i := strings.IndexByte(name, ':'); i != -1 {
+		vendorName, attrName = name[:i], name[i+1:]
+	}
+	if vendorName == "" {
+		if attr := registeredAttributeByName(attrName); attr != nil {
+			return &namedAttribute{Attribute: attr}, nil
+		}
+		return nil, errors.New("radius: unknown attribute " + strconv.Quote(name))
+	}
+
+	vendor := dictionary.VendorByName(d.Vendors, vendorName)
+	if vendor == nil {
+		return nil, errors.New("radius: unknown vendor " + strconv.Quote(vendorName))
+	}
+	if vendor.GetTypeOctets() != 1 || vendor.GetLengthOctets() != 1 {
+		return nil, errors.New("radius: unsupported vendor attribute format for " + strconv.Quote(vendorName))
+	}
+	attr := dictionary.AttributeByName(vendor.Attributes, attrName)
+	if attr == nil || len(attr.OID) != 1 || attr.OID[0] < 0 || attr.OID[0] > 255 {
+		return nil, errors.New("radius: unknown attribute " + strconv.Quote(name))
+	}
+	return &namedAttribute{Attribute: attr, vendor: vendor}, nil
+}
+
+// AddByName encodes value according to the dictionary definition of the
+// named attribute and appends it to a. Vendor-specific attributes are named
+// "Vendor-Name:Attribute-Name", and are added in their own Vendor-Specific
+// attribute.
+//
+// The dynamic type of value must be one of those listed in the TypedAVP
+// documentation for the attribute's kind, or []byte or Attribute to add a raw
+// wire value. An error is returned if no dictionary has been registered with
+// RegisterDictionary, if the name is unknown, or if value cannot be encoded.
+//
+// An error is also returned for attributes whose values the dictionary marks
+// as encrypted, such as User-Password, as they would otherwise be sent in
+// plaintext. Use Packet.SetUserPassword, or encrypt the value and use Add.
+func (a *Attributes) AddByName(name string, value interface{}) error {
+	named, err := resolveName(name)
+	if err != nil {
+		return err
+	}
+	if named.FlagEncrypt.Valid {
+		return errors.New("radius: attribute " + strconv.Quote(name) + " is encrypted")
+	}
+	attr, err := encodeValue(named.Type, named.HasTag(), 0, value)
+	if err != nil {
+		return err
+	}
+	if named.vendor != nil {
+		return a.AddVendor(uint32(named.vendor.Number), byte(named.OID[0]), attr)
+	}
+	a.Add(Type(named.OID[0]), attr)
+	return nil
+}
+
+// GetByName returns the wire value of the first attribute with the given
+// name, as per LookupByName. nil is returned if no such attribute exists in a.
+func (a *Attributes) GetByName(name string) Attribute {
+	attr, _ := a.LookupByName(name)
+	return attr
+}
+
+// LookupByName returns the wire value of the first attribute with the given
+// name. Vendor-specific attributes are named "Vendor-Name:Attribute-Name".
+// nil and false is returned if the name cannot be resolved using the
+// dictionary registered with RegisterDictionary, or if no such attribute
+// exists in a.
+func (a *Attributes) LookupByName(name string) (Attribute, bool) {
+	named, err := resolveName(name)
+	if err != nil {
+		return nil, false
+	}
+	if named.vendor != nil {
+		return a.LookupVendor(uint32(named.vendor.Number), byte(named.OID[0]))
+	}
+	return a.Lookup(Type(named.OID[0]))
+}
+
+// DelByName removes all attributes with the given name from a. It does
+// nothing if the name cannot be resolved.
+func (a *Attributes) DelByName(name string) {
+	named, err := resolveName(name)
+	if err != nil {
+		return
+	}
+	if named.vendor != nil {
+		a.DelVendor(uint32(named.vendor.Number), byte(named.OID[0]))
+		return
+	}
+	a.Del(Type(named.OID[0]))
+}
@@ -0,0 +1,80 @@
+package radius
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"layeh.com/radius/dictionary"
+)
+
+func TestAttributes_byName(t *testing.T) {
+	var a Attributes
+	if err := a.AddByName("User-Name", "bob"); err == nil {
+		t.Fatal("expecting no dictionary error")
+	}
+
+	RegisterDictionary(&dictionary.Dictionary{
+		Attributes: []*dictionary.Attribute{
+			{Name: "User-Name", OID: dictionary.OID{1}, Type: dictionary.AttributeString},
+			{Name: "User-Password", OID: dictionary.OID{2}, Type: dictionary.AttributeString, FlagEncrypt: dictionary.IntFlag{Int: 1, Valid: true}},
+			{Name: "Framed-IP-Address", OID: dictionary.OID{8}, Type: dictionary.AttributeIPAddr},
+		},
+		Vendors: []*dictionary.Vendor{
+			{
+				Name:   "Cisco",
+				Number: 9,
+				Attributes: []*dictionary.Attribute{
+					{Name: "Cisco-AVPair", OID: dictionary.OID{1}, Type: dictionary.AttributeString},
+				},
+			},
+		},
+	})
+	defer RegisterDictionary(nil)
+
+	if err := a.AddByName("Framed-IP-Address", net.IPv4(10, 0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddByName("Cisco:Cisco-AVPair", "shell:priv-lvl=15"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddByName("User-Name", Attribute(`raw`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := a.GetByName("Framed-IP-Address"); !bytes.Equal(v, []byte{10, 0, 0, 1}) {
+		t.Fatalf("got %x", v)
+	}
+	if v := a.GetByName("Cisco:Cisco-AVPair"); string(v) != "shell:priv-lvl=15" {
+		t.Fatalf("got %q", v)
+	}
+	if v := a.GetVendor(9, 1); string(v) != "shell:priv-lvl=15" {
+		t.Fatalf("got %q", v)
+	}
+	if v := a.GetByName("User-Name"); string(v) != "raw" {
+		t.Fatalf("got %q", v)
+	}
+
+	for _, name := range []string{"Unknown", "Unknown:Cisco-AVPair", "Cisco:Unknown", "Cisco-AVPair"} {
+		if err := a.AddByName(name, "x"); err == nil {
+			t.Fatalf("%s: expecting unknown name error", name)
+		}
+		if _, ok := a.LookupByName(name); ok {
+			t.Fatalf("%s: expecting lookup to fail", name)
+		}
+	}
+	for _, value := range []interface{}{"hunter2", []byte("hunter2")} {
+		if err := a.AddByName("User-Password", value); err == nil {
+			t.Fatalf("%T: expecting encrypted attribute error", value)
+		}
+	}
+	if err := a.AddByName("Framed-IP-Address", "10.0.0.1"); err == nil {
+		t.Fatal("expecting invalid value error")
+	}
+
+	a.DelByName("Cisco:Cisco-AVPair")
+	a.DelByName("User-Name")
+	if len(a) != 1 || a[0].Type != 8 {
+		t.Fatalf("unexpected attributes %v", a)
+	}
+}
@@ -11,6 +11,24 @@ import (
 // MaxPacketLength is the maximum wire length of a RADIUS packet.
 const MaxPacketLength = 4096
 
+// MaxExtendedPacketLength is the maximum wire length of a RADIUS packet that
+// can be represented in its Length field. Packets longer than MaxPacketLength
+// may only be used over transports that support them, such as TCP and TLS
+// (RFC 7930).
+const MaxExtendedPacketLength = 65535
+
+// maxPacketSize returns the maximum packet length for the limit n, which
+// defaults to MaxPacketLength if it is not positive.
+func maxPacketSize(n int) int {
+	switch {
+	case n <= 0:
+		return MaxPacketLength
+	case n > MaxExtendedPacketLength:
+		return MaxExtendedPacketLength
+	}
+	return n
+}
+
 // Packet is a RADIUS packet.
 type Packet struct {
 	Code          Code
@@ -18,6 +36,28 @@ type Packet struct {
 	Authenticator [16]byte
 	Secret        []byte
 	Attributes
+
+	// AuthAlgorithm is used to compute the Message-Authenticator attribute,
+	// if one is present. If nil, the standard HMAC-MD5 algorithm is used.
+	AuthAlgorithm AuthAlgorithm
+
+	// MessageAuthenticatorPolicy controls whether a Message-Authenticator
+	// attribute is added when the packet is encoded. If it is not
+	// MessageAuthenticatorIgnore, and the packet does not contain a
+	// Message-Authenticator, one is added as the first attribute.
+	MessageAuthenticatorPolicy MessageAuthenticatorPolicy
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of the
+	// packet when it is encoded, up to MaxExtendedPacketLength. If zero,
+	// MaxPacketLength is used.
+	MaxPacketSize int
+
+	// plaintext User-Password set with SetUserPassword
+	userPassword []byte
+
+	// wire is the packet as it was parsed, by which received packets are
+	// verified.
+	wire []byte
 }
 
 // New creates a new packet with the Code, Secret fields set to the given
@@ -43,37 +83,115 @@ func New(code Code, secret []byte) *Packet {
 // Parse parses an encoded RADIUS packet b. An error is returned if the packet
 // is malformed.
 func Parse(b, secret []byte) (*Packet, error) {
+	return ParseWith(b, secret, ParseOptions{})
+}
+
+// ParseWith parses an encoded RADIUS packet b like Parse, using the given
+// options.
+func ParseWith(b, secret []byte, opts ParseOptions) (*Packet, error) {
 	if len(b) < 20 {
 		return nil, errors.New("radius: packet not at least 20 bytes long")
 	}
 
 	length := int(binary.BigEndian.Uint16(b[2:4]))
-	if length < 20 || length > MaxPacketLength || len(b) < length {
+	if length < 20 || length > maxPacketSize(opts.MaxPacketSize) || len(b) < length {
 		return nil, errors.New("radius: invalid packet length")
 	}
 
-	attrs, err := ParseAttributes(b[20:length])
+	attrs, err := ParseAttributesWith(b[20:length], opts)
 	if err != nil {
 		return nil, err
 	}
 
+	wire := b[:length:length]
+	if !opts.NoCopy {
+		wire = append([]byte(nil), wire...)
+	}
 	packet := &Packet{
 		Code:       Code(b[0]),
 		Identifier: b[1],
 		Secret:     secret,
 		Attributes: attrs,
+		wire:       wire,
 	}
 	copy(packet.Authenticator[:], b[4:20])
 	return packet, nil
 }
 
-// Response returns a new packet that has the same identifier, secret, and
-// authenticator as the current packet.
+// Clone returns a deep copy of p, including its secret and attributes.
+func (p *Packet) Clone() *Packet {
+	q := new(Packet)
+	*q = *p
+	if p.Secret != nil {
+		q.Secret = append([]byte(nil), p.Secret...)
+	}
+	q.Attributes = p.Attributes.Clone()
+	if p.userPassword != nil {
+		q.userPassword = append([]byte(nil), p.userPassword...)
+	}
+	if p.wire != nil {
+		q.wire = append([]byte(nil), p.wire...)
+	}
+	return q
+}
+
+// effectiveMessageAuthenticatorPolicy returns p.MessageAuthenticatorPolicy,
+// raised to MessageAuthenticatorAdd for Status-Server packets, which must
+// always contain a Message-Authenticator (RFC 5997 section 3).
+func (p *Packet) effectiveMessageAuthenticatorPolicy() MessageAuthenticatorPolicy {
+	if p.Code == CodeStatusServer && p.MessageAuthenticatorPolicy < MessageAuthenticatorAdd {
+		return MessageAuthenticatorAdd
+	}
+	return p.MessageAuthenticatorPolicy
+}
+
+// typeUserPassword is the RFC 2865 User-Password attribute type.
+const typeUserPassword Type = 2
+
+// SetUserPassword sets the plaintext User-Password of the packet. The
+// password is encrypted using the packet's secret and authenticator when the
+// packet is encoded with Encode, replacing any User-Password attribute in p.
+// The encryption therefore always matches the final authenticator, even if it
+// is changed after SetUserPassword is called.
+//
+// Passing nil clears a password that was previously set.
+func (p *Packet) SetUserPassword(password []byte) {
+	if password == nil {
+		p.userPassword = nil
+		return
+	}
+	p.userPassword = append([]byte{}, password...)
+}
+
+// UserPassword returns the plaintext User-Password of the packet. If the
+// password was set with SetUserPassword, it is returned as-is. Otherwise, the
+// packet's User-Password attribute is decrypted using its secret and
+// authenticator, which is the case for a request received by a server.
+//
+// ErrNoAttribute is returned if the packet has no User-Password.
+func (p *Packet) UserPassword() ([]byte, error) {
+	if p.userPassword != nil {
+		return append([]byte(nil), p.userPassword...), nil
+	}
+	attr, ok := p.Lookup(typeUserPassword)
+	if !ok {
+		return nil, ErrNoAttribute
+	}
+	return UserPassword(attr, p.Secret, p.Authenticator[:])
+}
+
+// Response returns a new packet that has the same identifier, secret,
+// authenticator, and maximum size as the current packet. Responses to
+// Status-Server packets always include a Message-Authenticator.
 func (p *Packet) Response(code Code) *Packet {
 	q := &Packet{
-		Code:       code,
-		Identifier: p.Identifier,
-		Secret:     p.Secret,
+		Code:          code,
+		Identifier:    p.Identifier,
+		Secret:        p.Secret,
+		AuthAlgorithm: p.AuthAlgorithm,
+		MaxPacketSize: p.MaxPacketSize,
+
+		MessageAuthenticatorPolicy: p.effectiveMessageAuthenticatorPolicy(),
 	}
 	copy(q.Authenticator[:], p.Authenticator[:])
 	return q
@@ -87,24 +205,126 @@ func (p *Packet) Response(code Code) *Packet {
 // data and secret. Use MarshalBinary() to get the packet in wire
 // format without the hash calculation.
 //
+// If the packet contains a Message-Authenticator attribute, its value is
+// computed before the authenticator, using p.AuthAlgorithm. A
+// Message-Authenticator is added according to p.MessageAuthenticatorPolicy,
+// and is always added to Status-Server packets.
+// A password set with SetUserPassword is encrypted and added as the
+// User-Password attribute.
+//
 // An error is returned if the encoded packet is too long (due to its Attributes),
 // or if the packet has an unknown Code.
 func (p *Packet) Encode() ([]byte, error) {
-	b, err := p.MarshalBinary()
+	return p.encode(nil)
+}
+
+// EncodeTo encodes the RADIUS packet to wire format like Encode, writing it to
+// the start of buf rather than allocating a new slice. The length of the
+// encoded packet is returned. An error is returned if buf is too short; a
+// buffer of MaxPacketLength bytes (or p.MaxPacketSize, if set) is always large
+// enough.
+func (p *Packet) EncodeTo(buf []byte) (int, error) {
+	if buf == nil {
+		buf = []byte{}
+	}
+	b, err := p.encode(buf)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// encode encodes p into buf, or into a newly allocated slice if buf is nil.
+//
+// The password set with SetUserPassword, and any Message-Authenticator added
+// by the policy, are written directly to the encoded packet rather than to a
+// copy of p's attributes, so that EncodeTo does not allocate.
+func (p *Packet) encode(buf []byte) ([]byte, error) {
+	addMessageAuthenticator := p.effectiveMessageAuthenticatorPolicy() != MessageAuthenticatorIgnore
+	if addMessageAuthenticator {
+		_, exists := p.Lookup(typeMessageAuthenticator)
+		addMessageAuthenticator = !exists
+	}
+
+	size := 20
+	if addMessageAuthenticator {
+		size += 2 + messageAuthenticatorLen
+	}
+	if p.userPassword != nil {
+		if err := checkUserPassword(p.userPassword, p.Secret); err != nil {
+			return nil, err
+		}
+		size += 2 + userPasswordLen(p.userPassword)
+	}
+	for _, attr := range p.Attributes {
+		if p.userPassword != nil && attr.Type == typeUserPassword {
+			continue
+		}
+		n, err := attr.encodedLen()
+		if err != nil {
+			return nil, err
+		}
+		size += n
+	}
+	if size > maxPacketSize(p.MaxPacketSize) {
+		return nil, errors.New("radius: packet is too large")
+	}
+
+	var b []byte
+	if buf == nil {
+		b = make([]byte, size)
+	} else if len(buf) < size {
+		return nil, errors.New("radius: buffer too small for packet")
+	} else {
+		b = buf[:size]
+	}
+	b[0] = byte(p.Code)
+	b[1] = p.Identifier
+	binary.BigEndian.PutUint16(b[2:4], uint16(size))
+	copy(b[4:20], p.Authenticator[:])
+	rest := b[20:]
+	if addMessageAuthenticator {
+		rest[0] = byte(typeMessageAuthenticator)
+		rest[1] = 2 + messageAuthenticatorLen
+		for i := 2; i < 2+messageAuthenticatorLen; i++ {
+			rest[i] = 0
+		}
+		rest = rest[2+messageAuthenticatorLen:]
+	}
+	// Like Attributes.Set, the password replaces the first User-Password
+	// attribute, and is added after the other attributes if there is none.
+	password := p.userPassword
+	for _, attr := range p.Attributes {
+		if p.userPassword != nil && attr.Type == typeUserPassword {
+			if password != nil {
+				rest = p.encodeUserPassword(rest)
+				password = nil
+			}
+			continue
+		}
+		rest = attr.encodeTo(rest)
+	}
+	if password != nil {
+		p.encodeUserPassword(rest)
+	}
+
+	if offset := findMessageAuthenticator(b); offset != -1 {
+		authenticator := messageAuthenticatorRequest(p.Code, b)
+		if authenticator == nil {
+			authenticator = p.Authenticator[:]
+		}
+		messageAuthenticator(b[offset:offset+messageAuthenticatorLen], b, offset, authenticator, p.Secret, p.AuthAlgorithm)
 	}
 
 	switch p.Code {
 	case CodeAccessRequest, CodeStatusServer:
 		// Authenticator is sent as-is
-	case CodeAccessAccept, CodeAccessReject, CodeAccountingRequest, CodeAccountingResponse, CodeAccessChallenge, CodeDisconnectRequest, CodeDisconnectACK, CodeDisconnectNAK, CodeCoARequest, CodeCoAACK, CodeCoANAK:
+	case CodeAccessAccept, CodeAccessReject, CodeAccountingRequest, CodeAccountingResponse, CodeAccessChallenge, CodeDisconnectRequest, CodeDisconnectACK, CodeDisconnectNAK, CodeCoARequest, CodeCoAACK, CodeCoANAK, CodeProtocolError:
 		hash := md5.New()
 		hash.Write(b[:4])
 		switch p.Code {
 		case CodeAccountingRequest, CodeDisconnectRequest, CodeCoARequest:
-			var nul [16]byte
-			hash.Write(nul[:])
+			hash.Write(zeroAuthenticator[:])
 		default:
 			hash.Write(p.Authenticator[:])
 		}
@@ -118,6 +338,17 @@ func (p *Packet) Encode() ([]byte, error) {
 	return b, nil
 }
 
+// encodeUserPassword writes the User-Password attribute carrying the
+// encryption of the password set with SetUserPassword to the start of b, and
+// returns the rest of b.
+func (p *Packet) encodeUserPassword(b []byte) []byte {
+	size := 2 + userPasswordLen(p.userPassword)
+	b[0] = byte(typeUserPassword)
+	b[1] = byte(size)
+	encryptUserPassword(b[2:size], p.userPassword, p.Secret, p.Authenticator[:])
+	return b[size:]
+}
+
 // MarshalBinary returns the packet in wire format.
 //
 // The authenticator in the returned data is copied from p.Authenticator
@@ -125,21 +356,88 @@ func (p *Packet) Encode() ([]byte, error) {
 // to be sent to a RADIUS client and requires the authenticator to be
 // calculated.
 func (p *Packet) MarshalBinary() ([]byte, error) {
-	attributesLen, err := AttributesEncodedLen(p.Attributes)
+	size, err := p.encodedLen()
 	if err != nil {
 		return nil, err
 	}
+	b := make([]byte, size)
+	p.marshalTo(b)
+	return b, nil
+}
+
+// encodedLen returns the wire length of p, or an error if it is too large.
+func (p *Packet) encodedLen() (int, error) {
+	attributesLen, err := AttributesEncodedLen(p.Attributes)
+	if err != nil {
+		return 0, err
+	}
 	size := 20 + attributesLen
-	if size > MaxPacketLength {
-		return nil, errors.New("radius: packet is too large")
+	if size > maxPacketSize(p.MaxPacketSize) {
+		return 0, errors.New("radius: packet is too large")
 	}
-	b := make([]byte, size)
+	return size, nil
+}
+
+// marshalTo writes p in wire format to b, which must be exactly
+// p.encodedLen() bytes long.
+func (p *Packet) marshalTo(b []byte) {
 	b[0] = byte(p.Code)
 	b[1] = p.Identifier
-	binary.BigEndian.PutUint16(b[2:4], uint16(size))
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
 	copy(b[4:20], p.Authenticator[:])
 	p.Attributes.encodeTo(b[20:])
-	return b, nil
+}
+
+// IsAuthenticResponse returns if p, a received response, has a valid Response
+// Authenticator for the given request, using secret. The request must be the
+// packet that was sent (i.e. before it was encoded), or the request as it was
+// parsed, e.g. by a proxy.
+//
+// The response is verified as it was parsed from the wire, even if p was
+// modified afterwards. Packets that were not parsed are verified as they
+// would be marshaled by MarshalBinary.
+//
+// Client verifies responses in this way unless InsecureSkipVerify is set.
+func (p *Packet) IsAuthenticResponse(request *Packet, secret []byte) bool {
+	authenticator, ok := request.requestAuthenticator()
+	if !ok {
+		return false
+	}
+	responseWire, err := p.receivedWire()
+	if err != nil {
+		return false
+	}
+	var requestHeader [20]byte
+	copy(requestHeader[4:], authenticator)
+	return IsAuthenticResponse(responseWire, requestHeader[:], secret)
+}
+
+// requestAuthenticator returns the Request Authenticator with which p, a
+// request, was sent or received.
+func (p *Packet) requestAuthenticator() ([]byte, bool) {
+	if p.wire != nil {
+		return p.wire[4:20], true
+	}
+	switch p.Code {
+	case CodeAccountingRequest, CodeDisconnectRequest, CodeCoARequest:
+		// The authenticator is computed from the rest of the packet when
+		// it is encoded.
+		b, err := p.Encode()
+		if err != nil {
+			return nil, false
+		}
+		return b[4:20], true
+	}
+	return p.Authenticator[:], true
+}
+
+// receivedWire returns p as it was parsed, or as it is marshaled by
+// MarshalBinary if it was not parsed.
+func (p *Packet) receivedWire() ([]byte, error) {
+	if p.wire != nil {
+		return p.wire, nil
+	}
+	return p.MarshalBinary()
 }
 
 // IsAuthenticResponse returns if the given RADIUS response is an authentic
@@ -158,6 +456,23 @@ func IsAuthenticResponse(response, request, secret []byte) bool {
 	return bytes.Equal(hash.Sum(sum[:0]), response[4:20])
 }
 
+// IsAuthenticRequest returns if p, a received request, has a valid Request
+// Authenticator for its secret. This verifies the authenticator of
+// Accounting-Request, CoA-Request, and Disconnect-Request packets
+// (RFC 2866 section 3); Access-Request and Status-Server packets are always
+// considered authentic.
+//
+// The request is verified as it was parsed from the wire, even if p was
+// modified afterwards. Packets that were not parsed are verified as they
+// would be marshaled by MarshalBinary.
+func (p *Packet) IsAuthenticRequest() bool {
+	b, err := p.receivedWire()
+	if err != nil {
+		return false
+	}
+	return IsAuthenticRequest(b, p.Secret)
+}
+
 // IsAuthenticRequest returns if the given RADIUS request is an authentic
 // request using the given secret.
 func IsAuthenticRequest(request, secret []byte) bool {
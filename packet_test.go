@@ -2,6 +2,7 @@ package radius_test
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/hex"
 	"net"
 	"strings"
@@ -255,7 +256,17 @@ func TestParse_invalid(t *testing.T) {
 		Error string
 	}{
 		{"\x01", "packet not at least 20 bytes long"},
+		{
+			"\x01\xff\x00\x13\x01\x01\x01\x01\x01\x01" +
+				"\x01\x01\x01\x01\x01\x01\x01\x01\x01",
+			"packet not at least 20 bytes long",
+		},
 
+		{
+			"\x01\xff\x00\x0a\x01\x01\x01\x01\x01\x01" +
+				"\x01\x01\x01\x01\x01\x01\x01\x01\x01\x01",
+			"invalid packet length",
+		},
 		{
 			"\x01\xff\x00\x00\x01\x01\x01\x01\x01\x01" +
 				"\x01\x01\x01\x01\x01\x01\x01\x01\x01\x01",
@@ -323,3 +334,296 @@ func TestPacketMarshalBinary(t *testing.T) {
 		t.Errorf("MarshalBinary bytes != request, got %v", b)
 	}
 }
+
+func TestPacket_Clone(t *testing.T) {
+	p := radius.New(radius.CodeAccessRequest, []byte(`12345`))
+	rfc2865.UserName_SetString(p, "bob")
+
+	q := p.Clone()
+	q.Secret[0] = 'x'
+	q.Authenticator[0]++
+	rfc2865.UserName_SetString(q, "alice")
+	rfc2865.NASPort_Set(q, 5)
+
+	if string(p.Secret) != "12345" {
+		t.Fatalf("secret modified through clone: %q", p.Secret)
+	}
+	if p.Authenticator == q.Authenticator {
+		t.Fatal("authenticator shared with clone")
+	}
+	if rfc2865.UserName_GetString(p) != "bob" || len(p.Attributes) != 1 {
+		t.Fatal("attributes modified through clone")
+	}
+	if q.Identifier != p.Identifier || q.Code != p.Code {
+		t.Fatal("expecting clone header to match")
+	}
+}
+
+func TestPacket_SetUserPassword(t *testing.T) {
+	secret := []byte(`12345`)
+	p := radius.New(radius.CodeAccessRequest, secret)
+	p.Add(1, radius.Attribute(`bob`))
+	p.Add(2, radius.Attribute(`stale`))
+	p.SetUserPassword([]byte(`hunter2`))
+
+	// The password is encrypted against the authenticator used at encode time
+	p.Authenticator[0] ^= 0xff
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Attributes) != 2 || string(p.Attributes[1].Attribute) != `stale` {
+		t.Fatal("expecting Encode to not modify the packet's attributes")
+	}
+
+	received, err := radius.Parse(b, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Attributes) != 2 || len(received.Get(2)) != 16 {
+		t.Fatalf("unexpected attributes %v", received.Attributes)
+	}
+	password, err := received.UserPassword()
+	if err != nil || string(password) != `hunter2` {
+		t.Fatalf("got %q, %v; expecting hunter2", password, err)
+	}
+	if password, err := p.UserPassword(); err != nil || string(password) != `hunter2` {
+		t.Fatalf("got %q, %v; expecting hunter2", password, err)
+	}
+
+	if _, err := radius.New(radius.CodeAccessRequest, secret).UserPassword(); err != radius.ErrNoAttribute {
+		t.Fatalf("got %v; expecting radius.ErrNoAttribute", err)
+	}
+}
+
+func TestPacket_IsAuthenticResponse(t *testing.T) {
+	secret := []byte(`12345`)
+	for _, code := range []radius.Code{radius.CodeAccessRequest, radius.CodeAccountingRequest} {
+		req := radius.New(code, secret)
+		reqWire, err := req.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		received, err := radius.Parse(reqWire, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		respWire, err := received.Response(radius.CodeAccessAccept).Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := radius.Parse(respWire, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !resp.IsAuthenticResponse(req, secret) {
+			t.Fatalf("%v: expecting authentic response", code)
+		}
+		if resp.IsAuthenticResponse(req, []byte(`wrong`)) {
+			t.Fatalf("%v: expecting non-authentic response with wrong secret", code)
+		}
+		if !resp.IsAuthenticResponse(received, secret) {
+			t.Fatalf("%v: expecting authentic response to received request", code)
+		}
+
+		// the response is verified as it was received
+		resp.Add(18, radius.Attribute("added after parsing"))
+		if !resp.IsAuthenticResponse(req, secret) {
+			t.Fatalf("%v: expecting authentic response after modification", code)
+		}
+
+		respWire[4] ^= 0xff
+		resp, err = radius.Parse(respWire, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsAuthenticResponse(req, secret) {
+			t.Fatalf("%v: expecting non-authentic response with modified authenticator", code)
+		}
+
+		// the received wire is copied, so the read buffer can be reused
+		respWire[4] ^= 0xff
+		resp, err = radius.Parse(respWire, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range respWire {
+			respWire[i] = 0
+		}
+		if !resp.IsAuthenticResponse(req, secret) {
+			t.Fatalf("%v: expecting authentic response after reusing the buffer", code)
+		}
+	}
+}
+
+func TestPacket_IsAuthenticRequest(t *testing.T) {
+	secret := []byte(`12345`)
+
+	p := radius.New(radius.CodeAccountingRequest, secret)
+	rfc2865.UserName_SetString(p, "bob")
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RFC 2866 section 3: MD5(Code+Identifier+Length+16 zero octets+Attributes+Secret)
+	hash := md5.New()
+	hash.Write(b[:4])
+	hash.Write(make([]byte, 16))
+	hash.Write(b[20:])
+	hash.Write(secret)
+	if !bytes.Equal(hash.Sum(nil), b[4:20]) {
+		t.Fatal("unexpected Accounting-Request authenticator")
+	}
+
+	received, err := radius.Parse(b, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !received.IsAuthenticRequest() {
+		t.Fatal("expecting authentic request")
+	}
+	// the request is verified as it was received, not as it is re-encoded
+	rfc2865.UserName_SetString(received, "alice")
+	if !received.IsAuthenticRequest() {
+		t.Fatal("expecting authentic request after modification")
+	}
+	received.Secret = []byte(`wrong`)
+	if received.IsAuthenticRequest() {
+		t.Fatal("expecting non-authentic request with wrong secret")
+	}
+
+	// the received wire is copied, so the read buffer can be reused
+	buf := append([]byte(nil), b...)
+	received, err = radius.Parse(buf, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range buf {
+		buf[i] = 0
+	}
+	if !received.IsAuthenticRequest() {
+		t.Fatal("expecting authentic request after reusing the buffer")
+	}
+}
+
+func TestPacket_MaxPacketSize(t *testing.T) {
+	secret := []byte(`12345`)
+
+	p := radius.New(radius.CodeAccessRequest, secret)
+	for i := 0; i < 40; i++ {
+		p.Add(rfc2865.Class_Type, bytes.Repeat([]byte{byte(i)}, 250))
+	}
+	if _, err := p.Encode(); err == nil {
+		t.Fatal("expecting packet too large error")
+	}
+
+	p.MaxPacketSize = radius.MaxExtendedPacketLength
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) <= radius.MaxPacketLength {
+		t.Fatalf("got packet length %d; expecting more than %d", len(b), radius.MaxPacketLength)
+	}
+
+	if _, err := radius.Parse(b, secret); err == nil {
+		t.Fatal("expecting invalid packet length error")
+	}
+	q, err := radius.ParseWith(b, secret, radius.ParseOptions{MaxPacketSize: len(b)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(q.Attributes); n != 40 {
+		t.Fatalf("got %d attributes; expecting 40", n)
+	}
+	if r := q.Response(radius.CodeAccessChallenge); r.MaxPacketSize != 0 {
+		t.Fatalf("got response MaxPacketSize %d; expecting 0", r.MaxPacketSize)
+	}
+	q.MaxPacketSize = len(b)
+	if r := q.Response(radius.CodeAccessChallenge); r.MaxPacketSize != len(b) {
+		t.Fatalf("got response MaxPacketSize %d; expecting %d", r.MaxPacketSize, len(b))
+	}
+}
+
+func TestPacket_EncodeTo(t *testing.T) {
+	p := radius.New(radius.CodeAccountingRequest, []byte(`12345`))
+	rfc2865.UserName_SetString(p, "bob")
+	rfc2865.NASIdentifier_SetString(p, "nas")
+	expected, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, radius.MaxPacketLength)
+	n, err := p.EncodeTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], expected) {
+		t.Fatalf("got %x; expecting %x", buf[:n], expected)
+	}
+
+	if _, err := p.EncodeTo(buf[:len(expected)-1]); err == nil {
+		t.Fatal("expecting buffer too small error")
+	}
+	if _, err := p.EncodeTo(nil); err == nil {
+		t.Fatal("expecting buffer too small error")
+	}
+}
+
+func TestPacket_EncodeTo_allocs(t *testing.T) {
+	p := radius.New(radius.CodeAccessRequest, []byte(`12345`))
+	rfc2865.UserName_SetString(p, "bob")
+	rfc2865.UserPassword_SetString(p, "stale")
+	rfc2865.NASIdentifier_SetString(p, "nas")
+	p.SetUserPassword([]byte("a password longer than sixteen bytes"))
+	p.MessageAuthenticatorPolicy = radius.MessageAuthenticatorAdd
+	expected, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.Repeat([]byte{0xff}, radius.MaxPacketLength)
+	n, err := p.EncodeTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], expected) {
+		t.Fatalf("got %x; expecting %x", buf[:n], expected)
+	}
+	if !radius.IsValidMessageAuthenticator(buf[:n], nil, p.Secret, nil) {
+		t.Fatal("invalid Message-Authenticator")
+	}
+	q, err := radius.Parse(buf[:n], p.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Attributes) != 4 || q.Attributes[0].Type != rfc2869.MessageAuthenticator_Type || q.Attributes[2].Type != rfc2865.UserPassword_Type {
+		t.Fatalf("unexpected attributes %v", q.Attributes)
+	}
+	if password, err := q.UserPassword(); err != nil || string(password) != "a password longer than sixteen bytes" {
+		t.Fatalf("User-Password = %q (%v)", password, err)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { p.EncodeTo(buf) }); allocs != 0 {
+		t.Fatalf("EncodeTo allocated %v times; expecting 0", allocs)
+	}
+}
+
+func TestPacket_Encode_messageAuthenticatorLongSecret(t *testing.T) {
+	secret := bytes.Repeat([]byte(`secret`), 20)
+	for _, code := range []radius.Code{radius.CodeAccessRequest, radius.CodeAccountingRequest} {
+		p := radius.New(code, secret)
+		rfc2865.UserName_SetString(p, "bob")
+		p.MessageAuthenticatorPolicy = radius.MessageAuthenticatorAdd
+		b, err := p.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !radius.IsValidMessageAuthenticator(b, nil, secret, radius.NewHMACAuthAlgorithm(md5.New)) {
+			t.Fatalf("%v: Message-Authenticator does not match crypto/hmac", code)
+		}
+	}
+}
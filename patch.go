@@ -0,0 +1,64 @@
+package radius
+
+import (
+	"errors"
+	"strconv"
+)
+
+// PatchOp is the kind of modification a PatchOperation makes.
+type PatchOp int
+
+// Patch operation kinds.
+const (
+	// PatchSet replaces all Attributes of the given type with the value (see
+	// Attributes.Set).
+	PatchSet PatchOp = iota + 1
+	// PatchAdd appends the value (see Attributes.Add).
+	PatchAdd
+	// PatchDel removes all Attributes of the given type (see Attributes.Del).
+	PatchDel
+)
+
+// PatchOperation is a single modification in a Patch.
+type PatchOperation struct {
+	Op    PatchOp
+	Type  Type
+	Value Attribute
+}
+
+// Patch is a list of operations that can be applied to Attributes.
+type Patch []PatchOperation
+
+// ApplyPatch applies the operations in p to a, in order. Attributes that are
+// not affected by an operation keep their position and wire data.
+//
+// The patch is validated before any operation is applied; if an error is
+// returned, a is left unmodified.
+func (a *Attributes) ApplyPatch(p Patch) error {
+	for i, op := range p {
+		if op.Type < 0 || op.Type > 255 {
+			return errors.New("radius: patch operation " + strconv.Itoa(i) + " has invalid type")
+		}
+		switch op.Op {
+		case PatchSet, PatchAdd:
+			if len(op.Value) > 253 {
+				return errors.New("radius: patch operation " + strconv.Itoa(i) + " value too long")
+			}
+		case PatchDel:
+		default:
+			return errors.New("radius: patch operation " + strconv.Itoa(i) + " has unknown op")
+		}
+	}
+
+	for _, op := range p {
+		switch op.Op {
+		case PatchSet:
+			a.Set(op.Type, op.Value)
+		case PatchAdd:
+			a.Add(op.Type, op.Value)
+		case PatchDel:
+			a.Del(op.Type)
+		}
+	}
+	return nil
+}
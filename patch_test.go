@@ -0,0 +1,65 @@
+package radius
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAttributes_ApplyPatch(t *testing.T) {
+	var a Attributes
+	a.Add(1, []byte(`bob`))
+	a.Add(33, []byte(`state`))
+	a.Add(18, []byte(`hello`))
+	a.Add(4, []byte{10, 0, 0, 1})
+
+	untouched := a[1]
+
+	err := a.ApplyPatch(Patch{
+		{Op: PatchSet, Type: 1, Value: []byte(`alice`)},
+		{Op: PatchDel, Type: 18},
+		{Op: PatchAdd, Type: 25, Value: []byte(`class`)},
+	})
+	if err != nil {
+		t.Fatalf("got error %s; expecting none", err)
+	}
+
+	expected := []struct {
+		Type  Type
+		Value string
+	}{
+		{1, "alice"},
+		{33, "state"},
+		{4, "\x0a\x00\x00\x01"},
+		{25, "class"},
+	}
+	if len(a) != len(expected) {
+		t.Fatalf("got %d attributes; expecting %d", len(a), len(expected))
+	}
+	for i, e := range expected {
+		if a[i].Type != e.Type || !bytes.Equal(a[i].Attribute, []byte(e.Value)) {
+			t.Fatalf("attribute %d: got %d=%q; expecting %d=%q", i, a[i].Type, a[i].Attribute, e.Type, e.Value)
+		}
+	}
+	if a[1] != untouched {
+		t.Fatal("untouched attribute was re-created")
+	}
+}
+
+func TestAttributes_ApplyPatch_invalid(t *testing.T) {
+	var a Attributes
+	a.Add(1, []byte(`bob`))
+
+	tests := []Patch{
+		{{Op: PatchSet, Type: 1, Value: []byte(`alice`)}, {Op: 0, Type: 1}},
+		{{Op: PatchAdd, Type: 300, Value: []byte(`x`)}},
+		{{Op: PatchAdd, Type: 2, Value: make(Attribute, 254)}},
+	}
+	for i, p := range tests {
+		if err := a.ApplyPatch(p); err == nil {
+			t.Errorf("%d: expecting error", i)
+		}
+		if len(a) != 1 || !bytes.Equal(a[0].Attribute, []byte(`bob`)) {
+			t.Fatalf("%d: attributes modified by invalid patch", i)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package radius
+
+import (
+	"bytes"
+	"errors"
+)
+
+// typeProxyState is the RFC 2865 Proxy-State attribute type.
+const typeProxyState Type = 33
+
+// ErrProxyStateMismatch is returned by PopProxyState when the last
+// Proxy-State attribute of a response is not the one that was expected.
+var ErrProxyStateMismatch = errors.New("radius: Proxy-State mismatch")
+
+// PushProxyState appends a Proxy-State attribute with the given value to p, a
+// request that is being forwarded by a proxy. Per RFC 2865 section 5.33, the
+// attribute is added after any Proxy-State attributes that are already present.
+func (p *Packet) PushProxyState(state []byte) error {
+	if len(state) == 0 || len(state) > 253 {
+		return errors.New("radius: invalid Proxy-State length")
+	}
+	p.Add(typeProxyState, Attribute(append([]byte(nil), state...)))
+	return nil
+}
+
+// LastProxyState returns the value of the last Proxy-State attribute in p,
+// which is the one added by the closest proxy.
+func (p *Packet) LastProxyState() ([]byte, bool) {
+	for i := len(p.Attributes) - 1; i >= 0; i-- {
+		if p.Attributes[i].Type == typeProxyState {
+			return p.Attributes[i].Attribute, true
+		}
+	}
+	return nil, false
+}
+
+// PopProxyState verifies that the last Proxy-State attribute of p, a response
+// received by a proxy, has the given value, and removes it so that the response
+// can be forwarded. Other Proxy-State attributes are not modified.
+//
+// ErrNoAttribute is returned if p has no Proxy-State, and
+// ErrProxyStateMismatch if the last one does not match state.
+func (p *Packet) PopProxyState(state []byte) error {
+	last, ok := p.LastProxyState()
+	if !ok {
+		return ErrNoAttribute
+	}
+	if !bytes.Equal(last, state) {
+		return ErrProxyStateMismatch
+	}
+	p.DelNth(typeProxyState, -1)
+	return nil
+}
+
+// CopyProxyState appends the Proxy-State attributes of request to response, in
+// the same order, as required of servers by RFC 2865 section 5.33.
+func CopyProxyState(response, request *Packet) {
+	for _, avp := range request.Attributes {
+		if avp.Type == typeProxyState {
+			response.Add(typeProxyState, Attribute(append([]byte(nil), avp.Attribute...)))
+		}
+	}
+}
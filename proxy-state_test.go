@@ -0,0 +1,44 @@
+package radius
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPacket_ProxyState(t *testing.T) {
+	request := New(CodeAccessRequest, []byte(`secret`))
+	request.Add(typeProxyState, Attribute("nas"))
+	request.Add(typeUserName, Attribute("bob"))
+
+	if err := request.PushProxyState([]byte("proxy")); err != nil {
+		t.Fatal(err)
+	}
+	if err := request.PushProxyState(nil); err == nil {
+		t.Fatal("expecting error for empty Proxy-State")
+	}
+	if last, ok := request.LastProxyState(); !ok || string(last) != "proxy" {
+		t.Fatalf("got %q, %v; expecting proxy", last, ok)
+	}
+
+	response := request.Response(CodeAccessAccept)
+	CopyProxyState(response, request)
+	if n := response.count(typeProxyState); n != 2 {
+		t.Fatalf("got %d Proxy-State attributes; expecting 2", n)
+	}
+
+	if err := response.PopProxyState([]byte("other")); err != ErrProxyStateMismatch {
+		t.Fatalf("got %v; expecting ErrProxyStateMismatch", err)
+	}
+	if err := response.PopProxyState([]byte("proxy")); err != nil {
+		t.Fatal(err)
+	}
+	if last, _ := response.LastProxyState(); response.count(typeProxyState) != 1 || !bytes.Equal(last, []byte("nas")) {
+		t.Fatalf("got %q; expecting only nas to remain", last)
+	}
+	if err := response.PopProxyState([]byte("nas")); err != nil {
+		t.Fatal(err)
+	}
+	if err := response.PopProxyState([]byte("nas")); err != ErrNoAttribute {
+		t.Fatalf("got %v; expecting ErrNoAttribute", err)
+	}
+}
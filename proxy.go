@@ -0,0 +1,216 @@
+package radius
+
+import (
+	"crypto/rand"
+	"log"
+	"strings"
+)
+
+// typeCHAPChallenge is the RFC 2865 CHAP-Challenge attribute type.
+const typeCHAPChallenge Type = 60
+
+// ProxyRoute routes the requests of a realm to a pool of upstream servers.
+type ProxyRoute struct {
+	// Realm is the realm of the User-Names that the route matches, compared
+	// case-insensitively. A Realm starting with "*." also matches every
+	// subdomain of the rest of the realm (e.g. "*.example.com" matches
+	// "eu.example.com").
+	Realm string
+
+	// StripRealm, if true, removes the realm from the User-Name of the
+	// requests that are forwarded.
+	StripRealm bool
+
+	// Upstream is the pool of servers to which requests are forwarded, with
+	// failover between them.
+	Upstream *FailoverClient
+}
+
+// Proxy is a Handler that forwards requests to upstream servers, selected by
+// the realm of the request's User-Name, and relays their responses to the
+// client.
+//
+// The forwarded request is given a new Authenticator, and a Proxy-State
+// attribute that is verified and removed from the response. It is sent with
+// the secret of the selected upstream server, with its User-Password
+// re-encrypted, and the response is re-signed with the client's secret.
+// Other attributes that are encrypted with the secret (e.g. MS-MPPE keys or
+// Tunnel-Password) must be re-encrypted by RewriteRequest and
+// RewriteResponse.
+type Proxy struct {
+	// Routes are the routes to upstream servers; the first route whose Realm
+	// matches the request is used.
+	Routes []ProxyRoute
+
+	// Default, if non-nil, receives the requests that match no route, and
+	// those without a realm.
+	Default *ProxyRoute
+
+	// RealmSuffix is the delimiter that precedes the realm at the end of
+	// User-Names (e.g. "user@realm"). Defaults to "@".
+	RealmSuffix string
+
+	// RealmPrefix, if non-empty, is the delimiter that follows the realm at
+	// the start of User-Names (e.g. "realm/user"). A prefix realm takes
+	// precedence over a suffix realm.
+	RealmPrefix string
+
+	// RewriteRequest, if non-nil, is called with each request before it is
+	// forwarded upstream, and the request received from the client.
+	RewriteRequest func(upstream, downstream *Packet) error
+
+	// RewriteResponse, if non-nil, is called with each response before it is
+	// relayed to the client, and the response received from upstream.
+	RewriteResponse func(downstream, upstream *Packet) error
+
+	// FailureReply, if non-zero, is the code of the response (e.g.
+	// CodeAccessReject) sent to Access-Requests that match no route, or that
+	// could not be forwarded. Other such requests are dropped.
+	FailureReply Code
+
+	// ErrorLog specifies an optional logger for requests that could not be
+	// forwarded. If nil, logging is done via the log package's standard
+	// logger.
+	ErrorLog *log.Logger
+}
+
+// SplitRealm splits the User-Name name into its user and realm parts, using
+// the realm delimiters of p. An empty realm is returned if name has none.
+func (p *Proxy) SplitRealm(name string) (user, realm string) {
+	if p.RealmPrefix != "" {
+		if i := strings.Index(name, p.RealmPrefix); i > 0 {
+			return name[i+len(p.RealmPrefix):], name[:i]
+		}
+	}
+	suffix := p.RealmSuffix
+	if suffix == "" {
+		suffix = "@"
+	}
+	if i := strings.LastIndex(name, suffix); i >= 0 && i+len(suffix) < len(name) {
+		return name[:i], name[i+len(suffix):]
+	}
+	return name, ""
+}
+
+// Route returns the route of requests with the given User-Name, or nil if
+// none matches.
+func (p *Proxy) Route(name string) *ProxyRoute {
+	_, realm := p.SplitRealm(name)
+	if realm != "" {
+		for i := range p.Routes {
+			if matchRealm(p.Routes[i].Realm, realm) {
+				return &p.Routes[i]
+			}
+		}
+	}
+	return p.Default
+}
+
+func matchRealm(pattern, realm string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		domain := pattern[1:]
+		return len(realm) > len(domain) && strings.EqualFold(realm[len(realm)-len(domain):], domain)
+	}
+	return strings.EqualFold(pattern, realm)
+}
+
+func (p *Proxy) logf(format string, args ...interface{}) {
+	serverLogf(p.ErrorLog, format, args...)
+}
+
+func (p *Proxy) fail(w ResponseWriter, r *Request) {
+	if p.FailureReply != 0 && r.Code == CodeAccessRequest {
+		w.Write(r.Response(p.FailureReply))
+	}
+}
+
+// ServeRADIUS implements Handler.
+func (p *Proxy) ServeRADIUS(w ResponseWriter, r *Request) {
+	name := string(r.Get(typeUserName))
+	route := p.Route(name)
+	if route == nil || route.Upstream == nil {
+		p.logf("radius: no proxy route for %q from %v", name, r.RemoteAddr)
+		p.fail(w, r)
+		return
+	}
+
+	request, state, err := p.upstreamRequest(r, route)
+	if err != nil {
+		p.logf("radius: unable to proxy request from %v: %v", r.RemoteAddr, err)
+		p.fail(w, r)
+		return
+	}
+	response, err := route.Upstream.Exchange(r.Context(), request)
+	if err != nil {
+		p.logf("radius: unable to proxy request from %v: %v", r.RemoteAddr, err)
+		p.fail(w, r)
+		return
+	}
+	if err := response.PopProxyState(state); err != nil {
+		p.logf("radius: discarding proxied response for %v: %v", r.RemoteAddr, err)
+		p.fail(w, r)
+		return
+	}
+
+	downstream := r.Response(response.Code)
+	downstream.Attributes = response.Attributes
+	if p.RewriteResponse != nil {
+		if err := p.RewriteResponse(downstream, response); err != nil {
+			p.logf("radius: unable to relay proxied response for %v: %v", r.RemoteAddr, err)
+			p.fail(w, r)
+			return
+		}
+	}
+	w.Write(downstream)
+}
+
+// upstreamRequest returns the request to forward for r, and the value of the
+// Proxy-State added to it.
+func (p *Proxy) upstreamRequest(r *Request, route *ProxyRoute) (*Packet, []byte, error) {
+	request := &Packet{
+		Code:                       r.Code,
+		Identifier:                 r.Identifier,
+		Secret:                     r.Secret,
+		Attributes:                 append(Attributes(nil), r.Attributes...),
+		AuthAlgorithm:              r.AuthAlgorithm,
+		MessageAuthenticatorPolicy: r.MessageAuthenticatorPolicy,
+	}
+	if _, err := rand.Read(request.Authenticator[:]); err != nil {
+		return nil, nil, err
+	}
+
+	if _, ok := r.Lookup(typeUserPassword); ok {
+		password, err := r.UserPassword()
+		if err != nil {
+			return nil, nil, err
+		}
+		request.SetUserPassword(password)
+	}
+	if _, ok := r.Lookup(typeCHAPPassword); ok {
+		// The client's Authenticator is the CHAP challenge, unless one is
+		// given explicitly (RFC 2865 section 2.2).
+		if _, ok := r.Lookup(typeCHAPChallenge); !ok {
+			request.Add(typeCHAPChallenge, Attribute(append([]byte(nil), r.Authenticator[:]...)))
+		}
+	}
+	if route.StripRealm {
+		if name, ok := r.Lookup(typeUserName); ok {
+			user, _ := p.SplitRealm(string(name))
+			request.Set(typeUserName, Attribute(user))
+		}
+	}
+
+	state := make([]byte, 8)
+	if _, err := rand.Read(state); err != nil {
+		return nil, nil, err
+	}
+	if err := request.PushProxyState(state); err != nil {
+		return nil, nil, err
+	}
+	if p.RewriteRequest != nil {
+		if err := p.RewriteRequest(request, r.Packet); err != nil {
+			return nil, nil, err
+		}
+	}
+	return request, state, nil
+}
@@ -0,0 +1,107 @@
+package radius
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestProxy_Route(t *testing.T) {
+	eu := &FailoverClient{}
+	other := &FailoverClient{}
+	proxy := &Proxy{
+		Routes: []ProxyRoute{
+			{Realm: "example.com", Upstream: other},
+			{Realm: "*.example.com", Upstream: eu},
+		},
+		RealmPrefix: "/",
+	}
+	tests := []struct {
+		Name     string
+		Upstream *FailoverClient
+	}{
+		{"tim@example.com", other},
+		{"tim@EU.Example.com", eu},
+		{"example.com/tim", other},
+		{"tim@bad.com", nil},
+		{"tim", nil},
+		{"tim@", nil},
+	}
+	for _, tt := range tests {
+		route := proxy.Route(tt.Name)
+		var upstream *FailoverClient
+		if route != nil {
+			upstream = route.Upstream
+		}
+		if upstream != tt.Upstream {
+			t.Errorf("got wrong route for %q", tt.Name)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	upstreamSecret := []byte(`upstream`)
+	upstream := NewTestServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if state, ok := r.LastProxyState(); !ok || len(state) != 8 {
+			t.Error("expecting Proxy-State")
+		}
+		password, _ := r.UserPassword()
+		code := CodeAccessReject
+		if string(r.Get(typeUserName)) == "tim" && string(password) == "12345" {
+			code = CodeAccessAccept
+		}
+		resp := r.Response(code)
+		resp.Add(typeState, Attribute("upstream"))
+		CopyProxyState(resp, r.Packet)
+		w.Write(resp)
+	}), StaticSecretSource(upstreamSecret))
+	defer upstream.Close()
+
+	proxy := NewTestServer(&Proxy{
+		Routes: []ProxyRoute{
+			{
+				Realm:      "example.com",
+				StripRealm: true,
+				Upstream: &FailoverClient{
+					Client:  &Client{Retry: 100 * time.Millisecond},
+					Servers: []FailoverServer{{Addr: upstream.Addr, Secret: upstreamSecret}},
+				},
+			},
+		},
+		FailureReply: CodeAccessReject,
+		ErrorLog:     log.New(ioutil.Discard, "", 0),
+	}, StaticSecretSource([]byte(`downstream`)))
+	defer proxy.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	packet := New(CodeAccessRequest, []byte(`downstream`))
+	packet.Add(typeUserName, Attribute("tim@example.com"))
+	packet.SetUserPassword([]byte("12345"))
+	resp, err := Exchange(ctx, packet, proxy.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessAccept {
+		t.Fatalf("got code %v; expecting Access-Accept", resp.Code)
+	}
+	if string(resp.Get(typeState)) != "upstream" {
+		t.Fatal("expecting upstream attributes to be relayed")
+	}
+	if _, ok := resp.LastProxyState(); ok {
+		t.Fatal("expecting Proxy-State to be removed")
+	}
+
+	packet = New(CodeAccessRequest, []byte(`downstream`))
+	packet.Add(typeUserName, Attribute("tim@unknown.com"))
+	resp, err = Exchange(ctx, packet, proxy.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessReject {
+		t.Fatalf("got code %v for unrouted realm; expecting Access-Reject", resp.Code)
+	}
+}
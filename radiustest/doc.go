@@ -0,0 +1,5 @@
+// Package radiustest provides utilities for testing code that exchanges
+// RADIUS packets.
+//
+// API is currently unstable.
+package radiustest
@@ -0,0 +1,278 @@
+package radiustest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/dictionary"
+)
+
+// MockResponder is a radius.Handler that replies to requests with canned
+// responses.
+//
+// Rules are tried in order; the first rule that matches a request is used to
+// build the response. Requests that do not match any rule are not answered.
+type MockResponder struct {
+	Rules []*Rule
+}
+
+// Rule is a canned response returned by a MockResponder.
+type Rule struct {
+	// MatchCode, if not zero, is the code that a request must have.
+	MatchCode radius.Code
+	// MatchAttributes are the attributes that a request must contain. Each
+	// listed attribute must be equal to at least one attribute of the same
+	// type in the request.
+	MatchAttributes radius.Attributes
+
+	// Code is the code of the response packet.
+	Code radius.Code
+	// Attributes are added to the response packet.
+	Attributes radius.Attributes
+
+	encrypted map[radius.Type]bool
+}
+
+// Match returns if r matches the rule.
+func (rule *Rule) Match(r *radius.Request) bool {
+	if rule.MatchCode != 0 && r.Code != rule.MatchCode {
+		return false
+	}
+	for _, expected := range rule.MatchAttributes {
+		found := false
+		for _, avp := range r.Attributes {
+			if avp.Type != expected.Type {
+				continue
+			}
+			value := avp.Attribute
+			if rule.encrypted[avp.Type] {
+				decrypted, err := radius.UserPassword(value, r.Secret, r.Authenticator[:])
+				if err != nil {
+					continue
+				}
+				value = decrypted
+			}
+			if bytes.Equal(value, expected.Attribute) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeRADIUS implements radius.Handler.
+func (m *MockResponder) ServeRADIUS(w radius.ResponseWriter, r *radius.Request) {
+	for _, rule := range m.Rules {
+		if !rule.Match(r) {
+			continue
+		}
+		response := r.Response(rule.Code)
+		for _, avp := range rule.Attributes {
+			response.Add(avp.Type, avp.Attribute)
+		}
+		w.Write(response)
+		return
+	}
+}
+
+type jsonAttribute struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonRule struct {
+	Match struct {
+		Code       string          `json:"code"`
+		Attributes []jsonAttribute `json:"attributes"`
+	} `json:"match"`
+	Code       string          `json:"code"`
+	Attributes []jsonAttribute `json:"attributes"`
+}
+
+// LoadResponses reads a JSON list of canned responses from r and returns a
+// MockResponder that serves them. Attribute names and values are resolved
+// using d.
+//
+// The input has the following form:
+//
+//	[
+//	  {
+//	    "match": {
+//	      "code": "Access-Request",
+//	      "attributes": [{"name": "User-Name", "value": "bob"}]
+//	    },
+//	    "code": "Access-Accept",
+//	    "attributes": [{"name": "Session-Timeout", "value": 3600}]
+//	  }
+//	]
+//
+// Values are given as JSON strings or numbers, and are converted according to
+// the attribute's dictionary type. Integer attributes may also be given using
+// their dictionary VALUE names, and octets may be given in hexadecimal with a
+// "0x" prefix.
+func LoadResponses(r io.Reader, d *dictionary.Dictionary) (*MockResponder, error) {
+	if d == nil {
+		return nil, errors.New("radiustest: nil dictionary")
+	}
+
+	var rules []*jsonRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+
+	m := &MockResponder{}
+	for i, jr := range rules {
+		rule := &Rule{
+			encrypted: make(map[radius.Type]bool),
+		}
+		var err error
+		if jr.Match.Code != "" {
+			if rule.MatchCode, err = parseCode(jr.Match.Code); err != nil {
+				return nil, ruleError(i, err)
+			}
+		}
+		if rule.Code, err = parseCode(jr.Code); err != nil {
+			return nil, ruleError(i, err)
+		}
+		for _, ja := range jr.Match.Attributes {
+			attr, value, err := parseAttribute(d, ja)
+			if err != nil {
+				return nil, ruleError(i, err)
+			}
+			if attr.FlagEncrypt.Valid && attr.FlagEncrypt.Int == dictionary.EncryptUserPassword {
+				rule.encrypted[radius.Type(attr.OID[0])] = true
+			}
+			rule.MatchAttributes.Add(radius.Type(attr.OID[0]), value)
+		}
+		for _, ja := range jr.Attributes {
+			attr, value, err := parseAttribute(d, ja)
+			if err != nil {
+				return nil, ruleError(i, err)
+			}
+			if attr.FlagEncrypt.Valid {
+				return nil, ruleError(i, errors.New("encrypted attribute "+attr.Name+" not supported in responses"))
+			}
+			rule.Attributes.Add(radius.Type(attr.OID[0]), value)
+		}
+		m.Rules = append(m.Rules, rule)
+	}
+	return m, nil
+}
+
+func ruleError(i int, err error) error {
+	return errors.New("radiustest: rule " + strconv.Itoa(i) + ": " + err.Error())
+}
+
+func parseCode(s string) (radius.Code, error) {
+	for c := radius.Code(1); c <= radius.CodeReserved; c++ {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil && n > 0 {
+		return radius.Code(n), nil
+	}
+	return 0, errors.New("unknown code " + strconv.Quote(s))
+}
+
+func parseAttribute(d *dictionary.Dictionary, ja jsonAttribute) (*dictionary.Attribute, radius.Attribute, error) {
+	attr := dictionary.AttributeByName(d.Attributes, ja.Name)
+	if attr == nil || len(attr.OID) != 1 {
+		return nil, nil, errors.New("unknown attribute " + strconv.Quote(ja.Name))
+	}
+
+	var str string
+	if err := json.Unmarshal(ja.Value, &str); err != nil {
+		// Not a JSON string; use the raw token (e.g. a number).
+		str = string(ja.Value)
+	}
+
+	value, err := parseValue(d, attr, str)
+	if err != nil {
+		return nil, nil, errors.New(attr.Name + ": " + err.Error())
+	}
+	return attr, value, nil
+}
+
+func parseValue(d *dictionary.Dictionary, attr *dictionary.Attribute, s string) (radius.Attribute, error) {
+	switch attr.Type {
+	case dictionary.AttributeString:
+		return radius.NewString(s)
+	case dictionary.AttributeOctets:
+		if strings.HasPrefix(s, "0x") {
+			b, err := hex.DecodeString(s[2:])
+			if err != nil {
+				return nil, err
+			}
+			return radius.NewBytes(b)
+		}
+		return radius.NewBytes([]byte(s))
+	case dictionary.AttributeIPAddr:
+		return radius.NewIPAddr(net.ParseIP(s))
+	case dictionary.AttributeIPv6Addr:
+		return radius.NewIPv6Addr(net.ParseIP(s))
+	case dictionary.AttributeIPv6Prefix:
+		_, prefix, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		return radius.NewIPv6Prefix(prefix)
+	case dictionary.AttributeIFID:
+		addr, err := net.ParseMAC(s)
+		if err != nil {
+			return nil, err
+		}
+		return radius.NewIFID(addr)
+	case dictionary.AttributeDate:
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return radius.NewDate(t)
+		}
+		sec, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, errors.New("invalid date")
+		}
+		return radius.NewDate(time.Unix(int64(sec), 0))
+	case dictionary.AttributeInteger:
+		for _, value := range dictionary.ValuesByAttribute(d.Values, attr.Name) {
+			if value.Name == s {
+				return radius.NewInteger(uint32(value.Number)), nil
+			}
+		}
+		i, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		return radius.NewInteger(uint32(i)), nil
+	case dictionary.AttributeInteger64:
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return radius.NewInteger64(i), nil
+	case dictionary.AttributeShort:
+		i, err := strconv.ParseUint(s, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		return radius.NewShort(uint16(i)), nil
+	case dictionary.AttributeByte:
+		i, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return nil, err
+		}
+		return radius.Attribute{byte(i)}, nil
+	}
+	return nil, errors.New("unsupported attribute type " + attr.Type.String())
+}
@@ -0,0 +1,110 @@
+package radiustest_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/debug"
+	"layeh.com/radius/radiustest"
+	"layeh.com/radius/rfc2865"
+)
+
+const responses = `[
+	{
+		"match": {
+			"code": "Access-Request",
+			"attributes": [
+				{"name": "User-Name", "value": "bob"},
+				{"name": "User-Password", "value": "hunter2"}
+			]
+		},
+		"code": "Access-Accept",
+		"attributes": [
+			{"name": "Service-Type", "value": "Framed-User"},
+			{"name": "Session-Timeout", "value": 3600},
+			{"name": "Framed-IP-Address", "value": "10.0.0.5"}
+		]
+	},
+	{
+		"code": "Access-Reject",
+		"attributes": [
+			{"name": "Reply-Message", "value": "go away"}
+		]
+	}
+]`
+
+func TestLoadResponses(t *testing.T) {
+	secret := []byte(`12345`)
+
+	responder, err := radiustest.LoadResponses(strings.NewReader(responses), debug.IncludedDictionary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := radius.PacketServer{
+		SecretSource: radius.StaticSecretSource(secret),
+		Handler:      responder,
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	client := radius.Client{
+		Retry: time.Millisecond * 50,
+	}
+
+	req := radius.New(radius.CodeAccessRequest, secret)
+	rfc2865.UserName_SetString(req, "bob")
+	rfc2865.UserPassword_SetString(req, "hunter2")
+	resp, err := client.Exchange(context.Background(), req, pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != radius.CodeAccessAccept {
+		t.Fatalf("got %s; expecting Access-Accept", resp.Code)
+	}
+	if v := rfc2865.ServiceType_Get(resp); v != rfc2865.ServiceType_Value_FramedUser {
+		t.Fatalf("got Service-Type %v; expecting Framed-User", v)
+	}
+	if v := rfc2865.SessionTimeout_Get(resp); v != 3600 {
+		t.Fatalf("got Session-Timeout %v; expecting 3600", v)
+	}
+	if ip := rfc2865.FramedIPAddress_Get(resp); !ip.Equal(net.IPv4(10, 0, 0, 5)) {
+		t.Fatalf("got Framed-IP-Address %v; expecting 10.0.0.5", ip)
+	}
+
+	req = radius.New(radius.CodeAccessRequest, secret)
+	rfc2865.UserName_SetString(req, "bob")
+	rfc2865.UserPassword_SetString(req, "wrong")
+	resp, err = client.Exchange(context.Background(), req, pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != radius.CodeAccessReject {
+		t.Fatalf("got %s; expecting Access-Reject", resp.Code)
+	}
+	if msg := rfc2865.ReplyMessage_GetString(resp); msg != "go away" {
+		t.Fatalf("got Reply-Message %q; expecting %q", msg, "go away")
+	}
+}
+
+func TestLoadResponses_invalid(t *testing.T) {
+	tests := []string{
+		`[{"code": "Not-A-Code"}]`,
+		`[{"code": "Access-Accept", "attributes": [{"name": "Unknown-Attribute", "value": "x"}]}]`,
+		`[{"code": "Access-Accept", "attributes": [{"name": "Framed-IP-Address", "value": "nope"}]}]`,
+		`[{"code": "Access-Accept", "attributes": [{"name": "User-Password", "value": "x"}]}]`,
+	}
+	for _, tt := range tests {
+		if _, err := radiustest.LoadResponses(strings.NewReader(tt), debug.IncludedDictionary); err == nil {
+			t.Errorf("%s: expecting error", tt)
+		}
+	}
+}
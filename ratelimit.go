@@ -0,0 +1,196 @@
+package radius
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// RateLimiter decides whether an incoming packet from a given source should be
+// processed by a server.
+type RateLimiter interface {
+	// Allow returns true if a packet from remoteAddr may be processed. Packets
+	// that are not allowed are silently discarded.
+	Allow(remoteAddr net.Addr) bool
+}
+
+// NewTokenBucketRateLimiter returns a RateLimiter that keeps a token bucket
+// for each source IP address. The bucket holds up to burst tokens and is
+// refilled at rate tokens per second; each packet consumes one token.
+func NewTokenBucketRateLimiter(rate float64, burst int) RateLimiter {
+	return &tokenBucketRateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type tokenBucketRateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// tokenBucketPruneInterval is how often buckets that have been refilled to
+// capacity are discarded.
+const tokenBucketPruneInterval = time.Minute
+
+func (l *tokenBucketRateLimiter) Allow(remoteAddr net.Addr) bool {
+	return l.allow(addrKey(remoteAddr))
+}
+
+func (l *tokenBucketRateLimiter) allow(key string) bool {
+	_, ok := l.reserve(key, 0)
+	return ok
+}
+
+// reserve consumes a token from the bucket of key. If the bucket is empty, but
+// a token will be available within maxWait, the token is reserved and the
+// time until it is available is returned. false is returned otherwise.
+func (l *tokenBucketRateLimiter) reserve(key string, maxWait time.Duration) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) >= tokenBucketPruneInterval {
+		l.pruneLocked(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: l.burst,
+			last:   now,
+		}
+		l.buckets[key] = bucket
+	} else {
+		bucket.tokens += now.Sub(bucket.last).Seconds() * l.rate
+		if bucket.tokens > l.burst {
+			bucket.tokens = l.burst
+		}
+		bucket.last = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, true
+	}
+	if maxWait <= 0 || l.rate <= 0 {
+		return 0, false
+	}
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	if wait > maxWait {
+		return 0, false
+	}
+	bucket.tokens--
+	return wait, true
+}
+
+func (l *tokenBucketRateLimiter) pruneLocked(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// addrKey returns the host portion of addr, used to group packets by source.
+func addrKey(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.String()
+	case *net.TCPAddr:
+		return a.IP.String()
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
+
+// RateLimitAction is what RateLimitMiddleware does with requests that exceed
+// their rate.
+type RateLimitAction int
+
+// RateLimitAction values.
+const (
+	// RateLimitDrop silently discards the request.
+	RateLimitDrop RateLimitAction = iota
+
+	// RateLimitReject answers Access-Requests with an Access-Reject. Other
+	// requests are discarded.
+	RateLimitReject
+
+	// RateLimitDelay holds the request until the rate allows it, for up to
+	// MaxDelay, and discards it otherwise.
+	RateLimitDelay
+)
+
+// RateLimitOptions configure RateLimitMiddleware.
+type RateLimitOptions struct {
+	// Rate is the number of requests per second allowed for each key, and
+	// Burst the number of requests that can be made at once.
+	Rate  float64
+	Burst int
+
+	// ByUserName, if true, limits each User-Name from each source IP address
+	// separately, instead of all requests from the address together.
+	ByUserName bool
+
+	// Action is what is done with requests that exceed the rate.
+	Action RateLimitAction
+
+	// MaxDelay is the longest a request is held by RateLimitDelay.
+	MaxDelay time.Duration
+}
+
+// RateLimitMiddleware returns a Middleware that limits the rate of requests
+// from each source IP address, or each User-Name from it, using token buckets.
+//
+// Unlike PacketServer.RateLimiter, which discards packets before they are
+// parsed, the middleware can inspect the request, and can reject or delay it
+// instead of discarding it.
+func RateLimitMiddleware(opts RateLimitOptions) Middleware {
+	limiter := NewTokenBucketRateLimiter(opts.Rate, opts.Burst).(*tokenBucketRateLimiter)
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			key := addrKey(r.RemoteAddr)
+			if opts.ByUserName {
+				key += "\x00" + string(r.Get(typeUserName))
+			}
+
+			var maxWait time.Duration
+			if opts.Action == RateLimitDelay {
+				maxWait = opts.MaxDelay
+			}
+			wait, ok := limiter.reserve(key, maxWait)
+			if !ok {
+				if opts.Action == RateLimitReject && r.Code == CodeAccessRequest {
+					w.Write(r.Response(CodeAccessReject))
+				}
+				return
+			}
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				}
+			}
+			next.ServeRADIUS(w, r)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package radius
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewTokenBucketRateLimiter(1, 2).(*tokenBucketRateLimiter)
+	l.now = func() time.Time { return now }
+
+	a := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}
+	b := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1000}
+	aOtherPort := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 2000}
+
+	if !l.Allow(a) || !l.Allow(aOtherPort) {
+		t.Fatal("expecting burst to be allowed")
+	}
+	if l.Allow(a) {
+		t.Fatal("expecting packet beyond burst to be denied")
+	}
+	if !l.Allow(b) {
+		t.Fatal("expecting other source to be allowed")
+	}
+
+	now = now.Add(time.Second)
+	if !l.Allow(a) {
+		t.Fatal("expecting refilled token to be allowed")
+	}
+	if l.Allow(a) {
+		t.Fatal("expecting packet to be denied")
+	}
+
+	now = now.Add(time.Hour)
+	l.Allow(b)
+	if _, ok := l.buckets["10.0.0.1"]; ok {
+		t.Fatal("expecting idle bucket to be pruned")
+	}
+}
+
+type testResponseWriter struct {
+	responses []*Packet
+}
+
+func (w *testResponseWriter) Write(packet *Packet) error {
+	w.responses = append(w.responses, packet)
+	return nil
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	var handled int
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		handled++
+		w.Write(r.Response(CodeAccessAccept))
+	})
+	newRequest := func(name string) *Request {
+		packet := New(CodeAccessRequest, []byte(`12345`))
+		packet.Add(typeUserName, Attribute(name))
+		return &Request{
+			RemoteAddr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000},
+			Packet:     packet,
+		}
+	}
+
+	limited := RateLimitMiddleware(RateLimitOptions{
+		Rate:       0.001,
+		Burst:      1,
+		ByUserName: true,
+		Action:     RateLimitReject,
+	})(handler)
+	for _, name := range []string{"alice", "bob", "alice"} {
+		w := &testResponseWriter{}
+		limited.ServeRADIUS(w, newRequest(name))
+		if len(w.responses) != 1 {
+			t.Fatalf("%s: got %d responses; expecting 1", name, len(w.responses))
+		}
+	}
+	if handled != 2 {
+		t.Fatalf("handler called %d times; expecting 2", handled)
+	}
+
+	handled = 0
+	delayed := RateLimitMiddleware(RateLimitOptions{
+		Rate:     20,
+		Burst:    1,
+		Action:   RateLimitDelay,
+		MaxDelay: time.Second,
+	})(handler)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		delayed.ServeRADIUS(&testResponseWriter{}, newRequest("alice"))
+	}
+	if handled != 2 {
+		t.Fatalf("handler called %d times; expecting 2", handled)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("second request was not delayed (%v)", elapsed)
+	}
+
+	handled = 0
+	dropped := RateLimitMiddleware(RateLimitOptions{Rate: 0.001, Burst: 1})(handler)
+	w := &testResponseWriter{}
+	dropped.ServeRADIUS(w, newRequest("alice"))
+	dropped.ServeRADIUS(w, newRequest("alice"))
+	if handled != 1 || len(w.responses) != 1 {
+		t.Fatalf("got %d handled and %d responses; expecting 1 and 1", handled, len(w.responses))
+	}
+}
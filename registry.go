@@ -0,0 +1,122 @@
+package radius
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"layeh.com/radius/dictionary"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   *dictionary.Dictionary
+	// concat types derived from the registered dictionary and set using
+	// SetConcatType, respectively.
+	dictionaryConcat map[Type]bool
+	concatTypes      map[Type]bool
+
+	// concatSnapshot holds the union of dictionaryConcat and concatTypes, as
+	// an immutable map[Type]bool, so that isConcatType does not lock
+	// registryMu while packets are encoded and parsed.
+	concatSnapshot atomic.Value
+)
+
+// RegisterDictionary sets the dictionary that is used to resolve attribute
+// names (e.g. when encoding attributes to JSON). Passing nil unregisters the
+// current dictionary.
+//
+// The dictionary must not be modified after it has been registered.
+//
+// Top-level attributes that are marked with the "concat" flag in the
+// dictionary are handled as if they had been passed to SetConcatType.
+func RegisterDictionary(d *dictionary.Dictionary) {
+	var concat map[Type]bool
+	if d != nil {
+		for _, attr := range d.Attributes {
+			if len(attr.OID) == 1 && attr.FlagConcat.Valid && attr.FlagConcat.Bool {
+				if concat == nil {
+					concat = make(map[Type]bool)
+				}
+				concat[Type(attr.OID[0])] = true
+			}
+		}
+	}
+
+	registryMu.Lock()
+	registry = d
+	dictionaryConcat = concat
+	storeConcatSnapshotLocked()
+	registryMu.Unlock()
+}
+
+// RegisteredDictionary returns the dictionary set by RegisterDictionary, or
+// nil if no dictionary has been registered.
+func RegisteredDictionary() *dictionary.Dictionary {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry
+}
+
+// registeredAttribute returns the dictionary attribute of the given type from
+// the registered dictionary, or nil if it is not known.
+func registeredAttribute(t Type) *dictionary.Attribute {
+	d := RegisteredDictionary()
+	if d == nil {
+		return nil
+	}
+	return dictionary.AttributeByOID(d.Attributes, dictionary.OID{int(t)})
+}
+
+// registeredAttributeByName returns the dictionary attribute with the given
+// name from the registered dictionary, or nil if it is not known. Only
+// top-level (non-vendor) attributes are returned.
+func registeredAttributeByName(name string) *dictionary.Attribute {
+	d := RegisteredDictionary()
+	if d == nil {
+		return nil
+	}
+	attr := dictionary.AttributeByName(d.Attributes, name)
+	if attr == nil || len(attr.OID) != 1 {
+		return nil
+	}
+	return attr
+}
+
+// SetConcatType sets whether values of the given attribute type may be longer
+// than 253 bytes (e.g. EAP-Message, RFC 3579 section 3.1). Such values are
+// split into consecutive attributes of the same type when encoded, and
+// consecutive attributes of the type are joined back into a single value when
+// parsed.
+func SetConcatType(t Type, concat bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if concat {
+		if concatTypes == nil {
+			concatTypes = make(map[Type]bool)
+		}
+		concatTypes[t] = true
+	} else {
+		delete(concatTypes, t)
+	}
+	storeConcatSnapshotLocked()
+}
+
+// storeConcatSnapshotLocked replaces concatSnapshot with the current concat
+// types. registryMu must be held.
+func storeConcatSnapshotLocked() {
+	snapshot := make(map[Type]bool, len(concatTypes)+len(dictionaryConcat))
+	for t := range concatTypes {
+		snapshot[t] = true
+	}
+	for t := range dictionaryConcat {
+		snapshot[t] = true
+	}
+	concatSnapshot.Store(snapshot)
+}
+
+// isConcatType returns true if t was set using SetConcatType or is marked as
+// concat in the registered dictionary.
+func isConcatType(t Type) bool {
+	snapshot, _ := concatSnapshot.Load().(map[Type]bool)
+	return snapshot[t]
+}
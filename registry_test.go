@@ -0,0 +1,39 @@
+package radius
+
+import (
+	"testing"
+
+	"layeh.com/radius/dictionary"
+)
+
+func TestRegisterDictionary_concat(t *testing.T) {
+	RegisterDictionary(&dictionary.Dictionary{
+		Attributes: []*dictionary.Attribute{
+			{Name: "EAP-Message", OID: dictionary.OID{79}, Type: dictionary.AttributeOctets, FlagConcat: dictionary.BoolFlag{Bool: true, Valid: true}},
+		},
+	})
+	if !isConcatType(79) || isConcatType(1) {
+		t.Fatal("expecting only EAP-Message to be a concat type")
+	}
+	RegisterDictionary(nil)
+	if isConcatType(79) {
+		t.Fatal("expecting EAP-Message concat to be unregistered")
+	}
+}
+
+func TestSetConcatType_dictionary(t *testing.T) {
+	RegisterDictionary(&dictionary.Dictionary{
+		Attributes: []*dictionary.Attribute{
+			{Name: "EAP-Message", OID: dictionary.OID{79}, Type: dictionary.AttributeOctets, FlagConcat: dictionary.BoolFlag{Bool: true, Valid: true}},
+		},
+	})
+	SetConcatType(200, true)
+	defer SetConcatType(200, false)
+	if !isConcatType(79) || !isConcatType(200) {
+		t.Fatal("expecting both dictionary and SetConcatType concat types")
+	}
+	RegisterDictionary(nil)
+	if isConcatType(79) || !isConcatType(200) {
+		t.Fatal("expecting only the SetConcatType concat type after unregistering")
+	}
+}
@@ -0,0 +1,189 @@
+package radius
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how a Client retransmits a request that has not been
+// answered, as recommended by RFC 5080 section 2.2.1.
+//
+// Each retransmission is an identical copy of the original request, with the
+// same Identifier and Request Authenticator, so that servers can detect it as
+// a duplicate. A request whose attributes change must be sent as a new request
+// instead.
+type RetryPolicy struct {
+	// InitialTimeout is how long to wait for a response to the first
+	// transmission of the request. If zero, the InitialTimeout of
+	// DefaultRetryPolicy is used. Timeouts are never shorter than
+	// MinRetryTimeout.
+	InitialTimeout time.Duration
+
+	// Multiplier is the factor by which the timeout grows after each
+	// transmission. Values less than 1 are treated as 1.
+	Multiplier float64
+
+	// Jitter randomizes each timeout by up to the given fraction of it, in
+	// either direction (e.g. 0.1 for ±10%). It is clamped to [0, 1].
+	Jitter float64
+
+	// MaxTimeout, if positive, caps the timeout of a single transmission.
+	MaxTimeout time.Duration
+
+	// MaxRetries, if positive, is the maximum number of retransmissions.
+	MaxRetries int
+
+	// MaxElapsed, if positive, is the maximum time to wait for a response
+	// from the first transmission onwards.
+	//
+	// If neither MaxRetries nor MaxElapsed is positive, the limits of
+	// DefaultRetryPolicy are used, so that a request is never retransmitted
+	// forever.
+	MaxElapsed time.Duration
+}
+
+// MinRetryTimeout is the shortest timeout used by a RetryPolicy.
+const MinRetryTimeout = time.Millisecond
+
+// DefaultRetryPolicy contains the retransmission parameters recommended by RFC
+// 5080 section 2.2.1.
+var DefaultRetryPolicy = RetryPolicy{
+	InitialTimeout: 2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.1,
+	MaxTimeout:     16 * time.Second,
+	MaxRetries:     5,
+	MaxElapsed:     30 * time.Second,
+}
+
+// withDefaults returns a copy of r in which the zero fields that would make
+// it retransmit without waiting, or without end, are replaced by the values
+// of DefaultRetryPolicy.
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.InitialTimeout <= 0 {
+		r.InitialTimeout = DefaultRetryPolicy.InitialTimeout
+	}
+	if r.MaxRetries <= 0 && r.MaxElapsed <= 0 {
+		r.MaxRetries = DefaultRetryPolicy.MaxRetries
+		r.MaxElapsed = DefaultRetryPolicy.MaxElapsed
+	}
+	if r.Jitter < 0 {
+		r.Jitter = 0
+	} else if r.Jitter > 1 {
+		r.Jitter = 1
+	}
+	return r
+}
+
+// next returns the timeout that follows timeout, before jitter is applied.
+func (r *RetryPolicy) next(timeout time.Duration) time.Duration {
+	if r.Multiplier > 1 {
+		timeout = time.Duration(float64(timeout) * r.Multiplier)
+	}
+	if r.MaxTimeout > 0 && timeout > r.MaxTimeout {
+		timeout = r.MaxTimeout
+	}
+	return timeout
+}
+
+// jitter returns timeout randomized by r.Jitter, and no shorter than
+// MinRetryTimeout.
+func (r *RetryPolicy) jitter(timeout time.Duration) time.Duration {
+	if r.Jitter > 0 {
+		timeout += time.Duration(float64(timeout) * r.Jitter * (2*rand.Float64() - 1))
+	}
+	if timeout < MinRetryTimeout {
+		timeout = MinRetryTimeout
+	}
+	return timeout
+}
+
+// RetryAttempt describes a single transmission of a request.
+type RetryAttempt struct {
+	// Sent is when the request was transmitted.
+	Sent time.Time
+	// Timeout is how long the client waited for a response.
+	Timeout time.Duration
+}
+
+// RetryExhaustedError is returned by Client.Exchange when no response was
+// received before the limits of its RetryPolicy were reached.
+type RetryExhaustedError struct {
+	// Attempts contains each transmission of the request, in order.
+	Attempts []RetryAttempt
+	// Elapsed is the time from the first transmission until the client gave
+	// up.
+	Elapsed time.Duration
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return `radius: no response after ` + strconv.Itoa(len(e.Attempts)) + ` attempts in ` + e.Elapsed.String()
+}
+
+// retrySchedule tracks the transmissions of a request under a RetryPolicy.
+type retrySchedule struct {
+	policy   RetryPolicy
+	base     time.Duration
+	attempts []RetryAttempt
+}
+
+// schedule starts the schedule of a request that was first transmitted at
+// start, and returns it with the timeout of that transmission.
+func (r *RetryPolicy) schedule(start time.Time) (*retrySchedule, time.Duration) {
+	s := &retrySchedule{policy: r.withDefaults()}
+	s.base = s.policy.InitialTimeout
+	timeout := s.policy.jitter(s.base)
+	s.attempts = []RetryAttempt{{Sent: start, Timeout: timeout}}
+	return s, timeout
+}
+
+// next is called when the timeout of the last transmission expires, at now.
+// It returns the timeout of the retransmission to send, or a
+// *RetryExhaustedError if the policy's limits have been reached.
+func (s *retrySchedule) next(now time.Time) (time.Duration, error) {
+	r := &s.policy
+	elapsed := now.Sub(s.attempts[0].Sent)
+	if (r.MaxRetries > 0 && len(s.attempts) > r.MaxRetries) || (r.MaxElapsed > 0 && elapsed >= r.MaxElapsed) {
+		return 0, &RetryExhaustedError{
+			Attempts: s.attempts,
+			Elapsed:  elapsed,
+		}
+	}
+
+	s.base = r.next(s.base)
+	timeout := r.jitter(s.base)
+	if r.MaxElapsed > 0 && elapsed+timeout > r.MaxElapsed {
+		timeout = r.MaxElapsed - elapsed
+	}
+	s.attempts = append(s.attempts, RetryAttempt{Sent: now, Timeout: timeout})
+	return timeout, nil
+}
+
+// retransmit writes wire to w according to policy until ctx is done. If the
+// policy's limits are reached first, the error is sent on exhausted and cancel
+// is called.
+func (r *RetryPolicy) retransmit(ctx context.Context, cancel context.CancelFunc, w io.Writer, wire []byte, exhausted chan<- error) {
+	schedule, timeout := r.schedule(time.Now())
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		timeout, err := schedule.next(time.Now())
+		if err != nil {
+			exhausted <- err
+			cancel()
+			return
+		}
+		w.Write(wire)
+		timer.Reset(timeout)
+	}
+}
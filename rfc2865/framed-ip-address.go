@@ -0,0 +1,47 @@
+package rfc2865
+
+import (
+	"net"
+
+	"layeh.com/radius"
+)
+
+// FramedIPMode describes how a Framed-IP-Address value should be interpreted.
+type FramedIPMode int
+
+// Framed-IP-Address modes, as defined in RFC 2865 section 5.8.
+const (
+	// FramedIPModeAddress indicates that the value is the address to be
+	// configured for the user.
+	FramedIPModeAddress FramedIPMode = iota
+	// FramedIPModeUserSelect (255.255.255.255) indicates that the NAS should
+	// allow the user to select an address.
+	FramedIPModeUserSelect
+	// FramedIPModeNASAssign (255.255.255.254) indicates that the NAS should
+	// select an address for the user (e.g. from a pool).
+	FramedIPModeNASAssign
+)
+
+var (
+	framedIPUserSelect = net.IPv4(255, 255, 255, 255)
+	framedIPNASAssign  = net.IPv4(255, 255, 255, 254)
+)
+
+// GetFramedIPAddress returns the Framed-IP-Address of p along with how the
+// address should be interpreted. ok is false if the attribute is missing or
+// malformed.
+func GetFramedIPAddress(p *radius.Packet) (ip net.IP, mode FramedIPMode, ok bool) {
+	ip, err := FramedIPAddress_Lookup(p)
+	if err != nil {
+		return nil, FramedIPModeAddress, false
+	}
+	switch {
+	case ip.Equal(framedIPUserSelect):
+		mode = FramedIPModeUserSelect
+	case ip.Equal(framedIPNASAssign):
+		mode = FramedIPModeNASAssign
+	default:
+		mode = FramedIPModeAddress
+	}
+	return ip, mode, true
+}
@@ -0,0 +1,39 @@
+package rfc2865
+
+import (
+	"net"
+	"testing"
+
+	"layeh.com/radius"
+)
+
+func TestGetFramedIPAddress(t *testing.T) {
+	tests := []struct {
+		IP   net.IP
+		Mode FramedIPMode
+	}{
+		{net.IPv4(10, 0, 0, 1), FramedIPModeAddress},
+		{net.IPv4(255, 255, 255, 255), FramedIPModeUserSelect},
+		{net.IPv4(255, 255, 255, 254), FramedIPModeNASAssign},
+	}
+
+	for _, tt := range tests {
+		p := radius.New(radius.CodeAccessAccept, []byte(`12345`))
+		if err := FramedIPAddress_Set(p, tt.IP); err != nil {
+			t.Fatal(err)
+		}
+		ip, mode, ok := GetFramedIPAddress(p)
+		if !ok || mode != tt.Mode || !ip.Equal(tt.IP) {
+			t.Errorf("%s: got %s, %d, %v; expecting %d", tt.IP, ip, mode, ok, tt.Mode)
+		}
+	}
+
+	p := radius.New(radius.CodeAccessAccept, []byte(`12345`))
+	if _, _, ok := GetFramedIPAddress(p); ok {
+		t.Fatal("expecting missing attribute to return false")
+	}
+	p.Set(FramedIPAddress_Type, radius.Attribute{0x01})
+	if _, _, ok := GetFramedIPAddress(p); ok {
+		t.Fatal("expecting malformed attribute to return false")
+	}
+}
@@ -0,0 +1,21 @@
+package rfc2866
+
+import (
+	"layeh.com/radius"
+)
+
+// GroupByMultiSessionID groups the given accounting packets by their
+// Acct-Multi-Session-Id, which links the sessions of a multilink bundle (RFC
+// 2866 section 5.11). Packets in each group keep the order in which they were
+// given. Packets without an Acct-Multi-Session-Id are omitted.
+func GroupByMultiSessionID(packets []*radius.Packet) map[string][]*radius.Packet {
+	groups := make(map[string][]*radius.Packet)
+	for _, p := range packets {
+		id, err := AcctMultiSessionID_LookupString(p)
+		if err != nil {
+			continue
+		}
+		groups[id] = append(groups[id], p)
+	}
+	return groups
+}
@@ -0,0 +1,36 @@
+package rfc2866
+
+import (
+	"testing"
+
+	"layeh.com/radius"
+)
+
+func TestGroupByMultiSessionID(t *testing.T) {
+	newPacket := func(sessionID, multiSessionID string) *radius.Packet {
+		p := radius.New(radius.CodeAccountingRequest, []byte(`12345`))
+		AcctSessionID_SetString(p, sessionID)
+		if multiSessionID != "" {
+			AcctMultiSessionID_SetString(p, multiSessionID)
+		}
+		return p
+	}
+
+	packets := []*radius.Packet{
+		newPacket("1", "bundle-a"),
+		newPacket("2", "bundle-b"),
+		newPacket("3", "bundle-a"),
+		newPacket("4", ""),
+	}
+
+	groups := GroupByMultiSessionID(packets)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups; expecting 2", len(groups))
+	}
+	if g := groups["bundle-a"]; len(g) != 2 || AcctSessionID_GetString(g[0]) != "1" || AcctSessionID_GetString(g[1]) != "3" {
+		t.Fatalf("unexpected bundle-a group %v", g)
+	}
+	if g := groups["bundle-b"]; len(g) != 1 || AcctSessionID_GetString(g[0]) != "2" {
+		t.Fatalf("unexpected bundle-b group %v", g)
+	}
+}
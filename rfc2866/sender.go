@@ -0,0 +1,474 @@
+package rfc2866
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"layeh.com/radius"
+)
+
+// ErrQueueFull is returned by AccountingSender.Send when the sender's queue is
+// full and it has no Spool.
+var ErrQueueFull = errors.New("rfc2866: accounting queue full")
+
+// SpooledRecord is an accounting record stored in a Spool.
+type SpooledRecord struct {
+	// Packet is the encoded Accounting-Request.
+	Packet []byte
+	// Event is the time at which the accounting event occurred. The
+	// Acct-Delay-Time of the request is computed from it when it is sent.
+	Event time.Time
+}
+
+// Spool stores accounting records that could not be delivered, in the order
+// in which they were appended.
+type Spool interface {
+	// Append stores record after the other records of the spool.
+	Append(record SpooledRecord) error
+	// Peek returns the oldest record of the spool. false is returned if the
+	// spool is empty.
+	Peek() (SpooledRecord, bool, error)
+	// Remove removes the oldest record of the spool.
+	Remove() error
+}
+
+// AccountingSender reliably delivers Accounting-Requests to a server.
+//
+// Requests passed to Send are queued, and delivered one at a time by Run. If a
+// request cannot be delivered, it is retried with exponential backoff until it
+// is acknowledged by the server. If the sender has a Spool, the failed request
+// and the others in the queue are moved to the spool while the server is
+// unreachable, and are replayed from it once the server responds again.
+//
+// Every time a request is sent, its Acct-Delay-Time is updated to the number
+// of seconds since the accounting event occurred, and it is given a new
+// Identifier, as required by RFC 2866 section 5.2. Retransmissions within a
+// single exchange are left unchanged.
+type AccountingSender struct {
+	// Client is used to send the requests. If nil, a client that retransmits
+	// according to radius.DefaultRetryPolicy is used.
+	Client *radius.Client
+
+	// Addr is the address of the accounting server.
+	Addr string
+
+	// Secret is the secret shared with the server. It replaces the secret of
+	// the requests passed to Send.
+	Secret []byte
+
+	// QueueSize is the number of requests that can be queued in memory.
+	// Defaults to 1024.
+	QueueSize int
+
+	// MinBackoff is the time to wait before retrying a request that could not
+	// be delivered. It is doubled after each consecutive failure, up to
+	// MaxBackoff. Defaults to 1 second and 1 minute.
+	MinBackoff, MaxBackoff time.Duration
+
+	// Spool, if non-nil, stores requests while the server is unreachable, and
+	// when the queue is full.
+	Spool Spool
+
+	// OnError, if non-nil, is called with the errors encountered while
+	// delivering and spooling requests.
+	OnError func(err error)
+
+	once  sync.Once
+	queue chan accountingRecord
+}
+
+// accountingRecord is a queued request.
+type accountingRecord struct {
+	packet *radius.Packet
+	event  time.Time
+}
+
+var defaultAccountingClient = &radius.Client{
+	RetryPolicy:     &radius.DefaultRetryPolicy,
+	MaxPacketErrors: 10,
+}
+
+func (s *AccountingSender) getQueue() chan accountingRecord {
+	s.once.Do(func() {
+		size := s.QueueSize
+		if size <= 0 {
+			size = 1024
+		}
+		s.queue = make(chan accountingRecord, size)
+	})
+	return s.queue
+}
+
+// Send queues the given Accounting-Request for delivery. The packet is not
+// modified, and must not be modified after Send returns.
+//
+// The accounting event is assumed to have occurred Acct-Delay-Time seconds
+// ago. If the queue is full, the request is appended to the spool, or
+// ErrQueueFull is returned if the sender has none.
+func (s *AccountingSender) Send(packet *radius.Packet) error {
+	if packet.Code != radius.CodeAccountingRequest {
+		return errors.New("rfc2866: packet is not an Accounting-Request")
+	}
+	record := accountingRecord{
+		packet: packet,
+		event:  time.Now().Add(-time.Duration(AcctDelayTime_Get(packet)) * time.Second),
+	}
+	select {
+	case s.getQueue() <- record:
+		return nil
+	default:
+	}
+	if s.Spool == nil {
+		return ErrQueueFull
+	}
+	return s.spool(record)
+}
+
+// Run delivers the queued and spooled requests until ctx is canceled, and
+// returns ctx.Err(). If the sender has a Spool, the requests remaining in the
+// queue are moved to it before Run returns; otherwise, they are delivered by
+// the next call to Run.
+func (s *AccountingSender) Run(ctx context.Context) error {
+	queue := s.getQueue()
+	var pending *accountingRecord
+	var backoff time.Duration
+	for {
+		record, spooled, err := s.next(ctx, queue, pending)
+		if err != nil {
+			if ctx.Err() != nil {
+				return s.stop(ctx, queue, pending)
+			}
+			s.reportError(err)
+			backoff = s.backoff(backoff)
+			if !sleep(ctx, backoff) {
+				return s.stop(ctx, queue, pending)
+			}
+			continue
+		}
+
+		err = s.deliver(ctx, record)
+		if err == nil {
+			pending = nil
+			backoff = 0
+			if spooled {
+				if err := s.Spool.Remove(); err != nil {
+					s.reportError(err)
+				}
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			if !spooled {
+				pending = &record
+			}
+			return s.stop(ctx, queue, pending)
+		}
+		s.reportError(err)
+
+		if !spooled {
+			pending = &record
+			if s.Spool != nil && s.spool(record) == nil {
+				pending = nil
+				s.flush(queue)
+			}
+		}
+		backoff = s.backoff(backoff)
+		if !sleep(ctx, backoff) {
+			return s.stop(ctx, queue, pending)
+		}
+	}
+}
+
+// next returns the next record to deliver: the pending record that failed,
+// the oldest spooled record, or the next queued record, in that order.
+func (s *AccountingSender) next(ctx context.Context, queue chan accountingRecord, pending *accountingRecord) (accountingRecord, bool, error) {
+	if pending != nil {
+		return *pending, false, nil
+	}
+	if s.Spool != nil {
+		spooled, ok, err := s.Spool.Peek()
+		if err != nil {
+			return accountingRecord{}, false, err
+		}
+		if ok {
+			packet, err := radius.Parse(spooled.Packet, s.Secret)
+			if err != nil {
+				// The record cannot be delivered; skip it rather than
+				// blocking the spool forever.
+				if err := s.Spool.Remove(); err != nil {
+					s.reportError(err)
+				}
+				return accountingRecord{}, false, err
+			}
+			return accountingRecord{packet: packet, event: spooled.Event}, true, nil
+		}
+	}
+	select {
+	case record := <-queue:
+		return record, false, nil
+	case <-ctx.Done():
+		return accountingRecord{}, false, ctx.Err()
+	}
+}
+
+// deliver sends record to the server and waits for an Accounting-Response.
+func (s *AccountingSender) deliver(ctx context.Context, record accountingRecord) error {
+	client := s.Client
+	if client == nil {
+		client = defaultAccountingClient
+	}
+
+	var id [1]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	packet := *record.packet
+	packet.Secret = s.Secret
+	packet.Identifier = id[0]
+	packet.Attributes = append(radius.Attributes(nil), record.packet.Attributes...)
+	delay := time.Since(record.event) / time.Second
+	if delay < 0 {
+		delay = 0
+	}
+	if err := AcctDelayTime_Set(&packet, AcctDelayTime(delay)); err != nil {
+		return err
+	}
+
+	response, err := client.Exchange(ctx, &packet, s.Addr)
+	if err != nil {
+		return err
+	}
+	if response.Code != radius.CodeAccountingResponse {
+		return errors.New("rfc2866: unexpected response " + response.Code.String())
+	}
+	return nil
+}
+
+// spool appends record to the sender's spool.
+func (s *AccountingSender) spool(record accountingRecord) error {
+	packet := *record.packet
+	packet.Secret = s.Secret
+	wire, err := packet.Encode()
+	if err != nil {
+		return err
+	}
+	if err := s.Spool.Append(SpooledRecord{Packet: wire, Event: record.event}); err != nil {
+		s.reportError(err)
+		return err
+	}
+	return nil
+}
+
+// flush moves the queued records to the spool.
+func (s *AccountingSender) flush(queue chan accountingRecord) {
+	for {
+		select {
+		case record := <-queue:
+			if err := s.spool(record); err != nil {
+				// Keep the record in memory; it is retried once the
+				// server responds again.
+				select {
+				case queue <- record:
+				default:
+				}
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// stop moves the pending and queued records to the spool, if the sender has
+// one, when Run returns. Without a spool, the pending record is put back in
+// the queue if there is room.
+func (s *AccountingSender) stop(ctx context.Context, queue chan accountingRecord, pending *accountingRecord) error {
+	if s.Spool != nil {
+		if pending != nil {
+			s.spool(*pending)
+		}
+		s.flush(queue)
+	} else if pending != nil {
+		select {
+		case queue <- *pending:
+		default:
+			s.reportError(ErrQueueFull)
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *AccountingSender) backoff(previous time.Duration) time.Duration {
+	min, max := s.MinBackoff, s.MaxBackoff
+	if min <= 0 {
+		min = time.Second
+	}
+	if max <= 0 {
+		max = time.Minute
+	}
+	next := previous * 2
+	if next < min {
+		next = min
+	}
+	if next > max {
+		next = max
+	}
+	return next
+}
+
+func (s *AccountingSender) reportError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// sleep waits for d, returning false if ctx is canceled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// DirSpool is a Spool that stores each record as a file in a directory.
+//
+// The directory is listed once, when the DirSpool is first used; it must not
+// be modified by other means while the DirSpool is in use.
+type DirSpool struct {
+	// Dir is the directory in which records are stored. It must exist.
+	Dir string
+
+	mu    sync.Mutex
+	seq   uint64
+	names []string // sorted file names of the spooled records
+	init  bool
+}
+
+const dirSpoolExt = ".acct"
+
+// load lists the spooled records, if the directory has not been listed yet.
+func (d *DirSpool) load() error {
+	if d.init {
+		return nil
+	}
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasSuffix(name, dirSpoolExt) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		last := strings.TrimSuffix(names[len(names)-1], dirSpoolExt)
+		d.seq, _ = strconv.ParseUint(last, 10, 64)
+	}
+	d.names = names
+	d.init = true
+	return nil
+}
+
+// Append implements Spool.
+func (d *DirSpool) Append(record SpooledRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.load(); err != nil {
+		return err
+	}
+	d.seq++
+
+	b := make([]byte, 8, 8+len(record.Packet))
+	binary.BigEndian.PutUint64(b, uint64(record.Event.UnixNano()))
+	b = append(b, record.Packet...)
+
+	// Write to a temporary file first, so that a partial record is never
+	// read back.
+	name := fmt.Sprintf("%020d", d.seq)
+	path := filepath.Join(d.Dir, name)
+	if err := os.WriteFile(path+".tmp", b, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path+dirSpoolExt); err != nil {
+		return err
+	}
+	d.names = append(d.names, name+dirSpoolExt)
+	return nil
+}
+
+// Peek implements Spool.
+func (d *DirSpool) Peek() (SpooledRecord, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.load(); err != nil {
+		return SpooledRecord{}, false, err
+	}
+	var name string
+	var b []byte
+	for {
+		if len(d.names) == 0 {
+			return SpooledRecord{}, false, nil
+		}
+		name = d.names[0]
+		var err error
+		b, err = os.ReadFile(filepath.Join(d.Dir, name))
+		if os.IsNotExist(err) {
+			// Removed by other means; skip it.
+			d.removeFirst()
+			continue
+		}
+		if err != nil {
+			return SpooledRecord{}, false, err
+		}
+		break
+	}
+	if len(b) < 8 {
+		// Drop the corrupt record so that the spool is not blocked.
+		d.removeFirst()
+		return SpooledRecord{}, false, errors.New("rfc2866: invalid spooled record " + name)
+	}
+	record := SpooledRecord{
+		Packet: b[8:],
+		Event:  time.Unix(0, int64(binary.BigEndian.Uint64(b))),
+	}
+	return record, true, nil
+}
+
+// Remove implements Spool.
+func (d *DirSpool) Remove() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.load(); err != nil || len(d.names) == 0 {
+		return err
+	}
+	return d.removeFirst()
+}
+
+// removeFirst removes the oldest spooled record.
+func (d *DirSpool) removeFirst() error {
+	if err := os.Remove(filepath.Join(d.Dir, d.names[0])); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	d.names[0] = ""
+	d.names = d.names[1:]
+	return nil
+}
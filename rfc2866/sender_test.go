@@ -0,0 +1,151 @@
+package rfc2866
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"layeh.com/radius"
+)
+
+func TestAccountingSender(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	var mu sync.Mutex
+	var attempts int
+	delays := make(map[string]AcctDelayTime)
+	server := radius.PacketServer{
+		SecretSource: radius.StaticSecretSource(secret),
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, r *radius.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			// the server is unreachable for the first exchanges
+			if attempts++; attempts <= 4 {
+				return
+			}
+			delays[AcctSessionID_GetString(r.Packet)] = AcctDelayTime_Get(r.Packet)
+			w.Write(r.Response(radius.CodeAccountingResponse))
+		}),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	dir, err := os.MkdirTemp("", "radius-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sender := &AccountingSender{
+		Client: &radius.Client{
+			RetryPolicy: &radius.RetryPolicy{
+				InitialTimeout: 10 * time.Millisecond,
+				MaxRetries:     1,
+			},
+		},
+		Addr:       pc.LocalAddr().String(),
+		Secret:     secret,
+		MinBackoff: 10 * time.Millisecond,
+		Spool:      &DirSpool{Dir: dir},
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		packet := radius.New(radius.CodeAccountingRequest, []byte(`other`))
+		AcctSessionID_SetString(packet, id)
+		AcctDelayTime_Set(packet, 5)
+		if err := sender.Send(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.Run(ctx)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(delays)
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d delivered records; expecting 3", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("got %v; expecting context.Canceled", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, delay := range delays {
+		if delay < 5 {
+			t.Errorf("got Acct-Delay-Time %d for %q; expecting at least 5", delay, id)
+		}
+	}
+	if _, ok, err := sender.Spool.Peek(); ok || err != nil {
+		t.Fatalf("spool not empty (err = %v)", err)
+	}
+}
+
+func TestDirSpool(t *testing.T) {
+	dir, err := os.MkdirTemp("", "radius-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	event := time.Unix(1500000000, 0)
+	spool := &DirSpool{Dir: dir}
+	for _, b := range []string{"first", "second"} {
+		if err := spool.Append(SpooledRecord{Packet: []byte(b), Event: event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new spool on the same directory continues after the existing records
+	spool = &DirSpool{Dir: dir}
+	if err := spool.Append(SpooledRecord{Packet: []byte("third"), Event: event}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"first", "second", "third"} {
+		record, ok, err := spool.Peek()
+		if err != nil || !ok {
+			t.Fatalf("got ok = %v, err = %v; expecting record", ok, err)
+		}
+		if string(record.Packet) != expected || !record.Event.Equal(event) {
+			t.Fatalf("got %q at %v; expecting %q at %v", record.Packet, record.Event, expected, event)
+		}
+		if err := spool.Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok, _ := spool.Peek(); ok {
+		t.Fatal("expecting empty spool")
+	}
+
+	// records whose files were removed by other means are skipped
+	for _, b := range []string{"fourth", "fifth"} {
+		if err := spool.Append(SpooledRecord{Packet: []byte(b), Event: event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, spool.names[0])); err != nil {
+		t.Fatal(err)
+	}
+	if record, ok, err := spool.Peek(); err != nil || !ok || string(record.Packet) != "fifth" {
+		t.Fatalf("got %q, ok = %v, err = %v; expecting %q", record.Packet, ok, err, "fifth")
+	}
+}
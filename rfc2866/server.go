@@ -0,0 +1,141 @@
+package rfc2866
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	"layeh.com/radius/rfc2869"
+)
+
+// Record holds the standard attributes of an Accounting-Request, decoded by
+// AccountingHandler. Attributes that are absent from the request have their
+// zero value.
+type Record struct {
+	// Request is the Accounting-Request.
+	Request *radius.Request
+
+	StatusType     AcctStatusType
+	SessionID      string
+	MultiSessionID string
+	Authentic      AcctAuthentic
+	TerminateCause AcctTerminateCause
+
+	UserName      string
+	NASIPAddress  net.IP
+	NASIdentifier string
+
+	// DelayTime is the Acct-Delay-Time of the request, and EventTime the
+	// time at which the accounting event occurred: the Event-Timestamp of
+	// the request, if present, or the time the request was received minus
+	// DelayTime.
+	DelayTime time.Duration
+	EventTime time.Time
+
+	SessionTime time.Duration
+
+	// InputOctets and OutputOctets include the Acct-Input-Gigawords and
+	// Acct-Output-Gigawords of the request (RFC 2869 section 5.1).
+	InputOctets   uint64
+	OutputOctets  uint64
+	InputPackets  uint32
+	OutputPackets uint32
+}
+
+// NewRecord decodes the standard attributes of the Accounting-Request r,
+// received at the given time.
+func NewRecord(r *radius.Request, received time.Time) *Record {
+	p := r.Packet
+	record := &Record{
+		Request:        r,
+		StatusType:     AcctStatusType_Get(p),
+		SessionID:      AcctSessionID_GetString(p),
+		MultiSessionID: AcctMultiSessionID_GetString(p),
+		Authentic:      AcctAuthentic_Get(p),
+		TerminateCause: AcctTerminateCause_Get(p),
+		UserName:       rfc2865.UserName_GetString(p),
+		NASIPAddress:   rfc2865.NASIPAddress_Get(p),
+		NASIdentifier:  rfc2865.NASIdentifier_GetString(p),
+		DelayTime:      time.Duration(AcctDelayTime_Get(p)) * time.Second,
+		SessionTime:    time.Duration(AcctSessionTime_Get(p)) * time.Second,
+		InputOctets:    uint64(rfc2869.AcctInputGigawords_Get(p))<<32 | uint64(AcctInputOctets_Get(p)),
+		OutputOctets:   uint64(rfc2869.AcctOutputGigawords_Get(p))<<32 | uint64(AcctOutputOctets_Get(p)),
+		InputPackets:   uint32(AcctInputPackets_Get(p)),
+		OutputPackets:  uint32(AcctOutputPackets_Get(p)),
+	}
+	if timestamp, err := rfc2869.EventTimestamp_Lookup(p); err == nil {
+		record.EventTime = timestamp
+	} else {
+		record.EventTime = received.Add(-record.DelayTime)
+	}
+	return record
+}
+
+// AccountingFunc is called by AccountingHandler with the decoded record of
+// an Accounting-Request.
+type AccountingFunc func(ctx context.Context, record *Record) error
+
+// AccountingHandler is a radius.Handler for accounting servers. It decodes
+// each Accounting-Request into a Record, passes it to the callback for its
+// Acct-Status-Type, and acknowledges it with an Accounting-Response once the
+// callback returns nil.
+//
+// When a callback returns an error, no response is sent, so that the NAS
+// retransmits the request later (RFC 2866 section 2). Requests whose
+// Acct-Status-Type has no callback are acknowledged without being processed.
+// Packets other than Accounting-Requests are discarded.
+//
+// The Request Authenticator of Accounting-Requests is verified by the
+// server before the handler is called (see radius.IsAuthenticRequest).
+type AccountingHandler struct {
+	OnStart         AccountingFunc
+	OnStop          AccountingFunc
+	OnInterimUpdate AccountingFunc
+	OnAccountingOn  AccountingFunc
+	OnAccountingOff AccountingFunc
+
+	// OnOther, if non-nil, is called for other Acct-Status-Types (e.g.
+	// Failed), and for requests without one.
+	OnOther AccountingFunc
+
+	// OnError, if non-nil, is called with the errors returned by the
+	// callbacks.
+	OnError func(record *Record, err error)
+}
+
+func (h *AccountingHandler) callback(statusType AcctStatusType) AccountingFunc {
+	switch statusType {
+	case AcctStatusType_Value_Start:
+		return h.OnStart
+	case AcctStatusType_Value_Stop:
+		return h.OnStop
+	case AcctStatusType_Value_InterimUpdate:
+		return h.OnInterimUpdate
+	case AcctStatusType_Value_AccountingOn:
+		return h.OnAccountingOn
+	case AcctStatusType_Value_AccountingOff:
+		return h.OnAccountingOff
+	}
+	return h.OnOther
+}
+
+// ServeRADIUS implements radius.Handler.
+func (h *AccountingHandler) ServeRADIUS(w radius.ResponseWriter, r *radius.Request) {
+	if r.Code != radius.CodeAccountingRequest {
+		return
+	}
+	record := NewRecord(r, time.Now())
+	if fn := h.callback(record.StatusType); fn != nil {
+		if err := fn(r.Context(), record); err != nil {
+			if h.OnError != nil {
+				h.OnError(record, err)
+			}
+			return
+		}
+	}
+	response := r.Response(radius.CodeAccountingResponse)
+	radius.CopyProxyState(response, r.Packet)
+	w.Write(response)
+}
@@ -0,0 +1,83 @@
+package rfc2866
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	"layeh.com/radius/rfc2869"
+)
+
+type testResponseWriter struct {
+	responses []*radius.Packet
+}
+
+func (w *testResponseWriter) Write(packet *radius.Packet) error {
+	w.responses = append(w.responses, packet)
+	return nil
+}
+
+func TestAccountingHandler(t *testing.T) {
+	var stopped *Record
+	failStart := true
+	handler := &AccountingHandler{
+		OnStart: func(ctx context.Context, record *Record) error {
+			if failStart {
+				return errors.New("database unavailable")
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context, record *Record) error {
+			stopped = record
+			return nil
+		},
+	}
+
+	newRequest := func(statusType AcctStatusType) *radius.Request {
+		p := radius.New(radius.CodeAccountingRequest, []byte(`12345`))
+		AcctStatusType_Set(p, statusType)
+		AcctSessionID_SetString(p, "session")
+		rfc2865.UserName_SetString(p, "tim")
+		AcctDelayTime_Set(p, 10)
+		AcctInputOctets_Set(p, 5)
+		rfc2869.AcctInputGigawords_Set(p, 1)
+		return &radius.Request{Packet: p}
+	}
+
+	w := &testResponseWriter{}
+	handler.ServeRADIUS(w, newRequest(AcctStatusType_Value_Start))
+	if len(w.responses) != 0 {
+		t.Fatal("expecting no response when the callback fails")
+	}
+	failStart = false
+	handler.ServeRADIUS(w, newRequest(AcctStatusType_Value_Start))
+	if len(w.responses) != 1 || w.responses[0].Code != radius.CodeAccountingResponse {
+		t.Fatalf("got responses %v; expecting Accounting-Response", w.responses)
+	}
+
+	before := time.Now()
+	handler.ServeRADIUS(w, newRequest(AcctStatusType_Value_Stop))
+	if len(w.responses) != 2 || stopped == nil {
+		t.Fatal("expecting Stop to be handled and acknowledged")
+	}
+	if stopped.SessionID != "session" || stopped.UserName != "tim" || stopped.InputOctets != 1<<32|5 {
+		t.Fatalf("unexpected record %+v", stopped)
+	}
+	if stopped.DelayTime != 10*time.Second || stopped.EventTime.After(before.Add(-10*time.Second+time.Second)) {
+		t.Fatalf("got event time %v; expecting 10 seconds before %v", stopped.EventTime, before)
+	}
+
+	// Status types without a callback are acknowledged.
+	handler.ServeRADIUS(w, newRequest(AcctStatusType_Value_AccountingOn))
+	if len(w.responses) != 3 {
+		t.Fatal("expecting Accounting-On to be acknowledged")
+	}
+
+	handler.ServeRADIUS(w, &radius.Request{Packet: radius.New(radius.CodeAccessRequest, []byte(`12345`))})
+	if len(w.responses) != 3 {
+		t.Fatal("expecting Access-Request to be discarded")
+	}
+}
@@ -87,7 +87,7 @@ func TunnelType_Gets(p *radius.Packet) (tags []byte, values []TunnelType, err er
 		var tag byte
 		if len(attr) >= 1 && attr[0] <= 0x1F {
 			tag = attr[0]
-			attr[0] = 0x00
+			attr = append(radius.Attribute{0x00}, attr[1:]...)
 		}
 		i, err = radius.Integer(attr)
 		if err != nil {
@@ -107,7 +107,7 @@ func TunnelType_Lookup(p *radius.Packet) (tag byte, value TunnelType, err error)
 	}
 	if len(a) >= 1 && a[0] <= 0x1F {
 		tag = a[0]
-		a[0] = 0x00
+		a = append(radius.Attribute{0x00}, a[1:]...)
 	}
 	var i uint32
 	i, err = radius.Integer(a)
@@ -204,7 +204,7 @@ func TunnelMediumType_Gets(p *radius.Packet) (tags []byte, values []TunnelMedium
 		var tag byte
 		if len(attr) >= 1 && attr[0] <= 0x1F {
 			tag = attr[0]
-			attr[0] = 0x00
+			attr = append(radius.Attribute{0x00}, attr[1:]...)
 		}
 		i, err = radius.Integer(attr)
 		if err != nil {
@@ -224,7 +224,7 @@ func TunnelMediumType_Lookup(p *radius.Packet) (tag byte, value TunnelMediumType
 	}
 	if len(a) >= 1 && a[0] <= 0x1F {
 		tag = a[0]
-		a[0] = 0x00
+		a = append(radius.Attribute{0x00}, a[1:]...)
 	}
 	var i uint32
 	i, err = radius.Integer(a)
@@ -1009,7 +1009,7 @@ func TunnelPreference_Gets(p *radius.Packet) (tags []byte, values []TunnelPrefer
 		var tag byte
 		if len(attr) >= 1 && attr[0] <= 0x1F {
 			tag = attr[0]
-			attr[0] = 0x00
+			attr = append(radius.Attribute{0x00}, attr[1:]...)
 		}
 		i, err = radius.Integer(attr)
 		if err != nil {
@@ -1029,7 +1029,7 @@ func TunnelPreference_Lookup(p *radius.Packet) (tag byte, value TunnelPreference
 	}
 	if len(a) >= 1 && a[0] <= 0x1F {
 		tag = a[0]
-		a[0] = 0x00
+		a = append(radius.Attribute{0x00}, a[1:]...)
 	}
 	var i uint32
 	i, err = radius.Integer(a)
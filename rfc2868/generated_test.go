@@ -154,3 +154,23 @@ func Test_Tags(t *testing.T) {
 		}
 	}
 }
+
+func Test_TunnelPreferenceLookup_preservesTag(t *testing.T) {
+	p := radius.New(radius.CodeAccessAccept, []byte("secretly"))
+	if err := TunnelPreference_Add(p, 3, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		tag, value, err := TunnelPreference_Lookup(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag != 3 || value != 10 {
+			t.Fatalf("lookup %d: got tag %d, value %d; expecting 3, 10", i, tag, value)
+		}
+	}
+	if tag, ok := p.PreferredTunnel(); !ok || tag != 3 {
+		t.Fatalf("got preferred tunnel %d, %v; expecting 3, true", tag, ok)
+	}
+}
@@ -0,0 +1,35 @@
+package rfc3162
+
+import (
+	"errors"
+	"net"
+
+	"layeh.com/radius"
+)
+
+// GetNASIPv6 returns the NAS-IPv6-Address of p. false is returned if the
+// attribute is missing, is not 16 bytes long, or contains an IPv4-mapped
+// address.
+//
+// NASIPv6Address_Get can be used to accept IPv4-mapped addresses.
+func GetNASIPv6(p *radius.Packet) (net.IP, bool) {
+	ip, err := NASIPv6Address_Lookup(p)
+	if err != nil || ip.To4() != nil {
+		return nil, false
+	}
+	return ip, true
+}
+
+// SetNASIPv6 sets the NAS-IPv6-Address of p. An error is returned if ip is not
+// a 16 byte address, or if it is an IPv4-mapped address.
+//
+// NASIPv6Address_Set can be used to send IPv4-mapped addresses.
+func SetNASIPv6(p *radius.Packet, ip net.IP) error {
+	if len(ip) != net.IPv6len {
+		return errors.New("NAS-IPv6-Address must be 16 bytes long")
+	}
+	if ip.To4() != nil {
+		return errors.New("NAS-IPv6-Address must not be an IPv4-mapped address")
+	}
+	return NASIPv6Address_Set(p, ip)
+}
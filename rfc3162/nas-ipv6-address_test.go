@@ -0,0 +1,35 @@
+package rfc3162
+
+import (
+	"net"
+	"testing"
+
+	"layeh.com/radius"
+)
+
+func TestNASIPv6(t *testing.T) {
+	p := radius.New(radius.CodeAccessRequest, []byte(`12345`))
+	if _, ok := GetNASIPv6(p); ok {
+		t.Fatal("expecting missing attribute to return false")
+	}
+
+	ip := net.ParseIP("2001:db8::1")
+	if err := SetNASIPv6(p, ip); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := GetNASIPv6(p); !ok || !got.Equal(ip) {
+		t.Fatalf("got %s, %v; expecting %s, true", got, ok, ip)
+	}
+
+	if err := SetNASIPv6(p, net.ParseIP("10.0.0.1")); err == nil {
+		t.Fatal("expecting IPv4-mapped address to be rejected")
+	}
+	if err := SetNASIPv6(p, net.IPv4(10, 0, 0, 1).To4()); err == nil {
+		t.Fatal("expecting IPv4 address to be rejected")
+	}
+
+	NASIPv6Address_Set(p, net.ParseIP("10.0.0.1"))
+	if _, ok := GetNASIPv6(p); ok {
+		t.Fatal("expecting IPv4-mapped address to return false")
+	}
+}
@@ -0,0 +1,14 @@
+package rfc3576
+
+import (
+	"layeh.com/radius"
+)
+
+// AuthorizeOnlyNAK returns a NAK response to the given CoA-Request or
+// Disconnect-Request, with its Error-Cause set to cause. It is intended for
+// servers that receive a Service-Type of Authorize-Only but are unable to
+// re-authorize the session; ErrorCause_Value_UnsupportedService is the cause
+// that RFC 5176 section 3.1 specifies for NASes that do not support it.
+func AuthorizeOnlyNAK(request *radius.Packet, cause ErrorCause) *radius.Packet {
+	return NAK(request, cause)
+}
@@ -0,0 +1,39 @@
+package rfc3576
+
+import (
+	"testing"
+
+	"layeh.com/radius"
+	. "layeh.com/radius/rfc2865"
+)
+
+func TestAuthorizeOnly(t *testing.T) {
+	request := radius.New(radius.CodeCoARequest, []byte(`12345`))
+	if request.IsAuthorizeOnly() {
+		t.Fatal("expecting request without Service-Type not to be Authorize-Only")
+	}
+	ServiceType_Set(request, ServiceType_Value_FramedUser)
+	if request.IsAuthorizeOnly() {
+		t.Fatal("expecting Framed-User not to be Authorize-Only")
+	}
+	ServiceType_Set(request, ServiceType_Value_AuthorizeOnly)
+	if !request.IsAuthorizeOnly() {
+		t.Fatal("expecting request to be Authorize-Only")
+	}
+
+	response := AuthorizeOnlyNAK(request, ErrorCause_Value_UnsupportedService)
+	if response.Code != radius.CodeCoANAK {
+		t.Fatalf("got %s; expecting CoA-NAK", response.Code)
+	}
+	if response.Identifier != request.Identifier {
+		t.Fatal("expecting response identifier to match request")
+	}
+	if cause := ErrorCause_Get(response); cause != ErrorCause_Value_UnsupportedService {
+		t.Fatalf("got Error-Cause %s; expecting Unsupported-Service", cause)
+	}
+
+	request.Code = radius.CodeDisconnectRequest
+	if response := AuthorizeOnlyNAK(request, ErrorCause_Value_UnsupportedService); response.Code != radius.CodeDisconnectNAK {
+		t.Fatalf("got %s; expecting Disconnect-NAK", response.Code)
+	}
+}
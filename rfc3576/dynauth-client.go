@@ -0,0 +1,63 @@
+package rfc3576
+
+import (
+	"context"
+
+	"layeh.com/radius"
+)
+
+// DynAuthClient sends Dynamic Authorization requests (RFC 5176) to NASes.
+type DynAuthClient struct {
+	// Client is used to exchange packets with the NASes. If nil, a client
+	// that retransmits according to radius.DefaultRetryPolicy is used.
+	Client *radius.Client
+
+	// Secret is the secret shared with the NASes.
+	Secret []byte
+}
+
+var defaultDynAuthClient = &radius.Client{
+	RetryPolicy:     &radius.DefaultRetryPolicy,
+	MaxPacketErrors: 10,
+}
+
+// NewCoARequest returns a new CoA-Request packet with the client's secret.
+func (c *DynAuthClient) NewCoARequest() *radius.Packet {
+	return radius.New(radius.CodeCoARequest, c.Secret)
+}
+
+// NewDisconnectRequest returns a new Disconnect-Request packet with the
+// client's secret.
+func (c *DynAuthClient) NewDisconnectRequest() *radius.Packet {
+	return radius.New(radius.CodeDisconnectRequest, c.Secret)
+}
+
+// Exchange sends the given CoA-Request or Disconnect-Request to the NAS at
+// addr and waits for a response, in the same way as the Exchange function.
+// The request is retransmitted, with the same Identifier and Authenticator,
+// until a response is received or the client's retry policy is exhausted.
+//
+// If the NAS responds with a NAK, a *NAKError containing its Error-Cause is
+// returned.
+func (c *DynAuthClient) Exchange(ctx context.Context, packet *radius.Packet, addr string) (*radius.Packet, error) {
+	client := c.Client
+	if client == nil {
+		client = defaultDynAuthClient
+	}
+	return Exchange(ctx, client, packet, addr)
+}
+
+// CoA sends a CoA-Request containing attributes to the NAS at addr.
+func (c *DynAuthClient) CoA(ctx context.Context, addr string, attributes radius.Attributes) (*radius.Packet, error) {
+	packet := c.NewCoARequest()
+	packet.Attributes = append(packet.Attributes, attributes...)
+	return c.Exchange(ctx, packet, addr)
+}
+
+// Disconnect sends a Disconnect-Request containing attributes to the NAS at
+// addr.
+func (c *DynAuthClient) Disconnect(ctx context.Context, addr string, attributes radius.Attributes) (*radius.Packet, error) {
+	packet := c.NewDisconnectRequest()
+	packet.Attributes = append(packet.Attributes, attributes...)
+	return c.Exchange(ctx, packet, addr)
+}
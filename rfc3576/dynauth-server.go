@@ -0,0 +1,153 @@
+package rfc3576
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+// Error implements error, so that an ErrorCause can be returned by a
+// DynAuthFunc to NAK the request with that cause.
+func (a ErrorCause) Error() string {
+	return `rfc3576: ` + a.String()
+}
+
+// DynAuthFunc processes a CoA-Request or Disconnect-Request received by a
+// DynAuthServer. The request is acknowledged if it returns nil. Otherwise, it
+// is answered with a NAK, whose Error-Cause is the returned error if it is, or
+// wraps, an ErrorCause.
+type DynAuthFunc func(ctx context.Context, r *radius.Request) error
+
+// DynAuthServer receives Dynamic Authorization requests (RFC 5176) on a NAS,
+// and answers them with an ACK or a NAK.
+//
+// The Request Authenticator of each request is verified before it is passed
+// to OnCoA or OnDisconnect. Requests of a kind without a callback are NAKed
+// with ErrorCause_Value_UnsupportedExtension.
+//
+// CoA-Requests with a Service-Type of Authorize-Only (RFC 5176 section 3.1)
+// are passed to OnAuthorizeOnly instead of OnCoA.
+type DynAuthServer struct {
+	// The address on which the server listens. Defaults to :3799.
+	Addr string
+
+	// The source from which the secret of each Dynamic Authorization client
+	// is obtained.
+	SecretSource radius.SecretSource
+
+	// OnCoA and OnDisconnect process the CoA-Requests and
+	// Disconnect-Requests.
+	OnCoA        DynAuthFunc
+	OnDisconnect DynAuthFunc
+
+	// OnAuthorizeOnly processes the CoA-Requests with a Service-Type of
+	// Authorize-Only. It should start the re-authorization of the session,
+	// in an Access-Request sent by the NAS, and return without waiting for
+	// it. If it returns nil, the request is answered with a CoA-NAK whose
+	// Error-Cause is ErrorCause_Value_RequestInitiated, as RFC 5176 section
+	// 3.1 requires. If it is nil, the requests are NAKed with
+	// ErrorCause_Value_UnsupportedService.
+	OnAuthorizeOnly DynAuthFunc
+
+	// MessageAuthenticator controls how the Message-Authenticator of incoming
+	// requests is verified, and whether a Message-Authenticator attribute is
+	// added to responses, as in radius.PacketServer.
+	MessageAuthenticator radius.MessageAuthenticatorPolicy
+
+	// ErrorLog specifies an optional logger for errors around packet
+	// accepting, processing, and validation. If nil, logging is done via the
+	// log package's standard logger.
+	ErrorLog *log.Logger
+
+	once   sync.Once
+	server *radius.PacketServer
+}
+
+func (s *DynAuthServer) packetServer() *radius.PacketServer {
+	s.once.Do(func() {
+		addr := s.Addr
+		if addr == "" {
+			addr = ":" + Port
+		}
+		s.server = &radius.PacketServer{
+			Addr:                 addr,
+			SecretSource:         s.SecretSource,
+			Handler:              s,
+			MessageAuthenticator: s.MessageAuthenticator,
+			ErrorLog:             s.ErrorLog,
+		}
+	})
+	return s.server
+}
+
+// ServeRADIUS implements radius.Handler, so that the server can also be used
+// as the handler of another radius server. Packets other than CoA-Requests
+// and Disconnect-Requests are discarded.
+func (s *DynAuthServer) ServeRADIUS(w radius.ResponseWriter, r *radius.Request) {
+	var response *radius.Packet
+	switch {
+	case r.Code == radius.CodeCoARequest && r.IsAuthorizeOnly():
+		response = s.authorizeOnly(r)
+	case r.Code == radius.CodeCoARequest:
+		response = s.dynAuth(s.OnCoA, r)
+	case r.Code == radius.CodeDisconnectRequest && r.IsAuthorizeOnly():
+		// Authorize-Only is only meaningful in CoA-Requests.
+		response = AuthorizeOnlyNAK(r.Packet, ErrorCause_Value_UnsupportedService)
+	case r.Code == radius.CodeDisconnectRequest:
+		response = s.dynAuth(s.OnDisconnect, r)
+	default:
+		return
+	}
+	radius.CopyProxyState(response, r.Packet)
+	w.Write(response)
+}
+
+func (s *DynAuthServer) dynAuth(fn DynAuthFunc, r *radius.Request) *radius.Packet {
+	if fn == nil {
+		return NAK(r.Packet, ErrorCause_Value_UnsupportedExtension)
+	}
+	if err := fn(r.Context(), r); err != nil {
+		return NAK(r.Packet, errorCause(err))
+	}
+	return ACK(r.Packet)
+}
+
+func (s *DynAuthServer) authorizeOnly(r *radius.Request) *radius.Packet {
+	if s.OnAuthorizeOnly == nil {
+		return AuthorizeOnlyNAK(r.Packet, ErrorCause_Value_UnsupportedService)
+	}
+	if err := s.OnAuthorizeOnly(r.Context(), r); err != nil {
+		return AuthorizeOnlyNAK(r.Packet, errorCause(err))
+	}
+	response := AuthorizeOnlyNAK(r.Packet, ErrorCause_Value_RequestInitiated)
+	rfc2865.ServiceType_Set(response, ServiceType_Value_AuthorizeOnly)
+	return response
+}
+
+// errorCause returns the ErrorCause in err's chain, or zero if there is none.
+func errorCause(err error) ErrorCause {
+	var cause ErrorCause
+	errors.As(err, &cause)
+	return cause
+}
+
+// Serve accepts incoming requests on conn.
+func (s *DynAuthServer) Serve(conn net.PacketConn) error {
+	return s.packetServer().Serve(conn)
+}
+
+// ListenAndServe starts the server on the address given in s.
+func (s *DynAuthServer) ListenAndServe() error {
+	return s.packetServer().ListenAndServe()
+}
+
+// Shutdown gracefully stops the server, in the same way as
+// radius.PacketServer.Shutdown.
+func (s *DynAuthServer) Shutdown(ctx context.Context) error {
+	return s.packetServer().Shutdown(ctx)
+}
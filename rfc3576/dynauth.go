@@ -0,0 +1,81 @@
+package rfc3576
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"layeh.com/radius"
+)
+
+// Port is the default UDP port on which NASes listen for Dynamic
+// Authorization requests (RFC 5176 section 3).
+const Port = "3799"
+
+// NAKError is returned by Exchange when a CoA-NAK or Disconnect-NAK is
+// received.
+type NAKError struct {
+	Response *radius.Packet
+	// Cause is the Error-Cause of the response, or 0 if it did not contain
+	// one.
+	Cause ErrorCause
+}
+
+func (e *NAKError) Error() string {
+	msg := `rfc3576: received ` + e.Response.Code.String()
+	if e.Cause != 0 {
+		msg += ` (` + e.Cause.String() + `)`
+	}
+	return msg
+}
+
+// Exchange sends the given CoA-Request or Disconnect-Request to the NAS at
+// addr using client, and waits for a response. If addr does not contain a
+// port, Port is used.
+//
+// The CoA-ACK or Disconnect-ACK is returned if the NAS acknowledges the
+// request. If it responds with a NAK, a *NAKError is returned.
+func Exchange(ctx context.Context, client *radius.Client, packet *radius.Packet, addr string) (*radius.Packet, error) {
+	if packet.Code != radius.CodeCoARequest && packet.Code != radius.CodeDisconnectRequest {
+		return nil, errors.New("rfc3576: packet is not a CoA-Request or Disconnect-Request")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, Port)
+	}
+
+	response, err := client.Exchange(ctx, packet, addr)
+	if err != nil {
+		return nil, err
+	}
+	switch response.Code {
+	case radius.CodeCoANAK, radius.CodeDisconnectNAK:
+		return nil, &NAKError{
+			Response: response,
+			Cause:    ErrorCause_Get(response),
+		}
+	}
+	return response, nil
+}
+
+// ACK returns a CoA-ACK or Disconnect-ACK response to the given request.
+func ACK(request *radius.Packet) *radius.Packet {
+	code := radius.CodeCoAACK
+	if request.Code == radius.CodeDisconnectRequest {
+		code = radius.CodeDisconnectACK
+	}
+	return request.Response(code)
+}
+
+// NAK returns a CoA-NAK or Disconnect-NAK response to the given request. If
+// cause is not 0, it is added as the response's Error-Cause.
+func NAK(request *radius.Packet, cause ErrorCause) *radius.Packet {
+	code := radius.CodeCoANAK
+	if request.Code == radius.CodeDisconnectRequest {
+		code = radius.CodeDisconnectNAK
+	}
+	response := request.Response(code)
+	if cause != 0 {
+		ErrorCause_Set(response, cause)
+	}
+	return response
+}
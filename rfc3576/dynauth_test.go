@@ -0,0 +1,236 @@
+package rfc3576
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	. "layeh.com/radius/rfc2866"
+)
+
+func TestExchange(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	server := radius.PacketServer{
+		SecretSource: radius.StaticSecretSource(secret),
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, r *radius.Request) {
+			if AcctSessionID_GetString(r.Packet) == "active" {
+				w.Write(ACK(r.Packet))
+			} else {
+				w.Write(NAK(r.Packet, ErrorCause_Value_SessionContextNotFound))
+			}
+		}),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	client := &radius.Client{
+		Retry: time.Millisecond * 5,
+	}
+	addr := pc.LocalAddr().String()
+
+	for _, code := range []radius.Code{radius.CodeDisconnectRequest, radius.CodeCoARequest} {
+		packet := radius.New(code, secret)
+		AcctSessionID_SetString(packet, "active")
+		response, err := Exchange(context.Background(), client, packet, addr)
+		if err != nil {
+			t.Fatalf("%v: %v", code, err)
+		}
+		if expected := ACK(packet).Code; response.Code != expected {
+			t.Fatalf("got %v; expecting %v", response.Code, expected)
+		}
+
+		packet = radius.New(code, secret)
+		AcctSessionID_SetString(packet, "unknown")
+		_, err = Exchange(context.Background(), client, packet, addr)
+		nakErr, ok := err.(*NAKError)
+		if !ok {
+			t.Fatalf("%v: got err = %v; expecting *NAKError", code, err)
+		}
+		if nakErr.Cause != ErrorCause_Value_SessionContextNotFound {
+			t.Fatalf("got cause %v; expecting Session-Context-Not-Found", nakErr.Cause)
+		}
+	}
+
+	if _, err := Exchange(context.Background(), client, radius.New(radius.CodeAccessRequest, secret), addr); err == nil {
+		t.Fatal("expecting error for Access-Request")
+	}
+}
+
+func TestDynAuthClient(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	var attempts int32
+	server := radius.PacketServer{
+		SecretSource: radius.StaticSecretSource(secret),
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, r *radius.Request) {
+			// drop the first transmission
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				return
+			}
+			if r.Code == radius.CodeDisconnectRequest {
+				w.Write(NAK(r.Packet, ErrorCause_Value_SessionContextNotFound))
+				return
+			}
+			w.Write(ACK(r.Packet))
+		}),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	client := &DynAuthClient{
+		Client: &radius.Client{
+			RetryPolicy: &radius.RetryPolicy{
+				InitialTimeout: 20 * time.Millisecond,
+				MaxRetries:     3,
+			},
+		},
+		Secret: secret,
+	}
+	addr := pc.LocalAddr().String()
+
+	var attributes radius.Attributes
+	attributes.Add(AcctSessionID_Type, radius.Attribute("session"))
+	response, err := client.CoA(context.Background(), addr, attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != radius.CodeCoAACK {
+		t.Fatalf("got %v; expecting CoA-ACK", response.Code)
+	}
+
+	_, err = client.Disconnect(context.Background(), addr, attributes)
+	if nakErr, ok := err.(*NAKError); !ok || nakErr.Cause != ErrorCause_Value_SessionContextNotFound {
+		t.Fatalf("got err = %v; expecting Session-Context-Not-Found NAK", err)
+	}
+}
+
+func TestDynAuthServer(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	server := &DynAuthServer{
+		SecretSource: radius.StaticSecretSource(secret),
+		OnDisconnect: func(ctx context.Context, r *radius.Request) error {
+			if AcctSessionID_GetString(r.Packet) != "active" {
+				return ErrorCause_Value_SessionContextNotFound
+			}
+			return nil
+		},
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	client := &DynAuthClient{
+		Client: &radius.Client{Retry: time.Millisecond * 5},
+		Secret: secret,
+	}
+	addr := pc.LocalAddr().String()
+
+	packet := client.NewDisconnectRequest()
+	AcctSessionID_SetString(packet, "active")
+	resp, err := client.Exchange(context.Background(), packet, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != radius.CodeDisconnectACK {
+		t.Fatalf("got %v; expecting Disconnect-ACK", resp.Code)
+	}
+
+	packet = client.NewDisconnectRequest()
+	AcctSessionID_SetString(packet, "unknown")
+	_, err = client.Exchange(context.Background(), packet, addr)
+	if nakErr, ok := err.(*NAKError); !ok || nakErr.Cause != ErrorCause_Value_SessionContextNotFound {
+		t.Fatalf("got %v; expecting Session-Context-Not-Found NAK", err)
+	}
+
+	_, err = client.Exchange(context.Background(), client.NewCoARequest(), addr)
+	if nakErr, ok := err.(*NAKError); !ok || nakErr.Cause != ErrorCause_Value_UnsupportedExtension {
+		t.Fatalf("got %v; expecting Unsupported-Extension NAK", err)
+	}
+}
+
+func TestDynAuthServer_authorizeOnly(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	var initiated int32
+	server := &DynAuthServer{
+		SecretSource: radius.StaticSecretSource(secret),
+		OnCoA: func(ctx context.Context, r *radius.Request) error {
+			if r.IsAuthorizeOnly() {
+				t.Error("Authorize-Only request passed to OnCoA")
+			}
+			return fmt.Errorf("coa: %w", ErrorCause_Value_SessionContextNotFound)
+		},
+		OnAuthorizeOnly: func(ctx context.Context, r *radius.Request) error {
+			atomic.AddInt32(&initiated, 1)
+			return nil
+		},
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	client := &DynAuthClient{
+		Client: &radius.Client{Retry: time.Millisecond * 5},
+		Secret: secret,
+	}
+	addr := pc.LocalAddr().String()
+
+	_, err = client.Exchange(context.Background(), client.NewCoARequest(), addr)
+	if nakErr, ok := err.(*NAKError); !ok || nakErr.Cause != ErrorCause_Value_SessionContextNotFound {
+		t.Fatalf("got %v; expecting wrapped Session-Context-Not-Found NAK", err)
+	}
+
+	authorizeOnly := func() *radius.Packet {
+		packet := client.NewCoARequest()
+		rfc2865.ServiceType_Set(packet, ServiceType_Value_AuthorizeOnly)
+		return packet
+	}
+	_, err = client.Exchange(context.Background(), authorizeOnly(), addr)
+	nakErr, ok := err.(*NAKError)
+	if !ok || nakErr.Cause != ErrorCause_Value_RequestInitiated {
+		t.Fatalf("got %v; expecting Request-Initiated NAK", err)
+	}
+	if !nakErr.Response.IsAuthorizeOnly() {
+		t.Fatal("Request-Initiated NAK missing Service-Type Authorize-Only")
+	}
+	if atomic.LoadInt32(&initiated) == 0 {
+		t.Fatal("OnAuthorizeOnly not called")
+	}
+
+	var unsupported *radius.Packet
+	w := responseWriterFunc(func(p *radius.Packet) error {
+		unsupported = p
+		return nil
+	})
+	(&DynAuthServer{}).ServeRADIUS(w, &radius.Request{Packet: authorizeOnly()})
+	if cause := ErrorCause_Get(unsupported); unsupported.Code != radius.CodeCoANAK || cause != ErrorCause_Value_UnsupportedService {
+		t.Fatalf("got %v %v; expecting Unsupported-Service CoA-NAK", unsupported.Code, cause)
+	}
+}
+
+type responseWriterFunc func(p *radius.Packet) error
+
+func (f responseWriterFunc) Write(p *radius.Packet) error {
+	return f(p)
+}
@@ -0,0 +1,146 @@
+// Package rfc3579 drives EAP conversations over RADIUS from the client side
+// (RFC 3579).
+package rfc3579
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	"layeh.com/radius/rfc2869"
+	"layeh.com/radius/vendors/microsoft"
+)
+
+// EAP packet codes (RFC 3748 section 4).
+const (
+	EAPCodeRequest  = 1
+	EAPCodeResponse = 2
+	EAPCodeSuccess  = 3
+	EAPCodeFailure  = 4
+)
+
+// EAPTypeIdentity is the EAP Identity type (RFC 3748 section 5.1).
+const EAPTypeIdentity = 1
+
+// Method is an EAP method run by the peer. It is called with each EAP-Request
+// received from the server, and returns the EAP-Response to send.
+type Method func(request []byte) (response []byte, err error)
+
+// Conversation sends Access-Requests carrying EAP-Messages to a RADIUS server
+// on behalf of a peer, until the server accepts or rejects it.
+//
+// The State attribute of each Access-Challenge is echoed in the next
+// Access-Request, and every request carries a Message-Authenticator, which is
+// required in the server's responses.
+type Conversation struct {
+	// Client is used to exchange packets with the server. If nil,
+	// radius.DefaultClient is used.
+	Client radius.Exchanger
+
+	// Addr is the address of the server.
+	Addr string
+
+	// Secret is the secret shared with the server.
+	Secret []byte
+
+	// Attributes are added to every Access-Request, e.g. NAS-Identifier and
+	// Calling-Station-Id.
+	Attributes radius.Attributes
+
+	// MaxRounds is the maximum number of Access-Requests sent. Defaults to 50.
+	MaxRounds int
+}
+
+// Result is the outcome of a Conversation.
+type Result struct {
+	// Response is the final Access-Accept or Access-Reject.
+	Response *radius.Packet
+
+	// Accepted is true if the server sent an Access-Accept.
+	Accepted bool
+
+	// SendKey and RecvKey are the decrypted MS-MPPE-Send-Key and
+	// MS-MPPE-Recv-Key of the Access-Accept, if present (RFC 2548).
+	SendKey, RecvKey []byte
+}
+
+// IdentityResponse returns an EAP-Response/Identity packet with the given
+// Identifier.
+func IdentityResponse(identifier byte, identity string) []byte {
+	b := make([]byte, 5+len(identity))
+	b[0] = EAPCodeResponse
+	b[1] = identifier
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+	b[4] = EAPTypeIdentity
+	copy(b[5:], identity)
+	return b
+}
+
+// Run runs an EAP conversation for the peer with the given identity. The first
+// Access-Request carries an EAP-Response/Identity, and the EAP-Request of each
+// Access-Challenge is passed to method to produce the next EAP-Response.
+//
+// An error is returned if an exchange fails, if method returns an error, or if
+// the conversation does not finish within MaxRounds requests. A rejection is
+// not an error; it is reported by Result.Accepted.
+func (c *Conversation) Run(ctx context.Context, identity string, method Method) (*Result, error) {
+	client := c.Client
+	if client == nil {
+		client = radius.DefaultClient
+	}
+	maxRounds := c.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = 50
+	}
+
+	eap := IdentityResponse(0, identity)
+	var state []byte
+	for round := 0; round < maxRounds; round++ {
+		request := radius.New(radius.CodeAccessRequest, c.Secret)
+		request.MessageAuthenticatorPolicy = radius.MessageAuthenticatorRequire
+		request.Attributes = append(request.Attributes, c.Attributes...)
+		if err := rfc2865.UserName_SetString(request, identity); err != nil {
+			return nil, err
+		}
+		if err := rfc2869.EAPMessage_Set(request, eap); err != nil {
+			return nil, err
+		}
+		if state != nil {
+			if err := rfc2865.State_Set(request, state); err != nil {
+				return nil, err
+			}
+		}
+
+		response, err := client.Exchange(ctx, request, c.Addr)
+		if err != nil {
+			return nil, err
+		}
+		switch response.Code {
+		case radius.CodeAccessAccept:
+			result := &Result{
+				Response: response,
+				Accepted: true,
+			}
+			result.SendKey, _ = microsoft.MSMPPESendKey_Lookup(response, request)
+			result.RecvKey, _ = microsoft.MSMPPERecvKey_Lookup(response, request)
+			return result, nil
+		case radius.CodeAccessReject:
+			return &Result{Response: response}, nil
+		case radius.CodeAccessChallenge:
+		default:
+			return nil, errors.New("rfc3579: unexpected response " + response.Code.String())
+		}
+
+		serverEAP, err := rfc2869.EAPMessage_Lookup(response)
+		if err != nil {
+			return nil, errors.New("rfc3579: Access-Challenge without EAP-Message")
+		}
+		state = rfc2865.State_Get(response)
+		if eap, err = method(serverEAP); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("rfc3579: too many rounds")
+}
@@ -0,0 +1,81 @@
+package rfc3579
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	"layeh.com/radius/rfc2869"
+	"layeh.com/radius/vendors/microsoft"
+)
+
+func TestConversation(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	sendKey := bytes.Repeat([]byte{0xAA}, 32)
+	server := radius.PacketServer{
+		SecretSource: radius.StaticSecretSource(secret),
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, r *radius.Request) {
+			eap := rfc2869.EAPMessage_Get(r.Packet)
+			var response *radius.Packet
+			switch state := rfc2865.State_GetString(r.Packet); {
+			case state == "" && len(eap) > 4 && eap[4] == EAPTypeIdentity:
+				response = r.Response(radius.CodeAccessChallenge)
+				rfc2865.State_SetString(response, "round-1")
+				rfc2869.EAPMessage_Set(response, []byte{EAPCodeRequest, 1, 0, 6, 254, 'x'})
+			case state == "round-1" && bytes.Equal(eap, []byte{EAPCodeResponse, 1, 0, 6, 254, 'y'}):
+				response = r.Response(radius.CodeAccessAccept)
+				microsoft.MSMPPESendKey_Set(response, sendKey)
+				rfc2869.EAPMessage_Set(response, []byte{EAPCodeSuccess, 1, 0, 4})
+			default:
+				response = r.Response(radius.CodeAccessReject)
+			}
+			response.MessageAuthenticatorPolicy = radius.MessageAuthenticatorAdd
+			w.Write(response)
+		}),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	conversation := &Conversation{
+		Client: &radius.Client{Retry: 50 * time.Millisecond},
+		Addr:   pc.LocalAddr().String(),
+		Secret: secret,
+	}
+	method := func(request []byte) ([]byte, error) {
+		if !bytes.Equal(request, []byte{EAPCodeRequest, 1, 0, 6, 254, 'x'}) {
+			return nil, errors.New("unexpected EAP-Request")
+		}
+		return []byte{EAPCodeResponse, 1, 0, 6, 254, 'y'}, nil
+	}
+	result, err := conversation.Run(context.Background(), "alice", method)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Accepted {
+		t.Fatalf("got %v; expecting Access-Accept", result.Response.Code)
+	}
+	if !bytes.Equal(result.SendKey, sendKey) {
+		t.Fatalf("got MS-MPPE-Send-Key %x; expecting %x", result.SendKey, sendKey)
+	}
+
+	reject := func(request []byte) ([]byte, error) {
+		return []byte{EAPCodeResponse, 1, 0, 6, 254, 'z'}, nil
+	}
+	result, err = conversation.Run(context.Background(), "alice", reject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Accepted || result.Response.Code != radius.CodeAccessReject {
+		t.Fatalf("got %v; expecting Access-Reject", result.Response.Code)
+	}
+}
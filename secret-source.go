@@ -0,0 +1,190 @@
+package radius
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CIDRSecretSource is a SecretSource that maps client networks to secrets, in
+// the manner of a FreeRADIUS clients.conf. The secret of the most specific
+// network (longest prefix) that contains the client's IP address is used.
+//
+// The mapping can be replaced at any time with Update, LoadFile, or WatchFile;
+// requests being processed keep using the previous mapping.
+type CIDRSecretSource struct {
+	mu      sync.RWMutex
+	entries []cidrSecret // sorted by decreasing prefix length
+}
+
+type cidrSecret struct {
+	network *net.IPNet
+	ones    int
+	secret  []byte
+}
+
+// NewCIDRSecretSource returns a CIDRSecretSource with the given mapping, as
+// accepted by Update.
+func NewCIDRSecretSource(secrets map[string][]byte) (*CIDRSecretSource, error) {
+	s := &CIDRSecretSource{}
+	if err := s.Update(secrets); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update atomically replaces the mapping of the source. The keys of secrets are
+// IPv4 or IPv6 networks in CIDR notation, or single IP addresses. If an error
+// is returned, the previous mapping is kept.
+func (s *CIDRSecretSource) Update(secrets map[string][]byte) error {
+	entries := make([]cidrSecret, 0, len(secrets))
+	for cidr, secret := range secrets {
+		network, err := parseNetwork(cidr)
+		if err != nil {
+			return err
+		}
+		ones, _ := network.Mask.Size()
+		entries = append(entries, cidrSecret{
+			network: network,
+			ones:    ones,
+			secret:  secret,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ones > entries[j].ones
+	})
+
+	s.mu.Lock()
+	s.entries = entries
+	s.mu.Unlock()
+	return nil
+}
+
+// parseNetwork parses a network in CIDR notation, or a single IP address.
+func parseNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		if ip4 := network.IP.To4(); ip4 != nil && len(network.Mask) == net.IPv4len {
+			network.IP = ip4
+		}
+		return network, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.New("radius: invalid client address " + s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// ParseCIDRSecrets parses a mapping of client networks to secrets. Each line
+// contains a network (or IP address) and its secret, separated by whitespace.
+// Empty lines and lines starting with # are ignored.
+func ParseCIDRSecrets(r io.Reader) (map[string][]byte, error) {
+	secrets := make(map[string][]byte)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, errors.New("radius: invalid client secret on line " + strconv.Itoa(line))
+		}
+		secrets[fields[0]] = []byte(fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// LoadFile replaces the mapping of the source with the one in the given file,
+// in the format accepted by ParseCIDRSecrets.
+func (s *CIDRSecretSource) LoadFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	secrets, err := ParseCIDRSecrets(f)
+	if err != nil {
+		return err
+	}
+	return s.Update(secrets)
+}
+
+// WatchFile loads the given file, and then reloads it whenever its
+// modification time changes, checking every interval, until ctx is canceled.
+// Errors that occur while reloading are passed to onError, if it is non-nil,
+// and the previous mapping is kept.
+//
+// The error of the initial load is returned, or ctx.Err() once ctx is
+// canceled.
+func (s *CIDRSecretSource) WatchFile(ctx context.Context, name string, interval time.Duration, onError func(error)) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if err := s.LoadFile(name); err != nil {
+		return err
+	}
+	modTime := info.ModTime()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		info, err := os.Stat(name)
+		if err == nil && info.ModTime().Equal(modTime) {
+			continue
+		}
+		if err == nil {
+			modTime = info.ModTime()
+			err = s.LoadFile(name)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// RADIUSSecret implements SecretSource. An empty secret is returned, which
+// causes the packet to be discarded, if no network contains remoteAddr.
+func (s *CIDRSecretSource) RADIUSSecret(ctx context.Context, remoteAddr net.Addr) ([]byte, error) {
+	ip := net.ParseIP(addrKey(remoteAddr))
+	if ip == nil {
+		return nil, nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	s.mu.RLock()
+	entries := s.entries
+	s.mu.RUnlock()
+	for _, entry := range entries {
+		if entry.network.Contains(ip) {
+			return entry.secret, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,106 @@
+package radius
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCIDRSecretSource(t *testing.T) {
+	source, err := NewCIDRSecretSource(map[string][]byte{
+		"10.0.0.0/8":    []byte("wide"),
+		"10.1.0.0/16":   []byte("narrow"),
+		"10.1.2.3":      []byte("host"),
+		"2001:db8::/32": []byte("v6"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		IP     string
+		Secret string
+	}{
+		{"10.9.9.9", "wide"},
+		{"10.1.9.9", "narrow"},
+		{"10.1.2.3", "host"},
+		{"::ffff:10.1.2.3", "host"},
+		{"2001:db8::1", "v6"},
+		{"192.0.2.1", ""},
+	}
+	for _, tt := range tests {
+		secret, err := source.RADIUSSecret(context.Background(), &net.UDPAddr{IP: net.ParseIP(tt.IP), Port: 1812})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(secret) != tt.Secret {
+			t.Errorf("%s: got secret %q; expecting %q", tt.IP, secret, tt.Secret)
+		}
+	}
+
+	if err := source.Update(map[string][]byte{"not-an-ip": nil}); err == nil {
+		t.Fatal("expecting error for invalid network")
+	}
+	if secret, _ := source.RADIUSSecret(context.Background(), &net.UDPAddr{IP: net.ParseIP("10.9.9.9")}); string(secret) != "wide" {
+		t.Fatal("failed update replaced the mapping")
+	}
+}
+
+func TestParseCIDRSecrets(t *testing.T) {
+	secrets, err := ParseCIDRSecrets(strings.NewReader("# clients\n\n10.0.0.0/8 secret1\n  ::1   secret2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 || string(secrets["10.0.0.0/8"]) != "secret1" || string(secrets["::1"]) != "secret2" {
+		t.Fatalf("unexpected secrets %q", secrets)
+	}
+	if _, err := ParseCIDRSecrets(strings.NewReader("10.0.0.0/8\n")); err == nil {
+		t.Fatal("expecting error for line without secret")
+	}
+}
+
+func TestCIDRSecretSource_WatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radius-clients")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "clients")
+	if err := ioutil.WriteFile(name, []byte("127.0.0.1 before\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &CIDRSecretSource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go source.WatchFile(ctx, name, 10*time.Millisecond, nil)
+
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	waitFor := func(expected string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			secret, _ := source.RADIUSSecret(context.Background(), addr)
+			if string(secret) == expected {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("got secret %q; expecting %q", secret, expected)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("before")
+
+	if err := ioutil.WriteFile(name, []byte("127.0.0.1 after\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(name, later, later)
+	waitFor("after")
+}
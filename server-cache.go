@@ -0,0 +1,237 @@
+package radius
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// ContentFingerprint returns a fingerprint of the content of p as sent from
+// source, which identifies the sender (e.g. its IP address). Unlike
+// Fingerprint, it does not depend on the packet's Identifier and
+// Authenticator, so that identical requests sent at different times have the
+// same fingerprint.
+//
+// The Code and attributes of the packet are included, in order, except for
+// Message-Authenticator and Proxy-State attributes. The User-Password is
+// included in its decrypted form. If the packet has a CHAP-Password but no
+// CHAP-Challenge, the Authenticator is included, as it is the challenge.
+func (p *Packet) ContentFingerprint(source string) Fingerprint {
+	hash := sha256.New()
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(source)))
+	hash.Write(length[:])
+	hash.Write([]byte(source))
+	hash.Write([]byte{byte(p.Code)})
+
+	_, chap := p.Lookup(typeCHAPPassword)
+	_, challenge := p.Lookup(typeCHAPChallenge)
+	if chap && !challenge {
+		hash.Write(p.Authenticator[:])
+	}
+
+	for _, avp := range p.Attributes {
+		value := avp.Attribute
+		switch avp.Type {
+		case typeMessageAuthenticator, typeProxyState:
+			continue
+		case typeUserPassword:
+			if password, err := p.UserPassword(); err == nil {
+				value = password
+			}
+		}
+		binary.BigEndian.PutUint32(length[:], uint32(len(value)))
+		hash.Write([]byte{byte(avp.Type)})
+		hash.Write(length[:])
+		hash.Write(value)
+	}
+
+	var f Fingerprint
+	hash.Sum(f[:0])
+	return f
+}
+
+// ResponseCacheStore stores the responses cached by ResponseCacheMiddleware.
+// It must be safe for concurrent use.
+type ResponseCacheStore interface {
+	// Get returns the value stored with key, if it has not expired.
+	Get(key Fingerprint) ([]byte, bool)
+	// Set stores value with key for ttl.
+	Set(key Fingerprint, value []byte, ttl time.Duration)
+}
+
+// NewMemoryResponseCacheStore returns a ResponseCacheStore that keeps the
+// responses in memory.
+func NewMemoryResponseCacheStore() ResponseCacheStore {
+	return &memoryResponseCacheStore{}
+}
+
+type memoryResponseCacheStore struct {
+	cache duplicateCache
+}
+
+func (s *memoryResponseCacheStore) Get(key Fingerprint) ([]byte, bool) {
+	return s.cache.get(key, time.Now())
+}
+
+func (s *memoryResponseCacheStore) Set(key Fingerprint, value []byte, ttl time.Duration) {
+	s.cache.put(key, value, ttl, time.Now())
+}
+
+// ResponseCacheOptions configure ResponseCacheMiddleware.
+type ResponseCacheOptions struct {
+	// Store holds the cached responses. If nil, responses are cached in
+	// memory.
+	Store ResponseCacheStore
+
+	// TTL is how long Access-Accept responses are cached, and NegativeTTL
+	// how long Access-Reject responses are cached. Responses are not cached
+	// if the corresponding TTL is zero.
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+// ResponseCacheMiddleware returns a Middleware that caches the responses to
+// Access-Requests, keyed by the ContentFingerprint of the request and the IP
+// address of its source. Requests with the same content that are received
+// while the response is cached are answered with a copy of it, without
+// calling the next Handler, and identical requests received while the first
+// one is being handled wait for its response.
+//
+// Only Access-Accept and Access-Reject responses are cached. Requests that
+// are part of a multi-round exchange (those with a State or EAP-Message
+// attribute) are never answered from the cache. Responses carrying attributes
+// that are encrypted using the Request Authenticator (Tunnel-Password,
+// MS-MPPE-Send-Key, MS-MPPE-Recv-Key, and attributes marked as encrypted in
+// the registered dictionary) are not cached, as they could not be decrypted
+// as part of a response to another request.
+//
+// Unlike PacketServer.DuplicateCacheTTL, which answers retransmissions of a
+// single request, the cache answers new requests, so it must only be used
+// when the authorization decision of the next Handler depends on nothing
+// other than the content of the request.
+func ResponseCacheMiddleware(opts ResponseCacheOptions) Middleware {
+	store := opts.Store
+	if store == nil {
+		store = NewMemoryResponseCacheStore()
+	}
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[Fingerprint]chan struct{})
+	)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			if r.Code != CodeAccessRequest || r.Get(typeState) != nil || r.Get(typeEAPMessage) != nil {
+				next.ServeRADIUS(w, r)
+				return
+			}
+			key := r.ContentFingerprint(addrKey(r.RemoteAddr))
+
+			var done chan struct{}
+			for done == nil {
+				if value, ok := store.Get(key); ok {
+					if response, ok := decodeCachedResponse(value, r); ok {
+						w.Write(response)
+						return
+					}
+				}
+				mu.Lock()
+				wait, ok := inFlight[key]
+				if !ok {
+					done = make(chan struct{})
+					inFlight[key] = done
+				}
+				mu.Unlock()
+				if ok {
+					select {
+					case <-wait:
+					case <-r.Context().Done():
+						return
+					}
+				}
+			}
+			defer func() {
+				mu.Lock()
+				delete(inFlight, key)
+				mu.Unlock()
+				close(done)
+			}()
+
+			rw := &recordingResponseWriter{ResponseWriter: w}
+			next.ServeRADIUS(rw, r)
+			if rw.response == nil {
+				return
+			}
+			ttl := opts.TTL
+			if rw.response.Code == CodeAccessReject {
+				ttl = opts.NegativeTTL
+			} else if rw.response.Code != CodeAccessAccept {
+				ttl = 0
+			}
+			if ttl <= 0 || hasRequestEncryptedAttribute(rw.response) {
+				return
+			}
+			if value, err := encodeCachedResponse(rw.response); err == nil {
+				store.Set(key, value, ttl)
+			}
+		})
+	}
+}
+
+// vendorMicrosoft is the Microsoft vendor ID, whose MS-MPPE-Send-Key and
+// MS-MPPE-Recv-Key attributes (RFC 2548 sections 2.4.2 and 2.4.3) are
+// encrypted using the Request Authenticator.
+const vendorMicrosoft = 311
+
+// hasRequestEncryptedAttribute returns if p contains an attribute whose value
+// is encrypted using the Request Authenticator of the request it answers.
+func hasRequestEncryptedAttribute(p *Packet) bool {
+	if _, ok := p.Lookup(typeTunnelPassword); ok {
+		return true
+	}
+	for _, subType := range []byte{16, 17} {
+		if _, ok := p.LookupVendor(vendorMicrosoft, subType); ok {
+			return true
+		}
+	}
+	for _, avp := range p.Attributes {
+		if attr := registeredAttribute(avp.Type); attr != nil && attr.FlagEncrypt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// A cached response is stored as its Code and MessageAuthenticatorPolicy,
+// followed by its attributes other than Message-Authenticator and
+// Proxy-State.
+func encodeCachedResponse(response *Packet) ([]byte, error) {
+	policy := response.MessageAuthenticatorPolicy
+	if _, ok := response.Lookup(typeMessageAuthenticator); ok && policy == MessageAuthenticatorIgnore {
+		policy = MessageAuthenticatorAdd
+	}
+	attrs := response.Attributes.Filter(func(key Type, value Attribute) bool {
+		return key != typeMessageAuthenticator && key != typeProxyState
+	})
+	return attrs.AppendTo([]byte{byte(response.Code), byte(policy)})
+}
+
+// decodeCachedResponse returns the cached response as a response to r.
+func decodeCachedResponse(value []byte, r *Request) (*Packet, bool) {
+	if len(value) < 2 {
+		return nil, false
+	}
+	attrs, err := ParseAttributes(value[2:])
+	if err != nil {
+		return nil, false
+	}
+	response := r.Response(Code(value[0]))
+	if policy := MessageAuthenticatorPolicy(value[1]); policy > response.MessageAuthenticatorPolicy {
+		response.MessageAuthenticatorPolicy = policy
+	}
+	response.Attributes = attrs
+	CopyProxyState(response, r.Packet)
+	return response, true
+}
@@ -0,0 +1,139 @@
+package radius
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacket_ContentFingerprint(t *testing.T) {
+	secret := []byte(`12345`)
+	newRequest := func(id byte, password string) *Packet {
+		packet := New(CodeAccessRequest, secret)
+		packet.Identifier = id
+		packet.Add(typeUserName, Attribute("tim"))
+		packet.SetUserPassword([]byte(password))
+		wire, err := packet.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := Parse(wire, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	a := newRequest(1, "password")
+	b := newRequest(2, "password")
+	b.PushProxyState([]byte("proxy"))
+	if a.ContentFingerprint("") != b.ContentFingerprint("") {
+		t.Fatal("expecting identical requests to have the same fingerprint")
+	}
+	if a.ContentFingerprint("10.0.0.1") == a.ContentFingerprint("10.0.0.2") {
+		t.Fatal("expecting fingerprint to depend on source")
+	}
+	if a.ContentFingerprint("") == newRequest(1, "other").ContentFingerprint("") {
+		t.Fatal("expecting fingerprint to depend on password")
+	}
+}
+
+func TestResponseCacheMiddleware(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	handler := ResponseCacheMiddleware(ResponseCacheOptions{
+		TTL: time.Minute,
+	})(HandlerFunc(func(w ResponseWriter, r *Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		code := CodeAccessAccept
+		if string(r.Get(typeUserName)) != "tim" {
+			code = CodeAccessReject
+		}
+		resp := r.Response(code)
+		resp.Add(Type(18), Attribute("welcome"))
+		CopyProxyState(resp, r.Packet)
+		w.Write(resp)
+	}))
+
+	newRequest := func(id byte, name string) *Request {
+		packet := New(CodeAccessRequest, []byte(`12345`))
+		packet.Identifier = id
+		packet.Add(typeUserName, Attribute(name))
+		packet.PushProxyState([]byte{id})
+		return &Request{
+			RemoteAddr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000 + int(id)},
+			Packet:     packet,
+		}
+	}
+
+	writers := make([]*testResponseWriter, 5)
+	var wg sync.WaitGroup
+	for i := range writers {
+		writers[i] = &testResponseWriter{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			handler.ServeRADIUS(writers[i], newRequest(byte(i), "tim"))
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("handler called %d times; expecting 1", n)
+	}
+	for i, w := range writers {
+		if len(w.responses) != 1 {
+			t.Fatalf("got %d responses to request %d; expecting 1", len(w.responses), i)
+		}
+		resp := w.responses[0]
+		if resp.Code != CodeAccessAccept || resp.Identifier != byte(i) || string(resp.Get(Type(18))) != "welcome" {
+			t.Fatalf("unexpected response %v to request %d", resp, i)
+		}
+		if state, _ := resp.LastProxyState(); len(state) != 1 || state[0] != byte(i) {
+			t.Fatalf("got Proxy-State %v in response to request %d", state, i)
+		}
+	}
+
+	// Access-Rejects are not cached without a NegativeTTL.
+	for i := 0; i < 2; i++ {
+		handler.ServeRADIUS(&testResponseWriter{}, newRequest(0, "ned"))
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("handler called %d times; expecting 3", n)
+	}
+}
+
+func TestResponseCacheMiddleware_encryptedAttributes(t *testing.T) {
+	for _, add := range []func(resp *Packet){
+		func(resp *Packet) { resp.Add(typeTunnelPassword, Attribute("\x00salt-encrypted")) },
+		func(resp *Packet) { resp.AddVendor(vendorMicrosoft, 16, Attribute("salt-encrypted")) },
+		func(resp *Packet) { resp.AddVendor(vendorMicrosoft, 17, Attribute("salt-encrypted")) },
+	} {
+		var calls int
+		handler := ResponseCacheMiddleware(ResponseCacheOptions{
+			TTL: time.Minute,
+		})(HandlerFunc(func(w ResponseWriter, r *Request) {
+			calls++
+			resp := r.Response(CodeAccessAccept)
+			add(resp)
+			w.Write(resp)
+		}))
+
+		for i := 0; i < 2; i++ {
+			packet := New(CodeAccessRequest, []byte(`12345`))
+			packet.Add(typeUserName, Attribute("tim"))
+			handler.ServeRADIUS(&testResponseWriter{}, &Request{
+				RemoteAddr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000},
+				Packet:     packet,
+			})
+		}
+		if calls != 2 {
+			t.Fatalf("handler called %d times; expecting the response not to be cached", calls)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"time"
+)
+
+// DTLSServer listens for RADIUS requests over DTLS (RFC 7360).
+//
+// This package does not include a DTLS implementation. Serve is given a
+// net.Listener implemented using a third-party DTLS library, which performs
+// the handshake of each session with PSK or certificate authentication, and
+// protects the handshake against denial of service with cookie exchanges
+// (RFC 6347 section 4.2.1). Accept must only return sessions that have
+// completed the handshake, and each returned net.Conn must preserve datagram
+// boundaries: each Read returns a single record, and each Write sends a single
+// record.
+//
+// Each session is served until it is closed by the client, or until it has
+// been idle for IdleTimeout. As with UDP, invalid packets are silently
+// discarded and retransmissions of a request that is still being handled are
+// ignored.
+type DTLSServer struct {
+	// Handler which is called to process the request.
+	Handler Handler
+
+	// Middleware is applied, outermost first, to Handler.
+	Middleware []Middleware
+
+	// Recovery, if non-nil, recovers from panics in Handler and Middleware,
+	// as in PacketServer.
+	Recovery *RecoveryPolicy
+
+	// Secret is the shared secret used for all packets. If nil, DTLSSecret is
+	// used, as required by RFC 7360.
+	Secret []byte
+
+	// ClientSecret, if non-nil, is called for each session returned by the
+	// listener, allowing the client's PSK identity or certificate to be
+	// mapped to a client entry; conn is the session as returned by the DTLS
+	// library. It returns the secret to use for the session, or nil to use
+	// Secret. If it returns an error, the session is closed.
+	ClientSecret func(conn net.Conn) ([]byte, error)
+
+	// MessageAuthenticator controls how the Message-Authenticator of incoming
+	// requests is verified, and whether a Message-Authenticator attribute is
+	// added to responses. Requests that fail verification are discarded.
+	MessageAuthenticator MessageAuthenticatorPolicy
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of
+	// requests accepted and responses sent by the server, up to
+	// MaxExtendedPacketLength. If zero, MaxPacketLength is used.
+	MaxPacketSize int
+
+	// MaxSessions, if positive, is the maximum number of sessions that are
+	// served at once. Sessions accepted beyond it are closed immediately.
+	MaxSessions int
+
+	// IdleTimeout is how long a session may remain without receiving a
+	// request before it is closed (RFC 7360 section 3.4). Defaults to 10
+	// minutes; a negative value disables the timeout.
+	IdleTimeout time.Duration
+
+	// Metrics, if non-nil, receives measurements of the requests processed
+	// by the server.
+	Metrics ServerMetrics
+
+	// ErrorLog specifies an optional logger for errors around session
+	// accepting, and packet processing and validation. If nil, logging is
+	// done via the log package's standard logger.
+	ErrorLog *log.Logger
+
+	// Logger, if non-nil, receives structured events for received packets,
+	// parse errors, secret lookup failures, and shutdown, as in
+	// PacketServer.
+	Logger Logger
+
+	// Tap, if non-nil, receives a copy of every packet read from or written
+	// to the server's sessions.
+	Tap Tap
+
+	stream streamServer
+}
+
+func (s *DTLSServer) logf(format string, args ...interface{}) {
+	errorLogf(s.Logger, s.ErrorLog, format, args...)
+}
+
+// Serve accepts incoming DTLS sessions on l.
+func (s *DTLSServer) Serve(l net.Listener) error {
+	if s.Handler == nil {
+		return errors.New("radius: nil Handler")
+	}
+	handler := recoverHandler(ChainMiddleware(s.Handler, s.Middleware...), s.Recovery, s.logf)
+	idleTimeout := s.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = 10 * time.Minute
+	}
+
+	return s.stream.serve(l, s.MaxSessions, func(ctx context.Context, conn net.Conn) {
+		secret := s.Secret
+		if s.ClientSecret != nil {
+			clientSecret, err := s.ClientSecret(conn)
+			if err != nil {
+				s.logf("radius: rejecting session from %v: %v", conn.RemoteAddr(), err)
+				conn.Close()
+				return
+			}
+			if clientSecret != nil {
+				secret = clientSecret
+			}
+		}
+		if secret == nil {
+			secret = DTLSSecret
+		}
+		var state *tls.ConnectionState
+		if c, ok := conn.(interface{ ConnectionState() tls.ConnectionState }); ok {
+			cs := c.ConnectionState()
+			state = &cs
+		}
+
+		serveStreamConn(ctx, conn, streamConfig{
+			handler:              handler,
+			secret:               secret,
+			messageAuthenticator: s.MessageAuthenticator,
+			maxPacketSize:        s.MaxPacketSize,
+			idleTimeout:          idleTimeout,
+			datagram:             true,
+			tls:                  state,
+			metrics:              serverMetrics{metrics: s.Metrics, transport: "dtls"},
+			logger:               s.Logger,
+			tap:                  s.Tap,
+			logf:                 s.logf,
+		})
+	})
+}
+
+// Shutdown gracefully stops the server, in the same way as
+// RadSecServer.Shutdown.
+func (s *DTLSServer) Shutdown(ctx context.Context) error {
+	logEvent(s.Logger, ctx, LogLevelInfo, "radius: server shutting down")
+	return s.stream.closeShutdown(ctx)
+}
@@ -0,0 +1,102 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+// pipeDTLSListener is a net.Listener and DTLSDialer that connects clients and
+// servers with in-memory pipes, which preserve message boundaries, for
+// testing.
+type pipeDTLSListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+}
+
+func newPipeDTLSListener() *pipeDTLSListener {
+	return &pipeDTLSListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+func (l *pipeDTLSListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *pipeDTLSListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *pipeDTLSListener) Addr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2083}
+}
+
+func (l *pipeDTLSListener) DialDTLS(ctx context.Context, addr string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	// An invalid packet must be discarded without closing the session.
+	if _, err := client.Write([]byte("invalid")); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func TestDTLSServer(t *testing.T) {
+	l := newPipeDTLSListener()
+	server := &DTLSServer{
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			resp := r.Response(CodeAccessAccept)
+			resp.Add(typeUserName, r.Get(typeUserName))
+			w.Write(resp)
+		}),
+		MessageAuthenticator: MessageAuthenticatorAdd,
+		ErrorLog:             log.New(ioutil.Discard, "", 0),
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(l)
+	}()
+
+	client := &DTLSClient{
+		Dialer: l,
+		Client: &Client{Retry: 100 * time.Millisecond},
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, name := range []string{"tim", "ned"} {
+		packet := New(CodeAccessRequest, nil)
+		packet.Add(typeUserName, Attribute(name))
+		resp, err := client.Exchange(ctx, packet, "localhost")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != CodeAccessAccept || string(resp.Get(typeUserName)) != name {
+			t.Fatalf("unexpected response %v", resp)
+		}
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != ErrServerShutdown {
+		t.Fatalf("got Serve error %v; expecting ErrServerShutdown", err)
+	}
+}
@@ -0,0 +1,52 @@
+package radius
+
+import (
+	"sync"
+	"time"
+)
+
+// duplicateCache holds the encoded responses to recent requests, so that
+// retransmitted requests can be answered without calling the handler again
+// (RFC 5080 section 2.2.2).
+type duplicateCache struct {
+	mu        sync.Mutex
+	responses map[Fingerprint]cachedResponse
+	lastPrune time.Time
+}
+
+type cachedResponse struct {
+	wire    []byte
+	expires time.Time
+}
+
+// get returns the cached response to the request with the given fingerprint.
+func (c *duplicateCache) get(f Fingerprint, now time.Time) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	response, ok := c.responses[f]
+	if !ok || now.After(response.expires) {
+		return nil, false
+	}
+	return response.wire, true
+}
+
+// put caches the response to the request with the given fingerprint for ttl.
+func (c *duplicateCache) put(f Fingerprint, wire []byte, ttl time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.responses == nil {
+		c.responses = make(map[Fingerprint]cachedResponse)
+	}
+	if now.Sub(c.lastPrune) >= ttl {
+		for key, response := range c.responses {
+			if now.After(response.expires) {
+				delete(c.responses, key)
+			}
+		}
+		c.lastPrune = now
+	}
+	c.responses[f] = cachedResponse{
+		wire:    wire,
+		expires: now.Add(ttl),
+	}
+}
@@ -0,0 +1,98 @@
+package radius
+
+import (
+	"errors"
+	"time"
+)
+
+// Errors passed to ServerMetrics for packets that are discarded before they
+// are parsed.
+var (
+	errEmptySecret = errors.New("radius: empty secret returned from secret source")
+	errBadSecret   = errors.New("radius: bad secret")
+)
+
+// ServerMetrics receives measurements of the requests processed by a server.
+// Its methods are called concurrently, and must not block.
+//
+// transport identifies the kind of listener on which the packet was received:
+// the network of a PacketServer's listener (e.g. "udp"), or "tcp", "tls", or
+// "dtls" for TCPServer, RadSecServer, and DTLSServer.
+type ServerMetrics interface {
+	// PacketReceived is called for each packet read from a listener.
+	PacketReceived(transport string, code Code)
+	// PacketSent is called for each response sent.
+	PacketSent(transport string, code Code)
+	// PacketError is called when a received packet is discarded because it
+	// could not be parsed, or because its secret could not be obtained.
+	PacketError(transport string, err error)
+	// AuthFailure is called when a received packet is discarded because its
+	// Request Authenticator or Message-Authenticator is invalid or missing.
+	AuthFailure(transport string, err error)
+	// RequestHandled is called when the handler of a request returns.
+	// response is the code of the response that was written, or 0 if none
+	// was.
+	RequestHandled(transport string, request, response Code, elapsed time.Duration)
+	// QueueDepth is called with the number of requests waiting for a worker
+	// of a PacketServer, each time it changes.
+	QueueDepth(transport string, depth int)
+	// ConnectionOpened and ConnectionClosed are called when a connection or
+	// session of a stream or DTLS server is accepted and closed.
+	ConnectionOpened(transport string)
+	ConnectionClosed(transport string)
+}
+
+// serverMetrics reports the measurements of a transport to a ServerMetrics,
+// if it is non-nil.
+type serverMetrics struct {
+	metrics   ServerMetrics
+	transport string
+}
+
+func (m serverMetrics) received(code Code) {
+	if m.metrics != nil {
+		m.metrics.PacketReceived(m.transport, code)
+	}
+}
+
+func (m serverMetrics) sent(code Code) {
+	if m.metrics != nil {
+		m.metrics.PacketSent(m.transport, code)
+	}
+}
+
+func (m serverMetrics) packetError(err error) {
+	if m.metrics != nil {
+		m.metrics.PacketError(m.transport, err)
+	}
+}
+
+func (m serverMetrics) authFailure(err error) {
+	if m.metrics != nil {
+		m.metrics.AuthFailure(m.transport, err)
+	}
+}
+
+func (m serverMetrics) handled(request, response Code, start time.Time) {
+	if m.metrics != nil {
+		m.metrics.RequestHandled(m.transport, request, response, time.Since(start))
+	}
+}
+
+func (m serverMetrics) queueDepth(depth int) {
+	if m.metrics != nil {
+		m.metrics.QueueDepth(m.transport, depth)
+	}
+}
+
+func (m serverMetrics) opened() {
+	if m.metrics != nil {
+		m.metrics.ConnectionOpened(m.transport)
+	}
+}
+
+func (m serverMetrics) closed() {
+	if m.metrics != nil {
+		m.metrics.ConnectionClosed(m.transport)
+	}
+}
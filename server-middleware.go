@@ -0,0 +1,113 @@
+package radius
+
+import (
+	"log"
+	"time"
+)
+
+// Middleware wraps a server Handler with additional behavior, in the same way
+// as net/http middleware.
+type Middleware func(next Handler) Handler
+
+// ChainMiddleware returns a Handler that passes requests through each
+// middleware in turn before they reach h. The first middleware is the
+// outermost.
+func ChainMiddleware(h Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// recordingResponseWriter records the response written to a ResponseWriter.
+type recordingResponseWriter struct {
+	ResponseWriter
+	response *Packet
+}
+
+func (w *recordingResponseWriter) Write(packet *Packet) error {
+	if err := w.ResponseWriter.Write(packet); err != nil {
+		return err
+	}
+	w.response = packet
+	return nil
+}
+
+// serverLogf logs to logger, or to the standard logger if it is nil.
+func serverLogf(logger *log.Logger, format string, args ...interface{}) {
+	if logger != nil {
+		logger.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// LoggingMiddleware returns a Middleware that logs each request, with the code
+// of its response and the time taken to handle it, to logger. If logger is
+// nil, the log package's standard logger is used.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			start := time.Now()
+			recorder := &recordingResponseWriter{ResponseWriter: w}
+			next.ServeRADIUS(recorder, r)
+			response := "no response"
+			if recorder.response != nil {
+				response = recorder.response.Code.String()
+			}
+			serverLogf(logger, "radius: %v (id %d) from %v: %s in %v", r.Code, r.Identifier, r.RemoteAddr, response, time.Since(start))
+		})
+	}
+}
+
+// MetricsMiddleware returns a Middleware that calls observe after each request
+// has been handled, with the code of the request, the code of the response (0
+// if none was sent), and the time taken to handle it.
+func MetricsMiddleware(observe func(request, response Code, elapsed time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			start := time.Now()
+			recorder := &recordingResponseWriter{ResponseWriter: w}
+			next.ServeRADIUS(recorder, r)
+			var response Code
+			if recorder.response != nil {
+				response = recorder.response.Code
+			}
+			observe(r.Code, response, time.Since(start))
+		})
+	}
+}
+
+// MessageAuthenticatorMiddleware is a Middleware that discards Access-Request
+// and Status-Server packets without a Message-Authenticator attribute, and
+// any request whose Message-Authenticator is invalid. A Message-Authenticator
+// attribute is added to every response.
+//
+// The value of the attribute is verified by the middleware itself, regardless
+// of the server's MessageAuthenticator policy.
+func MessageAuthenticatorMiddleware(next Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		if _, ok := r.Lookup(typeMessageAuthenticator); ok {
+			wire, err := r.receivedWire()
+			if err != nil || !IsValidMessageAuthenticator(wire, nil, r.Secret, r.AuthAlgorithm) {
+				return
+			}
+		} else if requiresMessageAuthenticator(r.Code) {
+			return
+		}
+		next.ServeRADIUS(messageAuthenticatorResponseWriter{w}, r)
+	})
+}
+
+type messageAuthenticatorResponseWriter struct {
+	ResponseWriter
+}
+
+func (w messageAuthenticatorResponseWriter) Write(packet *Packet) error {
+	if packet.MessageAuthenticatorPolicy == MessageAuthenticatorIgnore {
+		response := *packet
+		response.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+		packet = &response
+	}
+	return w.ResponseWriter.Write(packet)
+}
@@ -0,0 +1,152 @@
+package radius
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPacketServer_middleware(t *testing.T) {
+	secret := []byte(`12345`)
+
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var logs bytes.Buffer
+	logger := log.New(lockedWriter{&mu, &logs}, "", 0)
+	var observed []Code
+
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			if string(r.Get(typeUserName)) == "panic" {
+				panic("handler failure")
+			}
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		Middleware: []Middleware{
+			LoggingMiddleware(logger),
+			MetricsMiddleware(func(request, response Code, elapsed time.Duration) {
+				mu.Lock()
+				observed = append(observed, response)
+				mu.Unlock()
+			}),
+			RecoveryMiddleware(RecoveryPolicy{ErrorLog: logger}),
+			MessageAuthenticatorMiddleware,
+		},
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+	addr := pc.LocalAddr().String()
+
+	client := Client{
+		Retry:                50 * time.Millisecond,
+		MessageAuthenticator: MessageAuthenticatorRequire,
+	}
+	request := New(CodeAccessRequest, secret)
+	request.Add(typeUserName, Attribute("alice"))
+	if _, err := client.Exchange(context.Background(), request, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	// requests without a Message-Authenticator and requests that panic are
+	// not answered
+	noMessageAuthenticator := New(CodeAccessRequest, secret)
+	panics := New(CodeAccessRequest, secret)
+	panics.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+	panics.Add(typeUserName, Attribute("panic"))
+	for _, packet := range []*Packet{noMessageAuthenticator, panics} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		_, err := (&Client{Retry: 50 * time.Millisecond}).Exchange(ctx, packet, addr)
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Fatalf("got %v; expecting context.DeadlineExceeded", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(observed) < 3 || observed[0] != CodeAccessAccept || observed[1] != 0 || observed[len(observed)-1] != 0 {
+		t.Fatalf("got observed responses %v", observed)
+	}
+	if !strings.Contains(logs.String(), "Access-Accept") || !strings.Contains(logs.String(), "panic serving") {
+		t.Fatalf("unexpected logs:\n%s", logs.String())
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	b  *bytes.Buffer
+}
+
+func (w lockedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.Write(b)
+}
+
+func TestPacketServer_MessageAuthenticatorMiddleware_invalid(t *testing.T) {
+	secret := []byte(`12345`)
+
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		Middleware: []Middleware{MessageAuthenticatorMiddleware},
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, tamper := range []bool{true, false} {
+		request := New(CodeAccessRequest, secret)
+		request.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+		wire, err := request.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset := findMessageAuthenticator(wire)
+		if tamper {
+			for i := offset; i < offset+messageAuthenticatorLen; i++ {
+				wire[i] = 0xff
+			}
+		}
+		if _, err := conn.Write(wire); err != nil {
+			t.Fatal(err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		b := make([]byte, MaxPacketLength)
+		n, err := conn.Read(b)
+		if tamper {
+			if err == nil {
+				t.Fatalf("got %v for an invalid Message-Authenticator; expecting no response", Code(b[0]))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Code(b[0]) != CodeAccessAccept || !IsValidMessageAuthenticator(b[:n], request.Authenticator[:], secret, nil) {
+			t.Fatalf("unexpected response %x", b[:n])
+		}
+	}
+}
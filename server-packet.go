@@ -7,12 +7,21 @@ import (
 	"net"
 	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 type packetResponseWriter struct {
 	// listener that received the packet
 	conn net.PacketConn
 	addr net.Addr
+	// local address on which the packet was received
+	localAddr net.Addr
+
+	// encoded response that was written
+	written []byte
+
+	metrics serverMetrics
 }
 
 func (r *packetResponseWriter) Write(packet *Packet) error {
@@ -20,9 +29,11 @@ func (r *packetResponseWriter) Write(packet *Packet) error {
 	if err != nil {
 		return err
 	}
-	if _, err := r.conn.WriteTo(encoded, r.addr); err != nil {
+	if _, err := writeToLocal(r.conn, encoded, r.addr, r.localAddr); err != nil {
 		return err
 	}
+	r.written = encoded
+	r.metrics.sent(packet.Code)
 	return nil
 }
 
@@ -42,6 +53,103 @@ type PacketServer struct {
 	// Handler which is called to process the request.
 	Handler Handler
 
+	// Middleware is applied, outermost first, to Handler.
+	Middleware []Middleware
+
+	// Recovery, if non-nil, recovers from panics in Handler and Middleware,
+	// and answers the request as it configures. If nil, a panic in a
+	// handler crashes the program.
+	Recovery *RecoveryPolicy
+
+	// RateLimiter, if non-nil, is consulted for each incoming packet before
+	// it is processed. Packets that are not allowed are silently discarded.
+	RateLimiter RateLimiter
+
+	// ParseOptions, if non-nil, configures how strictly incoming packets are
+	// parsed. Packets that cannot be parsed are discarded.
+	ParseOptions *ParseOptions
+
+	// MessageAuthenticator controls how the Message-Authenticator of incoming
+	// requests is verified, and whether a Message-Authenticator attribute is
+	// added to responses. Requests that fail verification are discarded.
+	MessageAuthenticator MessageAuthenticatorPolicy
+
+	// ClientMessageAuthenticator, if non-nil, returns the policy used instead
+	// of MessageAuthenticator for requests from remoteAddr, so that
+	// MessageAuthenticatorRequire can be enforced one client at a time.
+	ClientMessageAuthenticator func(remoteAddr net.Addr) MessageAuthenticatorPolicy
+
+	// RejectMissingMessageAuthenticator, if true, answers Access-Requests
+	// that are discarded for lacking a required Message-Authenticator with an
+	// Access-Reject, instead of silently discarding them.
+	RejectMissingMessageAuthenticator bool
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of
+	// requests accepted and responses sent by the server, up to
+	// MaxExtendedPacketLength. It is also used when ParseOptions does not set
+	// its own MaxPacketSize. If zero, MaxPacketLength is used.
+	MaxPacketSize int
+
+	// StatusServerReply, if non-zero, is the code of the response (either
+	// CodeAccessAccept or CodeAccountingResponse) that the server sends to
+	// Status-Server requests (RFC 5997) itself, without calling Handler.
+	// StatusServerAttributes, if non-nil, returns the attributes added to
+	// these responses (e.g. a Reply-Message with the server's uptime).
+	StatusServerReply      Code
+	StatusServerAttributes func(r *Request) Attributes
+
+	// Workers, if positive, is the number of goroutines that handle requests
+	// received by each call to Serve. Requests are queued for the workers,
+	// up to QueueSize (which defaults to Workers), and Overload determines
+	// what is done with requests that arrive when the queue is full. If zero,
+	// a goroutine is started for every request.
+	Workers   int
+	QueueSize int
+	Overload  OverloadPolicy
+
+	// ReusePortSockets, if greater than one, is the number of sockets that
+	// ListenAndServe opens on Addr with SO_REUSEPORT, each served in its own
+	// goroutine, so that the kernel spreads the incoming requests across
+	// them and they are received in parallel. Workers applies to each
+	// socket. SO_REUSEPORT is only supported on Linux; elsewhere, a single
+	// socket is opened.
+	ReusePortSockets int
+
+	// Control, if non-nil, is called by ListenAndServe after creating each
+	// socket and before binding it, as in net.ListenConfig, so that socket
+	// options can be set.
+	Control func(network, address string, c syscall.RawConn) error
+
+	// PacketInfo, if true, has the server obtain the destination address of
+	// each request received on a UDP socket, with IP_PKTINFO, so that the
+	// LocalAddr of requests received on a socket bound to an unspecified
+	// address (e.g. :1812) is the address of the receiving interface, and
+	// responses are sent from that address. This is needed on multi-homed
+	// hosts. It is only supported on Linux, and ignored elsewhere.
+	PacketInfo bool
+
+	// HandlerTimeout, if positive, limits the time given to Handler for each
+	// request; the request's context is canceled when it expires. If Handler
+	// has not responded by then, OnHandlerTimeout is called, an
+	// Access-Request is answered with a response of code TimeoutReply (e.g.
+	// CodeAccessReject) if it is non-zero, and responses later written by
+	// Handler are discarded. Other requests are dropped.
+	HandlerTimeout   time.Duration
+	TimeoutReply     Code
+	OnHandlerTimeout func(r *Request)
+
+	// DuplicateCacheTTL, if positive, is how long the response to each
+	// request is cached. Retransmissions of a request (with the same source,
+	// Code, Identifier, and Authenticator) that are received within this
+	// time are answered with the cached response, without calling Handler,
+	// as recommended by RFC 5080 section 2.2.2. Retransmissions received
+	// while the request is still being handled are always discarded.
+	DuplicateCacheTTL time.Duration
+
+	// Metrics, if non-nil, receives measurements of the requests processed
+	// by the server.
+	Metrics ServerMetrics
+
 	// Skip incoming packet authenticity validation.
 	// This should only be set to true for debugging purposes.
 	InsecureSkipVerify bool
@@ -51,13 +159,28 @@ type PacketServer struct {
 	// If nil, logging is done via the log package's standard logger.
 	ErrorLog *log.Logger
 
+	// Logger, if non-nil, receives structured events for received packets,
+	// parse errors, secret lookup failures, and shutdown. Errors are passed
+	// to it instead of ErrorLog.
+	Logger Logger
+
+	// Tap, if non-nil, receives a copy of every packet read from or written
+	// to the server's listeners.
+	Tap Tap
+
 	shutdownRequested int32
 
+	duplicates duplicateCache
+
+	statsMu sync.Mutex
+	stats   MessageAuthenticatorStats
+
 	mu          sync.Mutex
 	ctx         context.Context
 	ctxDone     context.CancelFunc
 	listeners   map[net.PacketConn]uint
-	lastActive  chan struct{} // closed when the last active item finishes
+	draining    []net.PacketConn // listeners to close once Shutdown finishes
+	lastActive  chan struct{}    // closed when the last active item finishes
 	activeCount int32
 }
 
@@ -80,11 +203,17 @@ func (s *PacketServer) activeDone() {
 }
 
 func (s *PacketServer) logf(format string, args ...interface{}) {
-	if s.ErrorLog != nil {
-		s.ErrorLog.Printf(format, args...)
-	} else {
-		log.Printf(format, args...)
+	errorLogf(s.Logger, s.ErrorLog, format, args...)
+}
+
+// metrics returns the reporter of the measurements of requests received on
+// conn.
+func (s *PacketServer) metrics(conn net.PacketConn) serverMetrics {
+	m := serverMetrics{metrics: s.Metrics}
+	if s.Metrics != nil && conn.LocalAddr() != nil {
+		m.transport = conn.LocalAddr().Network()
 	}
+	return m
 }
 
 // Serve accepts incoming connections on conn.
@@ -95,6 +224,15 @@ func (s *PacketServer) Serve(conn net.PacketConn) error {
 	if s.SecretSource == nil {
 		return errors.New("radius: nil SecretSource")
 	}
+	if s.PacketInfo {
+		var err error
+		if conn, err = packetInfoConn(conn); err != nil {
+			return err
+		}
+	}
+	if s.Tap != nil {
+		conn = &tapPacketConn{conn, s.Tap}
+	}
 
 	s.mu.Lock()
 	s.initLocked()
@@ -127,9 +265,102 @@ func (s *PacketServer) Serve(conn net.PacketConn) error {
 		s.activeDone()
 	}()
 
-	var buff [MaxPacketLength]byte
+	handler := recoverHandler(ChainMiddleware(s.Handler, s.Middleware...), s.Recovery, s.logf)
+	metrics := s.metrics(conn)
+	var transport string
+	if conn.LocalAddr() != nil {
+		transport = conn.LocalAddr().Network()
+	}
+
+	process := func(buff []byte, remoteAddr, localAddr net.Addr) {
+		defer s.activeDone()
+
+		packet := s.parseRequest(conn, buff, remoteAddr, localAddr)
+		if packet == nil {
+			return
+		}
+
+		var fingerprint Fingerprint
+		if s.DuplicateCacheTTL > 0 {
+			fingerprint = packet.Fingerprint(remoteAddr.Network() + ":" + remoteAddr.String())
+			if wire, ok := s.duplicates.get(fingerprint, time.Now()); ok {
+				if _, err := writeToLocal(conn, wire, remoteAddr, localAddr); err != nil {
+					s.logf("radius: unable to resend cached response: %v", err)
+				} else {
+					metrics.sent(Code(wire[0]))
+				}
+				return
+			}
+		}
+
+		key := requestKey{
+			IP:         remoteAddr.String(),
+			Identifier: packet.Identifier,
+		}
+
+		requestsLock.Lock()
+		if _, ok := requests[key]; ok {
+			requestsLock.Unlock()
+			return
+		}
+		requests[key] = struct{}{}
+		requestsLock.Unlock()
+
+		response := packetResponseWriter{
+			conn:      conn,
+			addr:      remoteAddr,
+			localAddr: localAddr,
+			metrics:   metrics,
+		}
+
+		defer func() {
+			requestsLock.Lock()
+			delete(requests, key)
+			requestsLock.Unlock()
+		}()
+
+		request := Request{
+			LocalAddr:  localAddr,
+			RemoteAddr: remoteAddr,
+			Transport:  transport,
+			Packet:     packet,
+			ctx:        s.ctx,
+		}
+
+		if packet.Code == CodeStatusServer && s.StatusServerReply != 0 {
+			reply := statusServerResponse(&request, s.StatusServerReply, s.StatusServerAttributes)
+			if err := response.Write(reply); err != nil {
+				s.logf("radius: unable to reply to Status-Server: %v", err)
+			}
+			return
+		}
+
+		start := time.Now()
+		if s.HandlerTimeout > 0 {
+			s.serveWithTimeout(handler, &response, &request)
+		} else {
+			handler.ServeRADIUS(&response, &request)
+		}
+		var responseCode Code
+		if response.written != nil {
+			responseCode = Code(response.written[0])
+		}
+		metrics.handled(packet.Code, responseCode, start)
+
+		if s.DuplicateCacheTTL > 0 && response.written != nil {
+			s.duplicates.put(fingerprint, response.written, s.DuplicateCacheTTL, time.Now())
+		}
+	}
+
+	var queue chan packetJob
+	if s.Workers > 0 {
+		queue = s.startWorkers(process, metrics)
+		defer close(queue)
+	}
+
+	buff := make([]byte, maxPacketSize(s.MaxPacketSize))
 	for {
-		n, remoteAddr, err := conn.ReadFrom(buff[:])
+		n, remoteAddr, localAddr, err := readFromLocal(conn, buff[:])
 		if err != nil {
 			if atomic.LoadInt32(&s.shutdownRequested) == 1 {
 				return ErrServerShutdown
@@ -142,64 +373,164 @@ func (s *PacketServer) Serve(conn net.PacketConn) error {
 			continue
 		}
 
+		if n > 0 {
+			metrics.received(Code(buff[0]))
+		}
+
+		if s.RateLimiter != nil && !s.RateLimiter.Allow(remoteAddr) {
+			continue
+		}
+
 		s.activeAdd()
-		go func(buff []byte, remoteAddr net.Addr) {
-			defer s.activeDone()
+		job := packetJob{append([]byte(nil), buff[:n]...), remoteAddr, localAddr}
+		if queue == nil {
+			go process(job.buff, job.remoteAddr, job.localAddr)
+			continue
+		}
+		s.enqueue(queue, job, conn, metrics)
+	}
+}
 
-			secret, err := s.SecretSource.RADIUSSecret(s.ctx, remoteAddr)
-			if err != nil {
-				s.logf("radius: error fetching from secret source: %v", err)
-				return
-			}
-			if len(secret) == 0 {
-				s.logf("radius: empty secret returned from secret source")
-				return
-			}
+// parseRequest verifies and parses the request in buff, received from
+// remoteAddr on localAddr of conn. nil is returned, and the error logged, if
+// the request is invalid.
+func (s *PacketServer) parseRequest(conn net.PacketConn, buff []byte, remoteAddr, localAddr net.Addr) *Packet {
+	metrics := s.metrics(conn)
+	secret, err := s.SecretSource.RADIUSSecret(s.ctx, remoteAddr)
+	if err != nil {
+		if !logEvent(s.Logger, s.ctx, LogLevelError, "radius: secret lookup failed", "remote", remoteAddr.String(), "error", err) {
+			s.logf("radius: error fetching from secret source: %v", err)
+		}
+		metrics.packetError(err)
+		return nil
+	}
+	if len(secret) == 0 {
+		if !logEvent(s.Logger, s.ctx, LogLevelWarn, "radius: secret lookup failed", "remote", remoteAddr.String(), "error", errEmptySecret) {
+			s.logf("radius: empty secret returned from secret source")
+		}
+		metrics.packetError(errEmptySecret)
+		return nil
+	}
 
-			if !s.InsecureSkipVerify && !IsAuthenticRequest(buff, secret) {
-				s.logf("radius: packet validation failed; bad secret")
-				return
-			}
+	if !s.InsecureSkipVerify && !IsAuthenticRequest(buff, secret) {
+		if !logEvent(s.Logger, s.ctx, LogLevelWarn, "radius: packet validation failed", "remote", remoteAddr.String(), "error", errBadSecret) {
+			s.logf("radius: packet validation failed; bad secret")
+		}
+		metrics.authFailure(errBadSecret)
+		return nil
+	}
 
-			packet, err := Parse(buff, secret)
-			if err != nil {
-				s.logf("radius: unable to parse packet: %v", err)
-				return
+	clientPolicy := s.MessageAuthenticator
+	if s.ClientMessageAuthenticator != nil {
+		clientPolicy = s.ClientMessageAuthenticator(remoteAddr)
+	}
+	if !s.InsecureSkipVerify {
+		policy := clientPolicy
+		if Code(buff[0]) == CodeStatusServer {
+			// RFC 5997 section 3
+			policy = MessageAuthenticatorRequire
+		}
+		missing := Code(buff[0]) == CodeAccessRequest && !hasMessageAuthenticator(buff)
+		err := policy.Verify(buff, nil, secret, nil)
+		s.countMessageAuthenticator(missing, err)
+		if err != nil {
+			if !logEvent(s.Logger, s.ctx, LogLevelWarn, "radius: packet validation failed", "remote", remoteAddr.String(), "error", err) {
+				s.logf("radius: packet validation failed; %v", err)
 			}
-
-			key := requestKey{
-				IP:         remoteAddr.String(),
-				Identifier: packet.Identifier,
+			metrics.authFailure(err)
+			if missing && s.RejectMissingMessageAuthenticator {
+				s.rejectRequest(conn, buff, secret, remoteAddr, localAddr)
 			}
+			return nil
+		}
+	}
 
-			requestsLock.Lock()
-			if _, ok := requests[key]; ok {
-				requestsLock.Unlock()
-				return
-			}
-			requests[key] = struct{}{}
-			requestsLock.Unlock()
+	var opts ParseOptions
+	if s.ParseOptions != nil {
+		opts = *s.ParseOptions
+	}
+	if opts.MaxPacketSize <= 0 {
+		opts.MaxPacketSize = s.MaxPacketSize
+	}
+	packet, err := ParseWith(buff, secret, opts)
+	if err != nil {
+		if !logEvent(s.Logger, s.ctx, LogLevelWarn, "radius: unable to parse packet", "remote", remoteAddr.String(), "error", err) {
+			s.logf("radius: unable to parse packet: %v", err)
+		}
+		metrics.packetError(err)
+		return nil
+	}
+	logEvent(s.Logger, s.ctx, LogLevelDebug, "radius: received packet",
+		"remote", remoteAddr.String(),
+		"code", packet.Code.String(),
+		"identifier", packet.Identifier,
+		"attributes", redactedAttributes(packet.Attributes))
+	packet.MessageAuthenticatorPolicy = clientPolicy
+	packet.MaxPacketSize = s.MaxPacketSize
+	return packet
+}
 
-			response := packetResponseWriter{
-				conn: conn,
-				addr: remoteAddr,
-			}
+// rejectRequest answers the Access-Request in buff with an Access-Reject,
+// which carries a Message-Authenticator.
+func (s *PacketServer) rejectRequest(conn net.PacketConn, buff, secret []byte, remoteAddr, localAddr net.Addr) {
+	packet, err := ParseWith(buff, secret, ParseOptions{MaxPacketSize: s.MaxPacketSize})
+	if err != nil {
+		return
+	}
+	response := packet.Response(CodeAccessReject)
+	response.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+	CopyProxyState(response, packet)
+	encoded, err := response.Encode()
+	if err != nil {
+		s.logf("radius: unable to encode Access-Reject: %v", err)
+		return
+	}
+	if _, err := writeToLocal(conn, encoded, remoteAddr, localAddr); err != nil {
+		s.logf("radius: unable to send Access-Reject: %v", err)
+		return
+	}
+	s.metrics(conn).sent(CodeAccessReject)
+}
 
-			defer func() {
-				requestsLock.Lock()
-				delete(requests, key)
-				requestsLock.Unlock()
-			}()
-
-			request := Request{
-				LocalAddr:  conn.LocalAddr(),
-				RemoteAddr: remoteAddr,
-				Packet:     packet,
-				ctx:        s.ctx,
-			}
+// MessageAuthenticatorStats counts the requests received by a PacketServer
+// without a valid Message-Authenticator.
+type MessageAuthenticatorStats struct {
+	// Missing is the number of Access-Requests received without a
+	// Message-Authenticator, including the ones that were accepted because
+	// the policy for their client did not require it. It is the number of
+	// requests that MessageAuthenticatorRequire would discard.
+	Missing uint64
+	// Dropped is the number of requests discarded, or rejected, because they
+	// lacked a required Message-Authenticator.
+	Dropped uint64
+	// Invalid is the number of requests discarded because their
+	// Message-Authenticator was invalid.
+	Invalid uint64
+}
 
-			s.Handler.ServeRADIUS(&response, &request)
-		}(append([]byte(nil), buff[:n]...), remoteAddr)
+// MessageAuthenticatorStats returns the counts of the requests received by
+// the server without a valid Message-Authenticator.
+func (s *PacketServer) MessageAuthenticatorStats() MessageAuthenticatorStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	return s.stats
+}
+
+func (s *PacketServer) countMessageAuthenticator(missing bool, err error) {
+	if !missing && err == nil {
+		return
+	}
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	if missing {
+		s.stats.Missing++
+	}
+	if maErr, ok := err.(*MessageAuthenticatorError); ok {
+		if maErr.Missing {
+			s.stats.Dropped++
+		} else {
+			s.stats.Invalid++
+		}
 	}
 }
 
@@ -222,27 +553,83 @@ func (s *PacketServer) ListenAndServe() error {
 		network = s.Network
 	}
 
-	pc, err := net.ListenPacket(network, addrStr)
+	conns, err := listenPackets(network, addrStr, s.ReusePortSockets, s.Control)
 	if err != nil {
 		return err
 	}
-	defer pc.Close()
-	return s.Serve(pc)
+	errs := make(chan error, len(conns))
+	for _, pc := range conns {
+		go func(pc net.PacketConn) {
+			errs <- s.Serve(pc)
+		}(pc)
+	}
+	err = <-errs
+	if err != ErrServerShutdown {
+		// Otherwise, the listeners are closed by Shutdown once the
+		// in-flight requests have been answered.
+		for _, pc := range conns {
+			pc.Close()
+		}
+	}
+	for i := 1; i < len(conns); i++ {
+		<-errs
+	}
+	return err
+}
+
+// listenPackets opens n sockets on address with SO_REUSEPORT, or a single
+// socket if n is less than two or SO_REUSEPORT is not supported. control, if
+// non-nil, is called for each socket before it is bound.
+func listenPackets(network, address string, n int, control func(network, address string, c syscall.RawConn) error) ([]net.PacketConn, error) {
+	if n < 2 || !reusePortSupported {
+		config := net.ListenConfig{Control: control}
+		pc, err := config.ListenPacket(context.Background(), network, address)
+		if err != nil {
+			return nil, err
+		}
+		return []net.PacketConn{pc}, nil
+	}
+
+	conns := make([]net.PacketConn, 0, n)
+	for len(conns) < n {
+		if len(conns) == 1 {
+			// Bind the remaining sockets to the port chosen for the first if
+			// address has none.
+			address = conns[0].LocalAddr().String()
+		}
+		pc, err := listenReusePort(network, address, control)
+		if err != nil {
+			for _, pc := range conns {
+				pc.Close()
+			}
+			return nil, err
+		}
+		conns = append(conns, pc)
+	}
+	return conns, nil
 }
 
-// Shutdown gracefully stops the server. It first closes all listeners and then
-// waits for any running handlers to complete.
+// Shutdown gracefully stops the server. It first stops reading new requests
+// from the listeners, and cancels the context of the running handlers. It then
+// waits for the handlers to complete, so that the responses to requests that
+// were already being processed are still sent, before closing the listeners.
 //
-// Shutdown returns after nil all handlers have completed. ctx.Err() is
-// returned if ctx is canceled.
+// Shutdown returns nil after all handlers have completed. If ctx is canceled
+// first, the listeners are closed, and ctx.Err() is returned.
 //
 // Any Serve methods return ErrShutdown after Shutdown is called.
 func (s *PacketServer) Shutdown(ctx context.Context) error {
+	logEvent(s.Logger, ctx, LogLevelInfo, "radius: server shutting down")
 	s.mu.Lock()
 	s.initLocked()
 	if atomic.CompareAndSwapInt32(&s.shutdownRequested, 0, 1) {
 		for listener := range s.listeners {
-			listener.Close()
+			s.draining = append(s.draining, listener)
+			// Unblock Serve without closing the listener, which is still
+			// used to send responses.
+			if listener.SetReadDeadline(time.Now()) != nil {
+				listener.Close()
+			}
 		}
 
 		s.ctxDone()
@@ -250,10 +637,18 @@ func (s *PacketServer) Shutdown(ctx context.Context) error {
 	}
 	s.mu.Unlock()
 
+	var err error
 	select {
 	case <-s.lastActive:
-		return nil
 	case <-ctx.Done():
-		return ctx.Err()
+		err = ctx.Err()
 	}
+
+	s.mu.Lock()
+	for _, listener := range s.draining {
+		listener.Close()
+	}
+	s.draining = nil
+	s.mu.Unlock()
+	return err
 }
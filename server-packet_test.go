@@ -3,6 +3,8 @@ package radius
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"net"
 	"sync/atomic"
 	"testing"
@@ -186,3 +188,245 @@ func TestPacketServer_singleUse(t *testing.T) {
 		t.Fatalf("got err %v; expecting ErrServerShutdown", err)
 	}
 }
+
+func TestPacketServer_messageAuthenticator(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	server := PacketServer{
+		SecretSource:         StaticSecretSource(secret),
+		MessageAuthenticator: MessageAuthenticatorRequire,
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	client := Client{
+		Retry: time.Millisecond * 5,
+	}
+	if _, err := client.Exchange(ctx, New(CodeAccessRequest, secret), pc.LocalAddr().String()); err != context.DeadlineExceeded {
+		t.Fatalf("got err = %v; expecting request without Message-Authenticator to be dropped", err)
+	}
+
+	client.MessageAuthenticator = MessageAuthenticatorRequire
+	client.MaxPacketErrors = 1
+	resp, err := client.Exchange(context.Background(), New(CodeAccessRequest, secret), pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Lookup(typeMessageAuthenticator); !ok {
+		t.Fatal("expecting response to contain a Message-Authenticator")
+	}
+}
+
+func TestPacketServer_statusServer(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	var handlerCalls int32
+	server := PacketServer{
+		SecretSource:      StaticSecretSource(secret),
+		StatusServerReply: CodeAccessAccept,
+		StatusServerAttributes: func(r *Request) Attributes {
+			return Attributes{{Type: 18, Attribute: Attribute("up")}}
+		},
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			atomic.AddInt32(&handlerCalls, 1)
+		}),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	req := New(CodeStatusServer, secret)
+	client := Client{
+		Retry:                time.Millisecond * 5,
+		MaxPacketErrors:      1,
+		MessageAuthenticator: MessageAuthenticatorRequire,
+	}
+	resp, err := client.Exchange(context.Background(), req, pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessAccept {
+		t.Fatalf("got %v; expecting Access-Accept", resp.Code)
+	}
+	if _, ok := resp.Lookup(typeMessageAuthenticator); !ok {
+		t.Fatal("expecting response to contain a Message-Authenticator")
+	}
+	if got := string(resp.Get(18)); got != "up" {
+		t.Fatalf("got Reply-Message %q; expecting configured attribute", got)
+	}
+	if n := atomic.LoadInt32(&handlerCalls); n != 0 {
+		t.Fatalf("got %d handler calls; expecting 0", n)
+	}
+
+	// Status-Server without a Message-Authenticator is discarded
+	wire, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(wire)
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 50))
+	var b [MaxPacketLength]byte
+	if _, err := conn.Read(b[:]); err == nil {
+		t.Fatal("expecting no response to Status-Server without Message-Authenticator")
+	}
+}
+
+func TestPacketServer_duplicateCache(t *testing.T) {
+	secret := []byte(`12345`)
+
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		DuplicateCacheTTL: time.Minute,
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	wire, err := New(CodeAccessRequest, secret).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var responses [][]byte
+	for i := 0; i < 2; i++ {
+		if _, err := conn.Write(wire); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		b := make([]byte, MaxPacketLength)
+		n, err := conn.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, b[:n])
+	}
+	if string(responses[0]) != string(responses[1]) {
+		t.Fatal("retransmission was not answered with the cached response")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("handler called %d times; expecting 1", n)
+	}
+}
+
+func TestPacketServer_shutdownDrain(t *testing.T) {
+	secret := []byte(`12345`)
+
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlerCalled := make(chan struct{})
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			close(handlerCalled)
+			time.Sleep(50 * time.Millisecond)
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(pc)
+	}()
+
+	responses := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := Exchange(ctx, New(CodeAccessRequest, secret), pc.LocalAddr().String())
+		responses <- err
+	}()
+
+	<-handlerCalled
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("got Shutdown error %v; expecting nil", err)
+	}
+	if err := <-serveErr; err != ErrServerShutdown {
+		t.Fatalf("got Serve error %v; expecting ErrServerShutdown", err)
+	}
+	if err := <-responses; err != nil {
+		t.Fatalf("response to in-flight request was not sent: %v", err)
+	}
+	if _, err := pc.WriteTo([]byte{0}, pc.LocalAddr()); err == nil {
+		t.Fatal("listener was not closed")
+	}
+}
+
+func TestPacketServer_requireMessageAuthenticator(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		ClientMessageAuthenticator: func(remoteAddr net.Addr) MessageAuthenticatorPolicy {
+			return MessageAuthenticatorRequire
+		},
+		RejectMissingMessageAuthenticator: true,
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	client := Client{
+		Retry: time.Millisecond * 5,
+	}
+	resp, err := client.Exchange(context.Background(), New(CodeAccessRequest, secret), pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessReject {
+		t.Fatalf("got %v; expecting Access-Reject", resp.Code)
+	}
+	if _, ok := resp.Lookup(typeMessageAuthenticator); !ok {
+		t.Fatal("expecting Access-Reject to contain a Message-Authenticator")
+	}
+
+	client.MessageAuthenticator = MessageAuthenticatorRequire
+	resp, err = client.Exchange(context.Background(), New(CodeAccessRequest, secret), pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessAccept {
+		t.Fatalf("got %v; expecting Access-Accept", resp.Code)
+	}
+
+	if stats := server.MessageAuthenticatorStats(); stats.Missing == 0 || stats.Dropped != stats.Missing || stats.Invalid != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
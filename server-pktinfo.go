@@ -0,0 +1,35 @@
+package radius
+
+import (
+	"net"
+)
+
+// localPacketConn is a net.PacketConn that reports the local address on which
+// each packet is received, and that can send packets from a given local
+// address. On sockets bound to an unspecified address, this is the address
+// of the interface that received the packet, rather than the address of the
+// socket.
+type localPacketConn interface {
+	net.PacketConn
+	readFromLocal(b []byte) (n int, remoteAddr, localAddr net.Addr, err error)
+	writeToLocal(b []byte, remoteAddr, localAddr net.Addr) (int, error)
+}
+
+// readFromLocal reads a packet from conn, and returns the local address on
+// which it was received, if conn reports it, or the address of conn.
+func readFromLocal(conn net.PacketConn, b []byte) (int, net.Addr, net.Addr, error) {
+	if lc, ok := conn.(localPacketConn); ok {
+		return lc.readFromLocal(b)
+	}
+	n, remoteAddr, err := conn.ReadFrom(b)
+	return n, remoteAddr, conn.LocalAddr(), err
+}
+
+// writeToLocal writes a packet to remoteAddr on conn, sent from localAddr if
+// conn supports it.
+func writeToLocal(conn net.PacketConn, b []byte, remoteAddr, localAddr net.Addr) (int, error) {
+	if lc, ok := conn.(localPacketConn); ok {
+		return lc.writeToLocal(b, remoteAddr, localAddr)
+	}
+	return conn.WriteTo(b, remoteAddr)
+}
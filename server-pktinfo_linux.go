@@ -0,0 +1,108 @@
+package radius
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// pktinfoConn is a localPacketConn that obtains the destination address of
+// each packet with IP_PKTINFO and IPV6_PKTINFO, and sets the source address
+// of responses with the same control messages.
+type pktinfoConn struct {
+	*net.UDPConn
+}
+
+// packetInfoConn returns conn, wrapped to report the local address on which
+// each packet is received, if it is a UDP socket.
+func packetInfoConn(conn net.PacketConn) (net.PacketConn, error) {
+	udp, ok := conn.(*net.UDPConn)
+	if !ok {
+		return conn, nil
+	}
+	rc, err := udp.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var v4Err, v6Err error
+	err = rc.Control(func(fd uintptr) {
+		v4Err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_PKTINFO, 1)
+		v6Err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_RECVPKTINFO, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if v4Err != nil && v6Err != nil {
+		// Neither an IPv4 nor an IPv6 socket.
+		return nil, v4Err
+	}
+	return &pktinfoConn{udp}, nil
+}
+
+func (c *pktinfoConn) readFromLocal(b []byte) (int, net.Addr, net.Addr, error) {
+	oob := make([]byte, 128)
+	n, oobn, _, remoteAddr, err := c.ReadMsgUDP(b, oob)
+	if err != nil {
+		return n, nil, nil, err
+	}
+	localAddr := c.LocalAddr()
+	if ip := pktinfoDestination(oob[:oobn]); ip != nil {
+		if addr, ok := localAddr.(*net.UDPAddr); ok {
+			localAddr = &net.UDPAddr{IP: ip, Port: addr.Port}
+		}
+	}
+	return n, remoteAddr, localAddr, nil
+}
+
+func (c *pktinfoConn) writeToLocal(b []byte, remoteAddr, localAddr net.Addr) (int, error) {
+	raddr, ok := remoteAddr.(*net.UDPAddr)
+	laddr, lok := localAddr.(*net.UDPAddr)
+	if !ok || !lok || laddr.IP == nil || laddr.IP.IsUnspecified() {
+		return c.WriteTo(b, remoteAddr)
+	}
+	n, _, err := c.WriteMsgUDP(b, pktinfoSource(laddr.IP), raddr)
+	return n, err
+}
+
+// pktinfoDestination returns the destination address in the IP_PKTINFO or
+// IPV6_PKTINFO control message in oob, or nil.
+func pktinfoDestination(oob []byte) net.IP {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil
+	}
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_PKTINFO && len(msg.Data) >= syscall.SizeofInet4Pktinfo:
+			// struct in_pktinfo { ipi_ifindex; ipi_spec_dst; ipi_addr; }
+			return net.IP(append([]byte(nil), msg.Data[8:12]...))
+		case msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == syscall.IPV6_PKTINFO && len(msg.Data) >= syscall.SizeofInet6Pktinfo:
+			// struct in6_pktinfo { ipi6_addr; ipi6_ifindex; }
+			return net.IP(append([]byte(nil), msg.Data[:16]...))
+		}
+	}
+	return nil
+}
+
+// pktinfoSource returns a control message that sets the source address of a
+// packet to ip.
+func pktinfoSource(ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		data := make([]byte, syscall.SizeofInet4Pktinfo)
+		copy(data[4:8], ip4) // ipi_spec_dst
+		return controlMessage(syscall.IPPROTO_IP, syscall.IP_PKTINFO, data)
+	}
+	data := make([]byte, syscall.SizeofInet6Pktinfo)
+	copy(data, ip.To16()) // ipi6_addr
+	return controlMessage(syscall.IPPROTO_IPV6, syscall.IPV6_PKTINFO, data)
+}
+
+func controlMessage(level, typ int, data []byte) []byte {
+	b := make([]byte, syscall.CmsgSpace(len(data)))
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = int32(level)
+	h.Type = int32(typ)
+	h.SetLen(syscall.CmsgLen(len(data)))
+	copy(b[syscall.CmsgLen(0):], data)
+	return b
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package radius
+
+import (
+	"net"
+)
+
+// packetInfoConn returns conn, as the local address on which each packet is
+// received can only be obtained on Linux.
+func packetInfoConn(conn net.PacketConn) (net.PacketConn, error) {
+	return conn, nil
+}
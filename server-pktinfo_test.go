@@ -0,0 +1,79 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPacketServer_PacketInfo(t *testing.T) {
+	pc, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+
+	secret := []byte(`12345`)
+	requests := make(chan *Request, 1)
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			requests <- r
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		PacketInfo: true,
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	if _, err := Exchange(ctx, New(CodeAccessRequest, secret), addr); err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-requests
+	if r.Transport != "udp" {
+		t.Fatalf("Transport = %q; expecting %q", r.Transport, "udp")
+	}
+	local, ok := r.LocalAddr.(*net.UDPAddr)
+	if !ok || local.Port != port {
+		t.Fatalf("unexpected LocalAddr %v", r.LocalAddr)
+	}
+	if runtime.GOOS == "linux" && !local.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("LocalAddr = %v; expecting 127.0.0.1", r.LocalAddr)
+	}
+}
+
+func TestPacketServer_Control(t *testing.T) {
+	var called int32
+	server := PacketServer{
+		Addr:         "127.0.0.1:0",
+		SecretSource: StaticSecretSource([]byte(`12345`)),
+		Handler:      HandlerFunc(func(w ResponseWriter, r *Request) {}),
+		Control: func(network, address string, c syscall.RawConn) error {
+			atomic.AddInt32(&called, 1)
+			return nil
+		},
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ListenAndServe()
+	}()
+
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&called) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Control was not called")
+		}
+	}
+	server.Shutdown(context.Background())
+	if err := <-done; err != ErrServerShutdown {
+		t.Fatalf("got %v; expecting ErrServerShutdown", err)
+	}
+}
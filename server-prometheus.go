@@ -0,0 +1,272 @@
+package radius
+
+import (
+	"bufio"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the default upper bounds, in seconds, of the
+// buckets of the request duration histogram of PrometheusServerMetrics.
+var DefaultDurationBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// PrometheusServerMetrics is a ServerMetrics that keeps counters, gauges, and
+// histograms of the measurements, and exposes them in the Prometheus text
+// exposition format. It implements http.Handler, so that it can be served at
+// the endpoint scraped by Prometheus, without depending on the Prometheus
+// client library.
+//
+// The following metrics are exposed:
+//
+//	radius_server_packets_received_total{transport, code}
+//	radius_server_packets_sent_total{transport, code}
+//	radius_server_packet_errors_total{transport}
+//	radius_server_auth_failures_total{transport}
+//	radius_server_request_duration_seconds{transport, code} (histogram)
+//	radius_server_queue_depth{transport}
+//	radius_server_active_connections{transport}
+//
+// The zero value is ready to use.
+type PrometheusServerMetrics struct {
+	// Buckets are the upper bounds, in seconds, of the buckets of the request
+	// duration histogram, in increasing order. If nil,
+	// DefaultDurationBuckets is used. It must not be modified once the
+	// metrics are in use.
+	Buckets []float64
+
+	mu           sync.Mutex
+	received     map[prometheusKey]uint64
+	sent         map[prometheusKey]uint64
+	packetErrors map[string]uint64
+	authFailures map[string]uint64
+	durations    map[prometheusKey]*prometheusHistogram
+	queueDepth   map[string]int
+	connections  map[string]int
+}
+
+// prometheusKey identifies a metric by its first label (the transport, or the
+// server address) and a packet code.
+type prometheusKey struct {
+	label string
+	code  Code
+}
+
+type prometheusHistogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+var _ ServerMetrics = (*PrometheusServerMetrics)(nil)
+
+func (m *PrometheusServerMetrics) initLocked() {
+	if m.received == nil {
+		m.received = make(map[prometheusKey]uint64)
+		m.sent = make(map[prometheusKey]uint64)
+		m.packetErrors = make(map[string]uint64)
+		m.authFailures = make(map[string]uint64)
+		m.durations = make(map[prometheusKey]*prometheusHistogram)
+		m.queueDepth = make(map[string]int)
+		m.connections = make(map[string]int)
+	}
+}
+
+func (m *PrometheusServerMetrics) buckets() []float64 {
+	if m.Buckets != nil {
+		return m.Buckets
+	}
+	return DefaultDurationBuckets
+}
+
+// PacketReceived implements ServerMetrics.
+func (m *PrometheusServerMetrics) PacketReceived(transport string, code Code) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.received[prometheusKey{transport, code}]++
+}
+
+// PacketSent implements ServerMetrics.
+func (m *PrometheusServerMetrics) PacketSent(transport string, code Code) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.sent[prometheusKey{transport, code}]++
+}
+
+// PacketError implements ServerMetrics.
+func (m *PrometheusServerMetrics) PacketError(transport string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.packetErrors[transport]++
+}
+
+// AuthFailure implements ServerMetrics.
+func (m *PrometheusServerMetrics) AuthFailure(transport string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.authFailures[transport]++
+}
+
+// RequestHandled implements ServerMetrics.
+func (m *PrometheusServerMetrics) RequestHandled(transport string, request, response Code, elapsed time.Duration) {
+	buckets := m.buckets()
+	seconds := elapsed.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	observe(m.durations, prometheusKey{transport, request}, buckets, seconds)
+}
+
+// QueueDepth implements ServerMetrics.
+func (m *PrometheusServerMetrics) QueueDepth(transport string, depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.queueDepth[transport] = depth
+}
+
+// ConnectionOpened implements ServerMetrics.
+func (m *PrometheusServerMetrics) ConnectionOpened(transport string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.connections[transport]++
+}
+
+// ConnectionClosed implements ServerMetrics.
+func (m *PrometheusServerMetrics) ConnectionClosed(transport string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+	m.connections[transport]--
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *PrometheusServerMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b := bufio.NewWriter(w)
+	defer b.Flush()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.initLocked()
+
+	writeCodeCounters(b, "radius_server_packets_received_total", "Packets received by the server.", "transport", m.received)
+	writeCodeCounters(b, "radius_server_packets_sent_total", "Responses sent by the server.", "transport", m.sent)
+	writeLabeledValues(b, "radius_server_packet_errors_total", "Received packets discarded because they could not be parsed.", "counter", "transport", uintValues(m.packetErrors))
+	writeLabeledValues(b, "radius_server_auth_failures_total", "Received packets discarded because of an invalid authenticator.", "counter", "transport", uintValues(m.authFailures))
+	writeHistograms(b, "radius_server_request_duration_seconds", "Time taken to handle requests.", "transport", m.buckets(), m.durations)
+	writeLabeledValues(b, "radius_server_queue_depth", "Requests waiting for a worker.", "gauge", "transport", intValues(m.queueDepth))
+	writeLabeledValues(b, "radius_server_active_connections", "Open connections and sessions.", "gauge", "transport", intValues(m.connections))
+}
+
+// observe adds seconds to the histogram of key in histograms.
+func observe(histograms map[prometheusKey]*prometheusHistogram, key prometheusKey, buckets []float64, seconds float64) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &prometheusHistogram{counts: make([]uint64, len(buckets))}
+		histograms[key] = h
+	}
+	if i := sort.SearchFloat64s(buckets, seconds); i < len(buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func writeSample(b *bufio.Writer, name, labels, value string) {
+	b.WriteString(name)
+	b.WriteString("{")
+	b.WriteString(labels)
+	b.WriteString("} ")
+	b.WriteString(value)
+	b.WriteString("\n")
+}
+
+func writeCodeCounters(b *bufio.Writer, name, help, label string, values map[prometheusKey]uint64) {
+	b.WriteString("# HELP " + name + " " + help + "\n")
+	b.WriteString("# TYPE " + name + " counter\n")
+	keys := make([]prometheusKey, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sortPrometheusKeys(keys)
+	for _, key := range keys {
+		writeSample(b, name, codeLabels(label, key), strconv.FormatUint(values[key], 10))
+	}
+}
+
+func writeHistograms(b *bufio.Writer, name, help, label string, buckets []float64, values map[prometheusKey]*prometheusHistogram) {
+	b.WriteString("# HELP " + name + " " + help + "\n")
+	b.WriteString("# TYPE " + name + " histogram\n")
+	for _, key := range sortedCodeKeys(values) {
+		h := values[key]
+		labels := codeLabels(label, key)
+		var cumulative uint64
+		for i, le := range buckets {
+			cumulative += h.counts[i]
+			writeSample(b, name+"_bucket", labels+`,le="`+strconv.FormatFloat(le, 'g', -1, 64)+`"`, strconv.FormatUint(cumulative, 10))
+		}
+		writeSample(b, name+"_bucket", labels+`,le="+Inf"`, strconv.FormatUint(h.count, 10))
+		writeSample(b, name+"_sum", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		writeSample(b, name+"_count", labels, strconv.FormatUint(h.count, 10))
+	}
+}
+
+func writeLabeledValues(b *bufio.Writer, name, help, kind, label string, values map[string]string) {
+	b.WriteString("# HELP " + name + " " + help + "\n")
+	b.WriteString("# TYPE " + name + " " + kind + "\n")
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeSample(b, name, label+`=`+strconv.Quote(key), values[key])
+	}
+}
+
+func codeLabels(label string, key prometheusKey) string {
+	return label + `=` + strconv.Quote(key.label) + `,code=` + strconv.Quote(key.code.String())
+}
+
+func uintValues(values map[string]uint64) map[string]string {
+	formatted := make(map[string]string, len(values))
+	for key, value := range values {
+		formatted[key] = strconv.FormatUint(value, 10)
+	}
+	return formatted
+}
+
+func intValues(values map[string]int) map[string]string {
+	formatted := make(map[string]string, len(values))
+	for key, value := range values {
+		formatted[key] = strconv.Itoa(value)
+	}
+	return formatted
+}
+
+func sortedCodeKeys(values map[prometheusKey]*prometheusHistogram) []prometheusKey {
+	keys := make([]prometheusKey, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sortPrometheusKeys(keys)
+	return keys
+}
+
+func sortPrometheusKeys(keys []prometheusKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].label != keys[j].label {
+			return keys[i].label < keys[j].label
+		}
+		return keys[i].code < keys[j].code
+	})
+}
@@ -0,0 +1,72 @@
+package radius
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusServerMetrics(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := &PrometheusServerMetrics{}
+	server := PacketServer{
+		SecretSource: StaticSecretSource([]byte(`12345`)),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		Metrics:  metrics,
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := Exchange(ctx, New(CodeAccessRequest, []byte(`12345`)), pc.LocalAddr().String()); err != nil {
+		t.Fatal(err)
+	}
+	wire, err := New(CodeAccountingRequest, []byte(`wrong`)).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(wire)
+
+	expected := []string{
+		`radius_server_packets_received_total{transport="udp",code="Access-Request"} 1`,
+		`radius_server_packets_received_total{transport="udp",code="Accounting-Request"} 1`,
+		`radius_server_packets_sent_total{transport="udp",code="Access-Accept"} 1`,
+		`radius_server_auth_failures_total{transport="udp"} 1`,
+		`radius_server_request_duration_seconds_count{transport="udp",code="Access-Request"} 1`,
+		`radius_server_request_duration_seconds_bucket{transport="udp",code="Access-Request",le="+Inf"} 1`,
+	}
+	var body string
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		rec := httptest.NewRecorder()
+		metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		body = rec.Body.String()
+		missing := false
+		for _, line := range expected {
+			if !strings.Contains(body, line+"\n") {
+				missing = true
+			}
+		}
+		if !missing {
+			return
+		}
+	}
+	t.Fatalf("missing expected metrics in:\n%s", body)
+}
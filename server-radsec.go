@@ -0,0 +1,188 @@
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RadSecServer listens for RADIUS requests over TLS (RadSec, RFC 6614).
+//
+// Clients must present a certificate that is verified against the
+// TLSConfig's ClientCAs. Multiple requests can be pipelined on each
+// connection; each is handled in its own goroutine.
+type RadSecServer struct {
+	// The address on which the server listens. Defaults to :2083.
+	Addr string
+
+	// Handler which is called to process the request.
+	Handler Handler
+
+	// Middleware is applied, outermost first, to Handler.
+	Middleware []Middleware
+
+	// Recovery, if non-nil, recovers from panics in Handler and Middleware,
+	// as in PacketServer.
+	Recovery *RecoveryPolicy
+
+	// TLSConfig configures the TLS connections, and must contain the server's
+	// certificate. If its ClientAuth is tls.NoClientCert, it is treated as
+	// tls.RequireAndVerifyClientCert.
+	TLSConfig *tls.Config
+
+	// Secret is the shared secret used for all packets. If nil, RadSecSecret
+	// is used, as required by RFC 6614.
+	Secret []byte
+
+	// ClientSecret, if non-nil, is called once the TLS handshake of each
+	// connection has completed, allowing the client's certificate identity to
+	// be mapped to a client entry. It returns the secret to use for the
+	// connection, or nil to use Secret. If it returns an error, the
+	// connection is closed.
+	ClientSecret func(state *tls.ConnectionState) ([]byte, error)
+
+	// MessageAuthenticator controls how the Message-Authenticator of incoming
+	// requests is verified. Connections on which a request fails verification
+	// are closed. A Message-Authenticator attribute is always added to
+	// responses, as RadSecClient requires one; MessageAuthenticatorIgnore is
+	// treated as MessageAuthenticatorAdd.
+	MessageAuthenticator MessageAuthenticatorPolicy
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of
+	// requests accepted and responses sent by the server, up to
+	// MaxExtendedPacketLength. If zero, MaxPacketLength is used.
+	MaxPacketSize int
+
+	// HandshakeTimeout is the time allowed for the TLS handshake of each
+	// connection. Defaults to 10 seconds.
+	HandshakeTimeout time.Duration
+
+	// Metrics, if non-nil, receives measurements of the requests processed
+	// by the server.
+	Metrics ServerMetrics
+
+	// ErrorLog specifies an optional logger for errors around connection
+	// accepting, and packet processing and validation. If nil, logging is
+	// done via the log package's standard logger.
+	ErrorLog *log.Logger
+
+	// Logger, if non-nil, receives structured events for received packets,
+	// parse errors, secret lookup failures, and shutdown, as in
+	// PacketServer.
+	Logger Logger
+
+	// Control, if non-nil, is called by ListenAndServe after creating the
+	// listening socket and before binding it, as in net.ListenConfig, so
+	// that socket options can be set.
+	Control func(network, address string, c syscall.RawConn) error
+
+	// Tap, if non-nil, receives a copy of every packet read from or written
+	// to the server's connections.
+	Tap Tap
+
+	stream streamServer
+}
+
+func (s *RadSecServer) logf(format string, args ...interface{}) {
+	errorLogf(s.Logger, s.ErrorLog, format, args...)
+}
+
+// Serve accepts incoming TCP connections on l, and performs the TLS handshake
+// on each of them. l must not itself be a TLS listener.
+func (s *RadSecServer) Serve(l net.Listener) error {
+	if s.Handler == nil {
+		return errors.New("radius: nil Handler")
+	}
+	if s.TLSConfig == nil {
+		return errors.New("radius: nil TLSConfig")
+	}
+	config := s.TLSConfig
+	if config.ClientAuth == tls.NoClientCert {
+		config = config.Clone()
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	handler := recoverHandler(ChainMiddleware(s.Handler, s.Middleware...), s.Recovery, s.logf)
+	policy := s.MessageAuthenticator
+	if policy == MessageAuthenticatorIgnore {
+		policy = MessageAuthenticatorAdd
+	}
+
+	return s.stream.serve(l, 0, func(ctx context.Context, conn net.Conn) {
+		tlsConn := tls.Server(conn, config)
+		timeout := s.HandshakeTimeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			s.logf("radius: TLS handshake with %v failed: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+		tlsConn.SetDeadline(time.Time{})
+		state := tlsConn.ConnectionState()
+
+		secret := s.Secret
+		if s.ClientSecret != nil {
+			clientSecret, err := s.ClientSecret(&state)
+			if err != nil {
+				s.logf("radius: rejecting connection from %v: %v", conn.RemoteAddr(), err)
+				tlsConn.Close()
+				return
+			}
+			if clientSecret != nil {
+				secret = clientSecret
+			}
+		}
+		if secret == nil {
+			secret = RadSecSecret
+		}
+
+		serveStreamConn(ctx, tlsConn, streamConfig{
+			handler:              handler,
+			secret:               secret,
+			messageAuthenticator: policy,
+			maxPacketSize:        s.MaxPacketSize,
+			tls:                  &state,
+			metrics:              serverMetrics{metrics: s.Metrics, transport: "tls"},
+			logger:               s.Logger,
+			tap:                  s.Tap,
+			logf:                 s.logf,
+		})
+	})
+}
+
+// ListenAndServe starts a RadSec server on the address given in s.
+func (s *RadSecServer) ListenAndServe() error {
+	if s.Handler == nil {
+		return errors.New("radius: nil Handler")
+	}
+	addr := s.Addr
+	if addr == "" {
+		addr = ":" + RadSecPort
+	}
+	config := net.ListenConfig{Control: s.Control}
+	l, err := config.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.Serve(l)
+}
+
+// Shutdown gracefully stops the server. It closes the listeners, stops
+// reading requests from the open connections, and cancels the context of the
+// running handlers. Each connection is closed once the responses to the
+// requests already received on it have been written.
+//
+// Shutdown returns nil after all connections have been closed. If ctx is
+// canceled first, the remaining connections are closed, and ctx.Err() is
+// returned.
+func (s *RadSecServer) Shutdown(ctx context.Context) error {
+	logEvent(s.Logger, ctx, LogLevelInfo, "radius: server shutting down")
+	return s.stream.closeShutdown(ctx)
+}
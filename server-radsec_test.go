@@ -0,0 +1,104 @@
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRadSecServer(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfigs()
+	serverConfig.ClientAuth = tls.NoClientCert
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var peerCertificates int32
+	server := &RadSecServer{
+		TLSConfig: serverConfig,
+		ClientSecret: func(state *tls.ConnectionState) ([]byte, error) {
+			atomic.StoreInt32(&peerCertificates, int32(len(state.PeerCertificates)))
+			return []byte("client"), nil
+		},
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			if r.TLS == nil {
+				t.Error("expecting request TLS state")
+			}
+			resp := r.Response(CodeAccessAccept)
+			resp.Add(typeUserName, r.Get(typeUserName))
+			w.Write(resp)
+		}),
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(l)
+	}()
+
+	client := &RadSecClient{
+		TLSConfig: clientConfig,
+		Secret:    []byte("client"),
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	packet := New(CodeAccessRequest, nil)
+	packet.Add(typeUserName, Attribute("tim"))
+	resp, err := client.Exchange(ctx, packet, l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeAccessAccept || string(resp.Get(typeUserName)) != "tim" {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	if n := atomic.LoadInt32(&peerCertificates); n != 1 {
+		t.Fatalf("got %d peer certificates; expecting 1", n)
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != ErrServerShutdown {
+		t.Fatalf("got Serve error %v; expecting ErrServerShutdown", err)
+	}
+}
+
+func TestRadSecServer_wrongSecret(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfigs()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &RadSecServer{
+		TLSConfig: serverConfig,
+		ClientSecret: func(state *tls.ConnectionState) ([]byte, error) {
+			return nil, errors.New("unknown client")
+		},
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			t.Error("handler called for rejected connection")
+		}),
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+	go server.Serve(l)
+	defer server.Shutdown(context.Background())
+
+	client := &RadSecClient{
+		TLSConfig: clientConfig,
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Exchange(ctx, New(CodeAccessRequest, nil), l.Addr().String()); err == nil {
+		t.Fatal("expecting error")
+	}
+}
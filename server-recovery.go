@@ -0,0 +1,152 @@
+package radius
+
+import (
+	"log"
+	"runtime/debug"
+)
+
+// typeErrorCause is the RFC 5176 Error-Cause attribute type.
+const typeErrorCause Type = 101
+
+// originalPacketCode is the RFC 7930 Original-Packet-Code attribute.
+var originalPacketCode = ExtendedType{Type: 241, Extended: 4}
+
+// errorCauseResourcesUnavailable is the RFC 5176 Resources Unavailable
+// Error-Cause value.
+const errorCauseResourcesUnavailable = 506
+
+// PanicResponse determines how a request is answered when its handler
+// panics.
+type PanicResponse int
+
+// PanicResponse values.
+const (
+	// PanicDrop sends no response, so that the client retransmits the
+	// request or fails over to another server.
+	PanicDrop PanicResponse = iota
+
+	// PanicReject answers Access-Requests with an Access-Reject. Other
+	// requests are dropped.
+	PanicReject
+
+	// PanicProtocolError answers the request with a Protocol-Error (RFC
+	// 7930), carrying an Error-Cause and the Original-Packet-Code.
+	PanicProtocolError
+)
+
+// PanicInfo describes a panic recovered from a handler.
+type PanicInfo struct {
+	// Request is the request whose handler panicked.
+	Request *Request
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+// RecoveryPolicy configures how panics in handlers are recovered from.
+//
+// No response is sent by the policy if the handler had already written one
+// before it panicked.
+type RecoveryPolicy struct {
+	// Response determines how the request is answered.
+	Response PanicResponse
+
+	// ErrorCause is the Error-Cause of Protocol-Error responses. If zero,
+	// 506 (Resources Unavailable) is used.
+	ErrorCause uint32
+
+	// OnPanic, if non-nil, is called with each recovered panic, e.g. to raise
+	// an alert. Otherwise, the panic is logged, with its stack trace.
+	OnPanic func(info *PanicInfo)
+
+	// ErrorLog specifies an optional logger for recovered panics, and for
+	// errors writing the responses. If nil, servers log to their own
+	// ErrorLog or Logger, and RecoveryMiddleware to the log package's
+	// standard logger.
+	ErrorLog *log.Logger
+}
+
+// RecoveryMiddleware returns a Middleware that recovers from panics in the
+// handler, and answers the request as configured by policy.
+//
+// Servers also accept a RecoveryPolicy, in their Recovery field, which
+// additionally covers panics in their Middleware.
+func RecoveryMiddleware(policy RecoveryPolicy) Middleware {
+	return policy.middleware(func(format string, args ...interface{}) {
+		serverLogf(policy.ErrorLog, format, args...)
+	})
+}
+
+// recoverHandler returns handler, wrapped to recover from panics according to
+// policy, if it is non-nil. logf is used if policy has no ErrorLog.
+func recoverHandler(handler Handler, policy *RecoveryPolicy, logf func(format string, args ...interface{})) Handler {
+	if policy == nil {
+		return handler
+	}
+	if policy.ErrorLog != nil {
+		logf = func(format string, args ...interface{}) {
+			serverLogf(policy.ErrorLog, format, args...)
+		}
+	}
+	return policy.middleware(logf)(handler)
+}
+
+func (p RecoveryPolicy) middleware(logf func(format string, args ...interface{})) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			recorder := &recordingResponseWriter{ResponseWriter: w}
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				info := &PanicInfo{
+					Request: r,
+					Value:   value,
+					Stack:   debug.Stack(),
+				}
+				if p.OnPanic != nil {
+					p.OnPanic(info)
+				} else {
+					logf("radius: panic serving %v (id %d) from %v: %v\n%s", r.Code, r.Identifier, r.RemoteAddr, value, info.Stack)
+				}
+				if recorder.response != nil {
+					return
+				}
+				if response := p.response(r); response != nil {
+					if err := w.Write(response); err != nil {
+						logf("radius: unable to answer request after panic: %v", err)
+					}
+				}
+			}()
+			next.ServeRADIUS(recorder, r)
+		})
+	}
+}
+
+// response returns the response sent for r after its handler panicked, or
+// nil.
+func (p RecoveryPolicy) response(r *Request) *Packet {
+	var response *Packet
+	switch p.Response {
+	case PanicReject:
+		if r.Code != CodeAccessRequest {
+			return nil
+		}
+		response = r.Response(CodeAccessReject)
+	case PanicProtocolError:
+		cause := p.ErrorCause
+		if cause == 0 {
+			cause = errorCauseResourcesUnavailable
+		}
+		response = r.Response(CodeProtocolError)
+		response.Add(typeErrorCause, NewInteger(cause))
+		response.AddExtended(originalPacketCode, NewInteger(uint32(r.Code)))
+		response.MessageAuthenticatorPolicy = MessageAuthenticatorAdd
+	default:
+		return nil
+	}
+	CopyProxyState(response, r.Packet)
+	return response
+}
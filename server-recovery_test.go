@@ -0,0 +1,113 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	panicking := HandlerFunc(func(w ResponseWriter, r *Request) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		name     string
+		policy   RecoveryPolicy
+		code     Code
+		expected Code // 0 if no response is expected
+	}{
+		{"drop", RecoveryPolicy{Response: PanicDrop}, CodeAccessRequest, 0},
+		{"reject", RecoveryPolicy{Response: PanicReject}, CodeAccessRequest, CodeAccessReject},
+		{"reject accounting", RecoveryPolicy{Response: PanicReject}, CodeAccountingRequest, 0},
+		{"protocol error", RecoveryPolicy{Response: PanicProtocolError}, CodeAccountingRequest, CodeProtocolError},
+	}
+	for _, tt := range tests {
+		var info *PanicInfo
+		tt.policy.OnPanic = func(i *PanicInfo) {
+			info = i
+		}
+		request := &Request{Packet: New(tt.code, []byte(`12345`))}
+		request.Add(typeProxyState, Attribute("state"))
+		w := &testResponseWriter{}
+		RecoveryMiddleware(tt.policy)(panicking).ServeRADIUS(w, request)
+
+		if info == nil || info.Value != "boom" || len(info.Stack) == 0 || info.Request != request {
+			t.Fatalf("%s: unexpected panic info %+v", tt.name, info)
+		}
+		if tt.expected == 0 {
+			if len(w.responses) != 0 {
+				t.Fatalf("%s: got %d responses; expecting none", tt.name, len(w.responses))
+			}
+			continue
+		}
+		if len(w.responses) != 1 || w.responses[0].Code != tt.expected {
+			t.Fatalf("%s: got %v; expecting a %v", tt.name, w.responses, tt.expected)
+		}
+		if state := w.responses[0].Get(typeProxyState); string(state) != "state" {
+			t.Fatalf("%s: Proxy-State = %q; expecting %q", tt.name, state, "state")
+		}
+	}
+}
+
+func TestRecoveryMiddleware_written(t *testing.T) {
+	handler := HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Write(r.Response(CodeAccessAccept))
+		panic("boom")
+	})
+	w := &testResponseWriter{}
+	policy := RecoveryPolicy{Response: PanicReject, OnPanic: func(*PanicInfo) {}}
+	RecoveryMiddleware(policy)(handler).ServeRADIUS(w, &Request{Packet: New(CodeAccessRequest, []byte(`12345`))})
+	if len(w.responses) != 1 || w.responses[0].Code != CodeAccessAccept {
+		t.Fatalf("got %v; expecting only the Access-Accept", w.responses)
+	}
+}
+
+func TestPacketServer_Recovery(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	panics := make(chan *PanicInfo, 1)
+	server := PacketServer{
+		SecretSource: StaticSecretSource([]byte(`12345`)),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			panic("boom")
+		}),
+		Recovery: &RecoveryPolicy{
+			Response:   PanicProtocolError,
+			ErrorCause: 506,
+			OnPanic: func(info *PanicInfo) {
+				select {
+				case panics <- info:
+				default:
+				}
+			},
+		},
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = Exchange(ctx, New(CodeAccessRequest, []byte(`12345`)), pc.LocalAddr().String())
+	protocolErr, ok := err.(*ProtocolError)
+	if !ok {
+		t.Fatalf("got %v; expecting *ProtocolError", err)
+	}
+	if protocolErr.Cause != 506 {
+		t.Fatalf("Error-Cause = %d; expecting 506", protocolErr.Cause)
+	}
+	response := protocolErr.Response
+	if response.Code != CodeProtocolError {
+		t.Fatalf("got %v; expecting %v", response.Code, CodeProtocolError)
+	}
+	if code, err := Integer(response.GetExtended(originalPacketCode)); err != nil || Code(code) != CodeAccessRequest {
+		t.Fatalf("Original-Packet-Code = %d (%v); expecting %d", code, err, CodeAccessRequest)
+	}
+	if info := <-panics; info.Value != "boom" {
+		t.Fatalf("unexpected panic value %v", info.Value)
+	}
+}
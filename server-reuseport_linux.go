@@ -0,0 +1,46 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"syscall"
+)
+
+// reusePortSupported is whether sockets can be opened with SO_REUSEPORT.
+const reusePortSupported = true
+
+// soReusePort is the value of SO_REUSEPORT, which the syscall package does
+// not define on every architecture.
+func soReusePort() int {
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le":
+		return 0x200
+	}
+	return 0xf
+}
+
+// listenReusePort opens a packet socket on address with SO_REUSEPORT set, so
+// that several sockets can be bound to the same address. control, if
+// non-nil, is called after the option is set.
+func listenReusePort(network, address string, control func(network, address string, c syscall.RawConn) error) (net.PacketConn, error) {
+	config := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort(), 1)
+			})
+			if err != nil {
+				return err
+			}
+			if sockErr != nil {
+				return sockErr
+			}
+			if control != nil {
+				return control(network, address, c)
+			}
+			return nil
+		},
+	}
+	return config.ListenPacket(context.Background(), network, address)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package radius
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// reusePortSupported is whether sockets can be opened with SO_REUSEPORT.
+const reusePortSupported = false
+
+// listenReusePort returns an error, as SO_REUSEPORT sockets are only
+// supported on Linux.
+func listenReusePort(network, address string, control func(network, address string, c syscall.RawConn) error) (net.PacketConn, error) {
+	return nil, errors.New("radius: SO_REUSEPORT is not supported on this platform")
+}
@@ -0,0 +1,52 @@
+package radius
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestPacketServer_ReusePort(t *testing.T) {
+	conns, err := listenPackets("udp", "127.0.0.1:0", 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := 1
+	if runtime.GOOS == "linux" {
+		expected = 4
+	}
+	if len(conns) != expected {
+		t.Fatalf("got %d sockets; expecting %d", len(conns), expected)
+	}
+	addr := conns[0].LocalAddr().String()
+	for _, pc := range conns[1:] {
+		if pc.LocalAddr().String() != addr {
+			t.Fatalf("socket bound to %v; expecting %v", pc.LocalAddr(), addr)
+		}
+	}
+
+	secret := []byte(`12345`)
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+	}
+	for _, pc := range conns {
+		go server.Serve(pc)
+	}
+	defer server.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 16; i++ {
+		response, err := Exchange(ctx, New(CodeAccessRequest, secret), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Code != CodeAccessAccept {
+			t.Fatalf("got %v; expecting %v", response.Code, CodeAccessAccept)
+		}
+	}
+}
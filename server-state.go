@@ -0,0 +1,175 @@
+package radius
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+)
+
+// StateStore stores the data of multi-round conversations, such as those
+// made of Access-Challenges, keyed by an opaque key derived from their State
+// attribute. It must be safe for concurrent use.
+type StateStore interface {
+	// Put stores data with key, for ttl.
+	Put(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// Take returns the data stored with key, and removes it from the store.
+	// false is returned if there is no data stored with key, or if it has
+	// expired.
+	Take(ctx context.Context, key string) ([]byte, bool, error)
+}
+
+// NewMemoryStateStore returns a StateStore that keeps the data in memory.
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{
+		entries: make(map[string]memoryStateEntry),
+	}
+}
+
+type memoryStateStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryStateEntry
+	lastPrune time.Time
+}
+
+type memoryStateEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func (s *memoryStateStore) Put(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastPrune) >= ttl {
+		for key, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, key)
+			}
+		}
+		s.lastPrune = now
+	}
+	s.entries[key] = memoryStateEntry{
+		data:    append([]byte(nil), data...),
+		expires: now.Add(ttl),
+	}
+	return nil
+}
+
+func (s *memoryStateStore) Take(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	delete(s.entries, key)
+	if time.Now().After(entry.expires) {
+		return nil, false, nil
+	}
+	return entry.data, true, nil
+}
+
+// StateManager associates data with the conversations of a server that span
+// multiple requests, using the State attribute (RFC 2865 section 5.24).
+//
+// Challenge mints a new, random State for the conversation and stores the
+// data of its next round; Resume returns that data when the client sends the
+// State back in its next Access-Request. Each State can be resumed only once,
+// and only by requests from the same IP address as the request that was
+// challenged. Retransmissions of the request that resumed a State, which have
+// the same Identifier and Request Authenticator, resume it again until the
+// TTL expires.
+type StateManager struct {
+	// Store holds the data of the conversations. If nil, the data is kept in
+	// memory.
+	Store StateStore
+
+	// TTL is how long a client has to answer a challenge. Defaults to 1
+	// minute.
+	TTL time.Duration
+
+	once  sync.Once
+	store StateStore
+}
+
+// ErrStateNotFound is returned by StateManager.Resume when the State of the
+// request is unknown, has expired, or was already resumed by another request.
+var ErrStateNotFound = errors.New("radius: State not found")
+
+func (m *StateManager) getStore() StateStore {
+	m.once.Do(func() {
+		m.store = m.Store
+		if m.store == nil {
+			m.store = NewMemoryStateStore()
+		}
+	})
+	return m.store
+}
+
+func (m *StateManager) ttl() time.Duration {
+	if m.TTL <= 0 {
+		return time.Minute
+	}
+	return m.TTL
+}
+
+func stateKey(r *Request, state []byte) string {
+	var source string
+	if r.RemoteAddr != nil {
+		source = addrKey(r.RemoteAddr)
+	}
+	return source + "\x00" + string(state)
+}
+
+// resumedKey returns the key of the data of a State resumed by r, which is
+// looked up by the retransmissions of r.
+func resumedKey(r *Request, state []byte) string {
+	return stateKey(r, state) + "\x00" + string(r.Identifier) + string(r.Authenticator[:])
+}
+
+// Challenge returns an Access-Challenge response to r with a new State
+// attribute, and stores data so that it is returned by Resume for the next
+// request of the conversation.
+func (m *StateManager) Challenge(r *Request, data []byte) (*Packet, error) {
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		return nil, err
+	}
+	if err := m.getStore().Put(r.Context(), stateKey(r, state), data, m.ttl()); err != nil {
+		return nil, err
+	}
+	response := r.Response(CodeAccessChallenge)
+	response.Set(typeState, Attribute(state))
+	return response, nil
+}
+
+// Resume returns the data stored by Challenge for the conversation of r,
+// identified by its State attribute. ErrNoAttribute is returned if r has no
+// State, and ErrStateNotFound if the State is not known.
+func (m *StateManager) Resume(r *Request) ([]byte, error) {
+	state, ok := r.Lookup(typeState)
+	if !ok {
+		return nil, ErrNoAttribute
+	}
+	store := m.getStore()
+	data, ok, err := store.Take(r.Context(), stateKey(r, state))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// r may be a retransmission of the request that resumed the State.
+		data, ok, err = store.Take(r.Context(), resumedKey(r, state))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrStateNotFound
+		}
+	}
+	if err := store.Put(r.Context(), resumedKey(r, state), data, m.ttl()); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,73 @@
+package radius
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStateManager(t *testing.T) {
+	var m StateManager
+	newRequest := func(ip net.IP, state []byte) *Request {
+		packet := New(CodeAccessRequest, []byte(`12345`))
+		if state != nil {
+			packet.Add(typeState, Attribute(state))
+		}
+		return &Request{
+			RemoteAddr: &net.UDPAddr{IP: ip, Port: 1812},
+			Packet:     packet,
+		}
+	}
+	nas := net.IPv4(10, 0, 0, 1)
+
+	if _, err := m.Resume(newRequest(nas, nil)); err != ErrNoAttribute {
+		t.Fatalf("got %v; expecting ErrNoAttribute", err)
+	}
+
+	challenge, err := m.Challenge(newRequest(nas, nil), []byte("round 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge.Code != CodeAccessChallenge {
+		t.Fatalf("got %v; expecting Access-Challenge", challenge.Code)
+	}
+	state := challenge.Get(typeState)
+	if len(state) != 16 {
+		t.Fatalf("got State %x; expecting 16 random bytes", state)
+	}
+
+	if _, err := m.Resume(newRequest(net.IPv4(10, 0, 0, 2), state)); err != ErrStateNotFound {
+		t.Fatalf("got %v resuming from another client; expecting ErrStateNotFound", err)
+	}
+	resumed := newRequest(nas, state)
+	data, err := m.Resume(resumed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "round 1" {
+		t.Fatalf("got data %q; expecting round 1", data)
+	}
+	// retransmissions of the request, whose response was lost, resume the
+	// State again
+	for i := 0; i < 2; i++ {
+		retransmission := newRequest(nas, state)
+		retransmission.Identifier = resumed.Identifier
+		retransmission.Authenticator = resumed.Authenticator
+		if data, err := m.Resume(retransmission); err != nil || string(data) != "round 1" {
+			t.Fatalf("got %q, %v resuming retransmission; expecting round 1", data, err)
+		}
+	}
+	if _, err := m.Resume(newRequest(nas, state)); err != ErrStateNotFound {
+		t.Fatalf("got %v resuming twice; expecting ErrStateNotFound", err)
+	}
+
+	m.TTL = time.Nanosecond
+	challenge, err = m.Challenge(newRequest(nas, nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := m.Resume(newRequest(nas, challenge.Get(typeState))); err != ErrStateNotFound {
+		t.Fatalf("got %v resuming expired State; expecting ErrStateNotFound", err)
+	}
+}
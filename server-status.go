@@ -0,0 +1,33 @@
+package radius
+
+// statusServerResponse returns the response to the Status-Server request r.
+func statusServerResponse(r *Request, reply Code, attributes func(r *Request) Attributes) *Packet {
+	response := r.Response(reply)
+	if attributes != nil {
+		response.Attributes = append(response.Attributes, attributes(r)...)
+	}
+	return response
+}
+
+// StatusServerMiddleware returns a Middleware that answers Status-Server
+// requests (RFC 5997) with a response of code reply (either CodeAccessAccept
+// or CodeAccountingResponse), without calling the next Handler. attributes,
+// if non-nil, returns the attributes added to the responses.
+//
+// As required by RFC 5997 section 3, Status-Server requests without a
+// Message-Authenticator are discarded. It is the equivalent of
+// PacketServer.StatusServerReply for the other servers.
+func StatusServerMiddleware(reply Code, attributes func(r *Request) Attributes) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			if r.Code != CodeStatusServer {
+				next.ServeRADIUS(w, r)
+				return
+			}
+			if r.Get(typeMessageAuthenticator) == nil {
+				return
+			}
+			w.Write(statusServerResponse(r, reply, attributes))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package radius
+
+import (
+	"testing"
+)
+
+func TestStatusServerMiddleware(t *testing.T) {
+	var handled int
+	handler := StatusServerMiddleware(CodeAccountingResponse, nil)(HandlerFunc(func(w ResponseWriter, r *Request) {
+		handled++
+	}))
+
+	packet := New(CodeStatusServer, []byte(`12345`))
+	w := &testResponseWriter{}
+	handler.ServeRADIUS(w, &Request{Packet: packet})
+	if len(w.responses) != 0 {
+		t.Fatal("expecting Status-Server without Message-Authenticator to be discarded")
+	}
+
+	packet.Add(typeMessageAuthenticator, make(Attribute, messageAuthenticatorLen))
+	handler.ServeRADIUS(w, &Request{Packet: packet})
+	if len(w.responses) != 1 || w.responses[0].Code != CodeAccountingResponse {
+		t.Fatalf("got responses %v; expecting Accounting-Response", w.responses)
+	}
+
+	handler.ServeRADIUS(w, &Request{Packet: New(CodeAccessRequest, []byte(`12345`))})
+	if handled != 1 {
+		t.Fatalf("got %d handled requests; expecting 1", handled)
+	}
+}
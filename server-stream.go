@@ -0,0 +1,308 @@
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// streamServer is the shared implementation of the servers that receive
+// requests over stream connections, on which packets are framed by their
+// Length field (RFC 6613 section 2.6).
+type streamServer struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	ctxDone   context.CancelFunc
+	shutdown  bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	active    sync.WaitGroup // connections being served
+}
+
+// streamConfig configures how a stream connection is served.
+type streamConfig struct {
+	handler              Handler
+	secret               []byte
+	messageAuthenticator MessageAuthenticatorPolicy
+	maxPacketSize        int
+	idleTimeout          time.Duration
+	datagram             bool // each Read returns a single packet
+	tls                  *tls.ConnectionState
+	metrics              serverMetrics
+	logger               Logger
+	tap                  Tap
+	logf                 func(format string, args ...interface{})
+}
+
+func (s *streamServer) initLocked() {
+	if s.ctx == nil {
+		s.ctx, s.ctxDone = context.WithCancel(context.Background())
+		s.listeners = make(map[net.Listener]struct{})
+		s.conns = make(map[net.Conn]struct{})
+	}
+}
+
+// serve accepts connections on l, and calls serveConn for each of them in a
+// new goroutine, until l fails or shutdown is called. If maxConns is
+// positive, connections accepted while maxConns connections are open are
+// closed immediately.
+func (s *streamServer) serve(l net.Listener, maxConns int, serveConn func(ctx context.Context, conn net.Conn)) error {
+	s.mu.Lock()
+	s.initLocked()
+	if s.shutdown {
+		s.mu.Unlock()
+		return ErrServerShutdown
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			shutdown := s.shutdown
+			s.mu.Unlock()
+			if shutdown {
+				return ErrServerShutdown
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.shutdown {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerShutdown
+		}
+		if maxConns > 0 && len(s.conns) >= maxConns {
+			s.mu.Unlock()
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.active.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.active.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+			}()
+			serveConn(s.ctx, conn)
+		}()
+	}
+}
+
+// closeShutdown stops accepting connections and reading requests, and cancels
+// the context of the running handlers. Connections are closed once the
+// responses to the requests already received have been written.
+func (s *streamServer) closeShutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.initLocked()
+	if !s.shutdown {
+		s.shutdown = true
+		for l := range s.listeners {
+			l.Close()
+		}
+		// The context is canceled before the deadlines are set, so that a
+		// connection that resets its idle deadline afterwards sees that it
+		// is shutting down once it checks its context.
+		s.ctxDone()
+		for conn := range s.conns {
+			conn.SetReadDeadline(time.Now())
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// streamResponseWriter writes responses to a stream connection.
+type streamResponseWriter struct {
+	conn    net.Conn
+	writeMu *sync.Mutex
+	metrics serverMetrics
+	tap     Tap
+
+	// code of the response that was written
+	written Code
+}
+
+func (w *streamResponseWriter) Write(packet *Packet) error {
+	encoded, err := packet.Encode()
+	if err != nil {
+		return err
+	}
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if _, err := w.conn.Write(encoded); err != nil {
+		return err
+	}
+	w.written = packet.Code
+	w.metrics.sent(packet.Code)
+	tapWire(w.tap, TapOutbound, w.conn.RemoteAddr(), encoded)
+	return nil
+}
+
+// serveStreamConn reads requests from conn, and calls the handler for each in
+// a new goroutine. Any invalid packet closes the connection, as required by
+// RFC 6613 section 2.6.4, unless the connection is a datagram one, on which
+// invalid packets are discarded. The connection is closed once the handlers
+// of the requests received on it have returned.
+func serveStreamConn(ctx context.Context, conn net.Conn, config streamConfig) {
+	var handlers sync.WaitGroup
+	config.metrics.opened()
+	defer func() {
+		handlers.Wait()
+		conn.Close()
+		config.metrics.closed()
+	}()
+
+	var (
+		writeMu    sync.Mutex
+		inProgress sync.Map // Identifier -> struct{}
+	)
+	buff := make([]byte, maxPacketSize(config.maxPacketSize))
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if config.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(config.idleTimeout))
+			// The idle deadline may have replaced the immediate one set by
+			// closeShutdown.
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		var n int
+		var err error
+		if config.datagram {
+			n, err = conn.Read(buff)
+		} else {
+			n, err = readPacket(conn, buff)
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+					config.logf("radius: closing connection from %v: %v", conn.RemoteAddr(), err)
+				}
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		wire := append([]byte(nil), buff[:n]...)
+		tapWire(config.tap, TapInbound, conn.RemoteAddr(), wire)
+		if n > 0 {
+			config.metrics.received(Code(wire[0]))
+		}
+
+		packet, err := parseStreamRequest(wire, config)
+		if err != nil {
+			if config.datagram {
+				if !logEvent(config.logger, ctx, LogLevelWarn, "radius: discarding packet", "remote", conn.RemoteAddr().String(), "error", err) {
+					config.logf("radius: discarding packet from %v: %v", conn.RemoteAddr(), err)
+				}
+				continue
+			}
+			if !logEvent(config.logger, ctx, LogLevelWarn, "radius: closing connection", "remote", conn.RemoteAddr().String(), "error", err) {
+				config.logf("radius: closing connection from %v: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		logEvent(config.logger, ctx, LogLevelDebug, "radius: received packet",
+			"remote", conn.RemoteAddr().String(),
+			"code", packet.Code.String(),
+			"identifier", packet.Identifier,
+			"attributes", redactedAttributes(packet.Attributes))
+		packet.MessageAuthenticatorPolicy = config.messageAuthenticator
+		packet.MaxPacketSize = config.maxPacketSize
+
+		if _, loaded := inProgress.LoadOrStore(packet.Identifier, struct{}{}); loaded {
+			continue
+		}
+
+		request := &Request{
+			LocalAddr:  conn.LocalAddr(),
+			RemoteAddr: conn.RemoteAddr(),
+			Transport:  config.metrics.transport,
+			TLS:        config.tls,
+			Packet:     packet,
+			ctx:        ctx,
+		}
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+			defer inProgress.Delete(request.Identifier)
+			w := &streamResponseWriter{conn: conn, writeMu: &writeMu, metrics: config.metrics, tap: config.tap}
+			start := time.Now()
+			config.handler.ServeRADIUS(w, request)
+			writeMu.Lock()
+			written := w.written
+			writeMu.Unlock()
+			config.metrics.handled(request.Code, written, start)
+		}()
+	}
+}
+
+// parseStreamRequest validates and parses a request received on a stream
+// connection.
+func parseStreamRequest(wire []byte, config streamConfig) (*Packet, error) {
+	if len(wire) < 20 {
+		err := errors.New("packet validation failed; too short")
+		config.metrics.packetError(err)
+		return nil, err
+	}
+	if !IsAuthenticRequest(wire, config.secret) {
+		config.metrics.authFailure(errBadSecret)
+		return nil, errors.New("packet validation failed; bad secret")
+	}
+	policy := config.messageAuthenticator
+	if Code(wire[0]) == CodeStatusServer {
+		// RFC 5997 section 3
+		policy = MessageAuthenticatorRequire
+	}
+	if err := policy.Verify(wire, nil, config.secret, nil); err != nil {
+		config.metrics.authFailure(err)
+		return nil, errors.New("packet validation failed; " + err.Error())
+	}
+	packet, err := ParseWith(wire, config.secret, ParseOptions{MaxPacketSize: config.maxPacketSize})
+	if err != nil {
+		config.metrics.packetError(err)
+		return nil, errors.New("unable to parse packet: " + err.Error())
+	}
+	return packet, nil
+}
@@ -0,0 +1,153 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"syscall"
+	"time"
+)
+
+// TCPServer listens for RADIUS requests over TCP (RFC 6613).
+//
+// Packets are framed by their Length field, and multiple requests can be
+// pipelined on each connection; each is handled in its own goroutine. A
+// connection on which an invalid packet is received is closed.
+type TCPServer struct {
+	// The address on which the server listens. Defaults to :1812.
+	Addr string
+
+	// The source from which the secret is obtained for parsing and validating
+	// the requests. It is consulted once for each connection; connections
+	// for which no secret is returned are closed.
+	SecretSource SecretSource
+
+	// Handler which is called to process the request.
+	Handler Handler
+
+	// Middleware is applied, outermost first, to Handler.
+	Middleware []Middleware
+
+	// Recovery, if non-nil, recovers from panics in Handler and Middleware,
+	// as in PacketServer.
+	Recovery *RecoveryPolicy
+
+	// MessageAuthenticator controls how the Message-Authenticator of incoming
+	// requests is verified, and whether a Message-Authenticator attribute is
+	// added to responses. Connections on which a request fails verification
+	// are closed.
+	MessageAuthenticator MessageAuthenticatorPolicy
+
+	// MaxPacketSize, if greater than zero, is the maximum wire length of
+	// requests accepted and responses sent by the server, up to
+	// MaxExtendedPacketLength. If zero, MaxPacketLength is used.
+	MaxPacketSize int
+
+	// MaxConnections, if positive, is the maximum number of connections that
+	// are served at once. Connections accepted beyond it are closed
+	// immediately.
+	MaxConnections int
+
+	// IdleTimeout, if positive, is how long a connection may remain without
+	// receiving a request before it is closed (RFC 6613 section 2.6.2).
+	IdleTimeout time.Duration
+
+	// Metrics, if non-nil, receives measurements of the requests processed
+	// by the server.
+	Metrics ServerMetrics
+
+	// ErrorLog specifies an optional logger for errors around connection
+	// accepting, and packet processing and validation. If nil, logging is
+	// done via the log package's standard logger.
+	ErrorLog *log.Logger
+
+	// Logger, if non-nil, receives structured events for received packets,
+	// parse errors, secret lookup failures, and shutdown, as in
+	// PacketServer.
+	Logger Logger
+
+	// Control, if non-nil, is called by ListenAndServe after creating the
+	// listening socket and before binding it, as in net.ListenConfig, so
+	// that socket options can be set.
+	Control func(network, address string, c syscall.RawConn) error
+
+	// Tap, if non-nil, receives a copy of every packet read from or written
+	// to the server's connections.
+	Tap Tap
+
+	stream streamServer
+}
+
+func (s *TCPServer) logf(format string, args ...interface{}) {
+	errorLogf(s.Logger, s.ErrorLog, format, args...)
+}
+
+// Serve accepts incoming connections on l.
+func (s *TCPServer) Serve(l net.Listener) error {
+	if s.Handler == nil {
+		return errors.New("radius: nil Handler")
+	}
+	if s.SecretSource == nil {
+		return errors.New("radius: nil SecretSource")
+	}
+	handler := recoverHandler(ChainMiddleware(s.Handler, s.Middleware...), s.Recovery, s.logf)
+
+	return s.stream.serve(l, s.MaxConnections, func(ctx context.Context, conn net.Conn) {
+		secret, err := s.SecretSource.RADIUSSecret(ctx, conn.RemoteAddr())
+		if err != nil {
+			if !logEvent(s.Logger, ctx, LogLevelError, "radius: secret lookup failed", "remote", conn.RemoteAddr().String(), "error", err) {
+				s.logf("radius: error fetching from secret source: %v", err)
+			}
+			conn.Close()
+			return
+		}
+		if len(secret) == 0 {
+			if !logEvent(s.Logger, ctx, LogLevelWarn, "radius: secret lookup failed", "remote", conn.RemoteAddr().String(), "error", errEmptySecret) {
+				s.logf("radius: empty secret returned from secret source")
+			}
+			conn.Close()
+			return
+		}
+
+		serveStreamConn(ctx, conn, streamConfig{
+			handler:              handler,
+			secret:               secret,
+			messageAuthenticator: s.MessageAuthenticator,
+			maxPacketSize:        s.MaxPacketSize,
+			idleTimeout:          s.IdleTimeout,
+			metrics:              serverMetrics{metrics: s.Metrics, transport: "tcp"},
+			logger:               s.Logger,
+			tap:                  s.Tap,
+			logf:                 s.logf,
+		})
+	})
+}
+
+// ListenAndServe starts a TCP RADIUS server on the address given in s.
+func (s *TCPServer) ListenAndServe() error {
+	if s.Handler == nil {
+		return errors.New("radius: nil Handler")
+	}
+	if s.SecretSource == nil {
+		return errors.New("radius: nil SecretSource")
+	}
+	addr := s.Addr
+	if addr == "" {
+		addr = ":1812"
+	}
+	config := net.ListenConfig{Control: s.Control}
+	l, err := config.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.Serve(l)
+}
+
+// Shutdown gracefully stops the server, in the same way as
+// RadSecServer.Shutdown.
+func (s *TCPServer) Shutdown(ctx context.Context) error {
+	logEvent(s.Logger, ctx, LogLevelInfo, "radius: server shutting down")
+	return s.stream.closeShutdown(ctx)
+}
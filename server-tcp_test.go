@@ -0,0 +1,112 @@
+package radius
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestTCPServer(t *testing.T, server *TCPServer) net.Listener {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.SecretSource == nil {
+		server.SecretSource = StaticSecretSource([]byte(`12345`))
+	}
+	if server.Handler == nil {
+		server.Handler = HandlerFunc(func(w ResponseWriter, r *Request) {
+			resp := r.Response(CodeAccessAccept)
+			resp.Add(typeUserName, r.Get(typeUserName))
+			w.Write(resp)
+		})
+	}
+	go server.Serve(l)
+	return l
+}
+
+func TestTCPServer(t *testing.T) {
+	server := &TCPServer{}
+	l := newTestTCPServer(t, server)
+	defer server.Shutdown(context.Background())
+
+	client := &TCPClient{}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			packet := New(CodeAccessRequest, []byte(`12345`))
+			packet.Add(typeUserName, Attribute(name))
+			resp, err := client.Exchange(ctx, packet, l.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got := string(resp.Get(typeUserName)); got != name {
+				t.Errorf("got response for %q; expecting %q", got, name)
+			}
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+}
+
+func TestTCPServer_limits(t *testing.T) {
+	server := &TCPServer{
+		MaxConnections: 1,
+		IdleTimeout:    100 * time.Millisecond,
+	}
+	l := newTestTCPServer(t, server)
+	defer server.Shutdown(context.Background())
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	// Wait for the first connection to be accepted.
+	time.Sleep(20 * time.Millisecond)
+
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := second.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("got %v reading over connection limit; expecting EOF", err)
+	}
+
+	first.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := first.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("got %v reading idle connection; expecting EOF", err)
+	}
+}
+
+func TestTCPServer_Shutdown_idleTimeout(t *testing.T) {
+	// The server resets the idle deadline of the connection after each
+	// response, which must not replace the deadline set to stop reading.
+	for i := 0; i < 50; i++ {
+		server := &TCPServer{IdleTimeout: time.Hour}
+		l := newTestTCPServer(t, server)
+
+		client := &TCPClient{}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := client.Exchange(ctx, New(CodeAccessRequest, []byte(`12345`)), l.Addr().String())
+		if err == nil {
+			err = server.Shutdown(ctx)
+		}
+		cancel()
+		client.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrHandlerTimeout is returned by the ResponseWriter of a request whose
+// handler has exceeded the server's HandlerTimeout.
+var ErrHandlerTimeout = errors.New("radius: handler timeout")
+
+// timeoutResponseWriter discards the responses written after the handler has
+// timed out.
+type timeoutResponseWriter struct {
+	w ResponseWriter
+
+	mu       sync.Mutex
+	timedOut bool
+}
+
+func (w *timeoutResponseWriter) Write(packet *Packet) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return ErrHandlerTimeout
+	}
+	return w.w.Write(packet)
+}
+
+// timeout marks the writer as timed out, and returns false if the handler had
+// already written a response.
+func (w *timeoutResponseWriter) timeout(written func() bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	return !written()
+}
+
+// serveWithTimeout calls handler, canceling the request's context after
+// s.HandlerTimeout. If the handler does not return in time, the request is
+// answered according to s.TimeoutReply, and later responses from the handler
+// are discarded. serveWithTimeout still waits for the handler to return.
+func (s *PacketServer) serveWithTimeout(handler Handler, w *packetResponseWriter, r *Request) {
+	// The context is only canceled once the writer has been marked as timed
+	// out, so that a handler woken by the cancellation cannot respond first.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	r = r.WithContext(ctx)
+	timer := time.NewTimer(s.HandlerTimeout)
+	defer timer.Stop()
+
+	tw := &timeoutResponseWriter{w: w}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeRADIUS(tw, r)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+		<-done
+		return
+	case <-timer.C:
+	}
+	timedOut := tw.timeout(func() bool { return w.written != nil })
+	cancel()
+	if timedOut {
+		if s.OnHandlerTimeout != nil {
+			s.OnHandlerTimeout(r)
+		}
+		if s.TimeoutReply != 0 && r.Code == CodeAccessRequest {
+			if err := w.Write(r.Response(s.TimeoutReply)); err != nil {
+				s.logf("radius: unable to reply to timed out request: %v", err)
+			}
+		}
+	}
+	<-done
+}
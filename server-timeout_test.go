@@ -0,0 +1,50 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacketServer_handlerTimeout(t *testing.T) {
+	secret := []byte(`12345`)
+
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var timeouts int32
+	lateWrite := make(chan error, 1)
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			<-r.Context().Done()
+			lateWrite <- w.Write(r.Response(CodeAccessAccept))
+		}),
+		HandlerTimeout: 20 * time.Millisecond,
+		TimeoutReply:   CodeAccessReject,
+		OnHandlerTimeout: func(r *Request) {
+			atomic.AddInt32(&timeouts, 1)
+		},
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err := (&Client{}).Exchange(ctx, New(CodeAccessRequest, secret), pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != CodeAccessReject {
+		t.Fatalf("got %v; expecting Access-Reject", response.Code)
+	}
+	if err := <-lateWrite; err != ErrHandlerTimeout {
+		t.Fatalf("got late write error %v; expecting ErrHandlerTimeout", err)
+	}
+	if n := atomic.LoadInt32(&timeouts); n != 1 {
+		t.Fatalf("got %d timeouts; expecting 1", n)
+	}
+}
@@ -0,0 +1,88 @@
+package radius
+
+import (
+	"net"
+)
+
+// OverloadPolicy determines what a PacketServer with Workers does with
+// incoming requests when its queue is full.
+type OverloadPolicy int
+
+// OverloadPolicy values.
+const (
+	// OverloadDropNew discards the incoming request.
+	OverloadDropNew OverloadPolicy = iota
+
+	// OverloadDropOldest discards the oldest queued request to make room for
+	// the incoming one.
+	OverloadDropOldest
+
+	// OverloadReject answers incoming Access-Requests with an Access-Reject,
+	// without calling Handler. Other requests are discarded.
+	OverloadReject
+)
+
+// packetJob is a received packet waiting to be processed by a worker.
+type packetJob struct {
+	buff       []byte
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+// startWorkers starts s.Workers goroutines that call process for each job sent
+// on the returned queue, until it is closed.
+func (s *PacketServer) startWorkers(process func(buff []byte, remoteAddr, localAddr net.Addr), metrics serverMetrics) chan packetJob {
+	size := s.QueueSize
+	if size <= 0 {
+		size = s.Workers
+	}
+	queue := make(chan packetJob, size)
+	for i := 0; i < s.Workers; i++ {
+		go func() {
+			for job := range queue {
+				metrics.queueDepth(len(queue))
+				process(job.buff, job.remoteAddr, job.localAddr)
+			}
+		}()
+	}
+	return queue
+}
+
+// enqueue queues job for the workers, applying s.Overload if the queue is full.
+// Jobs that are not queued are marked as done.
+func (s *PacketServer) enqueue(queue chan packetJob, job packetJob, conn net.PacketConn, metrics serverMetrics) {
+	select {
+	case queue <- job:
+		metrics.queueDepth(len(queue))
+		return
+	default:
+	}
+
+	switch s.Overload {
+	case OverloadDropOldest:
+		select {
+		case <-queue:
+			s.activeDone()
+		default:
+		}
+		select {
+		case queue <- job:
+			metrics.queueDepth(len(queue))
+			return
+		default:
+		}
+	case OverloadReject:
+		if packet := s.parseRequest(conn, job.buff, job.remoteAddr, job.localAddr); packet != nil && packet.Code == CodeAccessRequest {
+			response := packetResponseWriter{
+				conn:      conn,
+				addr:      job.remoteAddr,
+				localAddr: job.localAddr,
+				metrics:   metrics,
+			}
+			if err := response.Write(packet.Response(CodeAccessReject)); err != nil {
+				s.logf("radius: unable to reject request: %v", err)
+			}
+		}
+	}
+	s.activeDone()
+}
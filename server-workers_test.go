@@ -0,0 +1,76 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacketServer_workers(t *testing.T) {
+	secret := []byte(`12345`)
+
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlerCalled := make(chan struct{}, 3)
+	release := make(chan struct{})
+	var handled int32
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			atomic.AddInt32(&handled, 1)
+			handlerCalled <- struct{}{}
+			<-release
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		Workers:   1,
+		QueueSize: 1,
+		Overload:  OverloadReject,
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+	addr := pc.LocalAddr().String()
+
+	client := Client{Retry: time.Second}
+	results := make(chan Result, 2)
+	go func() {
+		response, err := client.Exchange(context.Background(), New(CodeAccessRequest, secret), addr)
+		results <- Result{response, err}
+	}()
+	<-handlerCalled
+
+	// queued behind the first request
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	wire, err := New(CodeAccessRequest, secret).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write(wire)
+
+	// rejected while the queue is full
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err := (&Client{}).Exchange(ctx, New(CodeAccessRequest, secret), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != CodeAccessReject {
+		t.Fatalf("got %v; expecting Access-Reject", response.Code)
+	}
+
+	close(release)
+	if result := <-results; result.Err != nil || result.Packet.Code != CodeAccessAccept {
+		t.Fatalf("got %v, %v; expecting Access-Accept", result.Packet, result.Err)
+	}
+	<-handlerCalled
+	if n := atomic.LoadInt32(&handled); n != 2 {
+		t.Fatalf("handler called %d times; expecting 2", n)
+	}
+}
@@ -2,6 +2,8 @@ package radius
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"errors"
 	"net"
 )
@@ -27,12 +29,26 @@ func (h HandlerFunc) ServeRADIUS(w ResponseWriter, r *Request) {
 // Request is an incoming RADIUS request that is being handled by the server.
 type Request struct {
 	// LocalAddr is the local address on which the incoming RADIUS request
-	// was received.
+	// was received. For requests received by a PacketServer with PacketInfo
+	// on a socket bound to an unspecified address, it is the address of the
+	// receiving interface.
 	LocalAddr net.Addr
 	// RemoteAddr is the address from which the incoming RADIUS request
 	// was sent.
 	RemoteAddr net.Addr
 
+	// Transport is the transport on which the request was received: the
+	// network of a PacketServer's listener (e.g. "udp"), or "tcp", "tls", or
+	// "dtls" for TCPServer, RadSecServer, and DTLSServer.
+	Transport string
+
+	// TLS is the state of the TLS connection on which the request was
+	// received, or nil if it was not received over TLS. It identifies the
+	// client by its certificates. For a DTLSServer, it is set if the
+	// session's net.Conn has a ConnectionState() tls.ConnectionState
+	// method.
+	TLS *tls.ConnectionState
+
 	// Packet is the RADIUS packet sent in the request.
 	*Packet
 
@@ -75,6 +91,14 @@ type SecretSource interface {
 	RADIUSSecret(ctx context.Context, remoteAddr net.Addr) ([]byte, error)
 }
 
+// SecretSourceFunc allows a function to implement SecretSource.
+type SecretSourceFunc func(ctx context.Context, remoteAddr net.Addr) ([]byte, error)
+
+// RADIUSSecret calls f(ctx, remoteAddr).
+func (f SecretSourceFunc) RADIUSSecret(ctx context.Context, remoteAddr net.Addr) ([]byte, error) {
+	return f(ctx, remoteAddr)
+}
+
 // StaticSecretSource returns a SecretSource that uses secret for all requests.
 func StaticSecretSource(secret []byte) SecretSource {
 	return &staticSecretSource{secret}
@@ -87,3 +111,16 @@ type staticSecretSource struct {
 func (s *staticSecretSource) RADIUSSecret(ctx context.Context, remoteAddr net.Addr) ([]byte, error) {
 	return s.secret, nil
 }
+
+// VerifyUserPassword decrypts the given "User-Password"-encrypted Attribute
+// and reports whether it matches expected. The comparison is done in constant
+// time with respect to the password contents.
+//
+// false is returned if the attribute cannot be decrypted.
+func VerifyUserPassword(attr Attribute, secret, requestAuth []byte, expected string) bool {
+	password, err := UserPassword(attr, secret, requestAuth)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(password, []byte(expected)) == 1
+}
@@ -0,0 +1,27 @@
+package radius
+
+import (
+	"testing"
+)
+
+func TestVerifyUserPassword(t *testing.T) {
+	secret := []byte(`xyzzy5461`)
+	authenticator := make([]byte, 16)
+	attr, err := NewUserPassword([]byte(`arctangent`), secret, authenticator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !VerifyUserPassword(attr, secret, authenticator, "arctangent") {
+		t.Fatal("expecting password to match")
+	}
+	if VerifyUserPassword(attr, secret, authenticator, "arctangen") {
+		t.Fatal("expecting prefix not to match")
+	}
+	if VerifyUserPassword(attr, []byte(`wrong`), authenticator, "arctangent") {
+		t.Fatal("expecting wrong secret not to match")
+	}
+	if VerifyUserPassword(Attribute{0x01}, secret, authenticator, "") {
+		t.Fatal("expecting malformed attribute not to match")
+	}
+}
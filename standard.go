@@ -0,0 +1,106 @@
+package radius
+
+// lengthConstraint is the permitted value length range of an attribute.
+type lengthConstraint struct {
+	Min, Max int
+}
+
+func fixedLength(n int) lengthConstraint { return lengthConstraint{n, n} }
+
+var (
+	lengthString  = lengthConstraint{1, 253}
+	lengthInteger = fixedLength(4)
+)
+
+// standardLengths contains the length constraints of standard attributes, as
+// defined in RFC 2865, RFC 2866, RFC 2869, and RFC 3162.
+var standardLengths = map[Type]lengthConstraint{
+	1:   lengthString,    // User-Name
+	2:   {16, 128},       // User-Password
+	3:   fixedLength(17), // CHAP-Password
+	4:   fixedLength(4),  // NAS-IP-Address
+	5:   lengthInteger,   // NAS-Port
+	6:   lengthInteger,   // Service-Type
+	7:   lengthInteger,   // Framed-Protocol
+	8:   fixedLength(4),  // Framed-IP-Address
+	9:   fixedLength(4),  // Framed-IP-Netmask
+	10:  lengthInteger,   // Framed-Routing
+	11:  lengthString,    // Filter-Id
+	12:  lengthInteger,   // Framed-MTU
+	13:  lengthInteger,   // Framed-Compression
+	14:  fixedLength(4),  // Login-IP-Host
+	15:  lengthInteger,   // Login-Service
+	16:  lengthInteger,   // Login-TCP-Port
+	18:  lengthString,    // Reply-Message
+	19:  lengthString,    // Callback-Number
+	20:  lengthString,    // Callback-Id
+	22:  lengthString,    // Framed-Route
+	23:  fixedLength(4),  // Framed-IPX-Network
+	24:  lengthString,    // State
+	25:  lengthString,    // Class
+	26:  {5, 253},        // Vendor-Specific
+	27:  lengthInteger,   // Session-Timeout
+	28:  lengthInteger,   // Idle-Timeout
+	29:  lengthInteger,   // Termination-Action
+	30:  lengthString,    // Called-Station-Id
+	31:  lengthString,    // Calling-Station-Id
+	32:  lengthString,    // NAS-Identifier
+	33:  lengthString,    // Proxy-State
+	34:  lengthString,    // Login-LAT-Service
+	35:  lengthString,    // Login-LAT-Node
+	36:  fixedLength(32), // Login-LAT-Group
+	37:  lengthInteger,   // Framed-AppleTalk-Link
+	38:  lengthInteger,   // Framed-AppleTalk-Network
+	39:  lengthString,    // Framed-AppleTalk-Zone
+	40:  lengthInteger,   // Acct-Status-Type
+	41:  lengthInteger,   // Acct-Delay-Time
+	42:  lengthInteger,   // Acct-Input-Octets
+	43:  lengthInteger,   // Acct-Output-Octets
+	44:  lengthString,    // Acct-Session-Id
+	45:  lengthInteger,   // Acct-Authentic
+	46:  lengthInteger,   // Acct-Session-Time
+	47:  lengthInteger,   // Acct-Input-Packets
+	48:  lengthInteger,   // Acct-Output-Packets
+	49:  lengthInteger,   // Acct-Terminate-Cause
+	50:  lengthString,    // Acct-Multi-Session-Id
+	51:  lengthInteger,   // Acct-Link-Count
+	52:  lengthInteger,   // Acct-Input-Gigawords
+	53:  lengthInteger,   // Acct-Output-Gigawords
+	55:  fixedLength(4),  // Event-Timestamp
+	60:  {5, 253},        // CHAP-Challenge
+	61:  lengthInteger,   // NAS-Port-Type
+	62:  lengthInteger,   // Port-Limit
+	63:  lengthString,    // Login-LAT-Port
+	79:  lengthString,    // EAP-Message
+	80:  fixedLength(16), // Message-Authenticator
+	95:  fixedLength(16), // NAS-IPv6-Address
+	96:  fixedLength(8),  // Framed-Interface-Id
+	97:  {2, 18},         // Framed-IPv6-Prefix
+	98:  fixedLength(16), // Login-IPv6-Host
+	99:  lengthString,    // Framed-IPv6-Route
+	100: lengthString,    // Framed-IPv6-Pool
+}
+
+// ValidateStandard checks the length of every standard attribute in a (those
+// defined in RFC 2865, RFC 2866, RFC 2869, and RFC 3162) against the lengths
+// permitted by its RFC. Attributes of other types are not checked.
+//
+// An *InvalidAttributeLengthError is returned for the first attribute that
+// violates its constraint.
+func (a *Attributes) ValidateStandard() error {
+	for _, avp := range *a {
+		constraint, ok := standardLengths[avp.Type]
+		if !ok {
+			continue
+		}
+		if l := len(avp.Attribute); l < constraint.Min || l > constraint.Max {
+			return &InvalidAttributeLengthError{
+				Type:   avp.Type,
+				Length: l,
+				Min:    constraint.Min,
+				Max:    constraint.Max,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package radius
+
+import (
+	"testing"
+)
+
+func TestAttributes_ValidateStandard(t *testing.T) {
+	var a Attributes
+	a.Add(1, Attribute(`bob`))
+	a.Add(4, Attribute{10, 0, 0, 1})
+	a.Add(2, make(Attribute, 16))
+	a.Add(200, nil) // not a standard attribute
+	if err := a.ValidateStandard(); err != nil {
+		t.Fatalf("got error %v; expecting none", err)
+	}
+
+	tests := []struct {
+		Type  Type
+		Value Attribute
+	}{
+		{1, nil},
+		{2, make(Attribute, 8)},
+		{2, make(Attribute, 144)},
+		{4, Attribute{10, 0, 0}},
+		{80, make(Attribute, 15)},
+		{26, Attribute{0, 0, 0, 9}},
+	}
+	for _, tt := range tests {
+		var b Attributes
+		b.Add(1, Attribute(`bob`))
+		b.Add(tt.Type, tt.Value)
+		err := b.ValidateStandard()
+		lengthErr, ok := err.(*InvalidAttributeLengthError)
+		if !ok {
+			t.Errorf("type %d, length %d: got %v; expecting *InvalidAttributeLengthError", tt.Type, len(tt.Value), err)
+			continue
+		}
+		if lengthErr.Type != tt.Type || lengthErr.Length != len(tt.Value) {
+			t.Errorf("got %+v; expecting type %d, length %d", lengthErr, tt.Type, len(tt.Value))
+		}
+	}
+}
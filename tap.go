@@ -0,0 +1,126 @@
+package radius
+
+import (
+	"io"
+	"net"
+	"time"
+)
+
+// TapDirection is the direction of a packet passed to a Tap.
+type TapDirection int
+
+// TapDirection values.
+const (
+	// TapInbound is a packet received from the peer.
+	TapInbound TapDirection = iota
+	// TapOutbound is a packet sent to the peer.
+	TapOutbound
+)
+
+func (d TapDirection) String() string {
+	if d == TapOutbound {
+		return "outbound"
+	}
+	return "inbound"
+}
+
+// TapPacket is a copy of a packet sent or received by a client or server,
+// passed to a Tap.
+type TapPacket struct {
+	Direction TapDirection
+	// Peer is the address of the other end of the exchange: the client of a
+	// server, or the server of a client.
+	Peer net.Addr
+	// Time is when the packet was read or written.
+	Time time.Time
+	// Wire is the packet as it was read or written, before any validation.
+	// It is owned by the Tap, and is not modified after the Tap returns.
+	Wire []byte
+}
+
+// Tap receives copies of the packets sent and received by a client or
+// server, for debugging or for feeding an external analyzer without
+// modifying handlers. Inbound packets are passed to it before they are
+// validated, so that packets discarded because they are invalid are also
+// seen.
+//
+// It is called synchronously from the goroutine reading or writing the
+// packet, and concurrently from several goroutines, so it must not block.
+type Tap func(packet *TapPacket)
+
+// ChannelTap returns a Tap that sends packets to ch. Packets are dropped if
+// ch is not ready to receive them.
+func ChannelTap(ch chan<- *TapPacket) Tap {
+	return func(packet *TapPacket) {
+		select {
+		case ch <- packet:
+		default:
+		}
+	}
+}
+
+// tapWire passes a copy of wire to tap, if it is non-nil.
+func tapWire(tap Tap, direction TapDirection, peer net.Addr, wire []byte) {
+	if tap == nil {
+		return
+	}
+	tap(&TapPacket{
+		Direction: direction,
+		Peer:      peer,
+		Time:      time.Now(),
+		Wire:      append([]byte(nil), wire...),
+	})
+}
+
+// tapWriter returns w, wrapped to pass the packets written to it to tap.
+func tapWriter(tap Tap, w io.Writer, peer net.Addr) io.Writer {
+	if tap == nil {
+		return w
+	}
+	return writerFunc(func(b []byte) (int, error) {
+		n, err := w.Write(b)
+		if err == nil {
+			tapWire(tap, TapOutbound, peer, b)
+		}
+		return n, err
+	})
+}
+
+// tapPacketConn passes the packets read and written on a net.PacketConn to
+// a Tap.
+type tapPacketConn struct {
+	net.PacketConn
+	tap Tap
+}
+
+func (c *tapPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if err == nil {
+		tapWire(c.tap, TapInbound, addr, b[:n])
+	}
+	return n, addr, err
+}
+
+func (c *tapPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	if err == nil {
+		tapWire(c.tap, TapOutbound, addr, b)
+	}
+	return n, err
+}
+
+func (c *tapPacketConn) readFromLocal(b []byte) (int, net.Addr, net.Addr, error) {
+	n, remoteAddr, localAddr, err := readFromLocal(c.PacketConn, b)
+	if err == nil {
+		tapWire(c.tap, TapInbound, remoteAddr, b[:n])
+	}
+	return n, remoteAddr, localAddr, err
+}
+
+func (c *tapPacketConn) writeToLocal(b []byte, remoteAddr, localAddr net.Addr) (int, error) {
+	n, err := writeToLocal(c.PacketConn, b, remoteAddr, localAddr)
+	if err == nil {
+		tapWire(c.tap, TapOutbound, remoteAddr, b)
+	}
+	return n, err
+}
@@ -0,0 +1,69 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTap(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`12345`)
+	serverTap := make(chan *TapPacket, 10)
+	server := PacketServer{
+		SecretSource: StaticSecretSource(secret),
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Write(r.Response(CodeAccessAccept))
+		}),
+		Tap: ChannelTap(serverTap),
+	}
+	go server.Serve(pc)
+	defer server.Shutdown(context.Background())
+
+	clientTap := make(chan *TapPacket, 10)
+	client := &Client{Tap: ChannelTap(clientTap)}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	response, err := client.Exchange(ctx, New(CodeAccessRequest, secret), pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(ch chan *TapPacket) *TapPacket {
+		select {
+		case packet := <-ch:
+			return packet
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for tapped packet")
+			return nil
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		ch        chan *TapPacket
+		direction TapDirection
+		code      Code
+	}{
+		{"client", clientTap, TapOutbound, CodeAccessRequest},
+		{"client", clientTap, TapInbound, CodeAccessAccept},
+		{"server", serverTap, TapInbound, CodeAccessRequest},
+		{"server", serverTap, TapOutbound, CodeAccessAccept},
+	} {
+		packet := next(tc.ch)
+		if packet.Direction != tc.direction || Code(packet.Wire[0]) != tc.code {
+			t.Fatalf("%s: got %v %v; expecting %v %v", tc.name, packet.Direction, Code(packet.Wire[0]), tc.direction, tc.code)
+		}
+		if packet.Peer == nil || packet.Time.IsZero() {
+			t.Fatalf("%s: missing peer or time: %+v", tc.name, packet)
+		}
+		if packet.Wire[1] != response.Identifier {
+			t.Fatalf("%s: identifier = %d; expecting %d", tc.name, packet.Wire[1], response.Identifier)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package radius
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"layeh.com/radius/dictionary"
+)
+
+// TypedAVP is an attribute-value pair whose value has been decoded according
+// to the dictionary registered with RegisterDictionary.
+//
+// The dynamic type of Value depends on Kind:
+//
+//	dictionary.AttributeString      string
+//	dictionary.AttributeIPAddr      net.IP
+//	dictionary.AttributeIPv6Addr    net.IP
+//	dictionary.AttributeIPv6Prefix  *net.IPNet
+//	dictionary.AttributeIFID        net.HardwareAddr
+//	dictionary.AttributeDate        time.Time
+//	dictionary.AttributeInteger     uint32
+//	dictionary.AttributeInteger64   uint64
+//	dictionary.AttributeShort       uint16
+//	dictionary.AttributeByte        byte
+//
+// Values of all other kinds, encrypted attributes, and attributes that are
+// not in the dictionary are left as their wire value ([]byte).
+type TypedAVP struct {
+	Type Type
+	// Name is the dictionary name of the attribute, or empty if the attribute
+	// is not in the dictionary.
+	Name string
+	Kind dictionary.AttributeType
+	// Tag is the RFC 2868 tag of the attribute, if the dictionary marks it as
+	// tagged.
+	Tag   byte
+	Value interface{}
+}
+
+// Typed decodes avp using the dictionary registered with RegisterDictionary.
+// An error is returned if the attribute's value is malformed for its kind.
+func (avp *AVP) Typed() (*TypedAVP, error) {
+	typed := &TypedAVP{
+		Type:  avp.Type,
+		Kind:  dictionary.AttributeOctets,
+		Value: []byte(avp.Attribute),
+	}
+	dictAttr := registeredAttribute(avp.Type)
+	if dictAttr == nil {
+		return typed, nil
+	}
+	typed.Name = dictAttr.Name
+	typed.Kind = dictAttr.Type
+	if dictAttr.FlagEncrypt.Valid {
+		return typed, nil
+	}
+
+	attr := avp.Attribute
+	if dictAttr.HasTag() {
+		switch dictAttr.Type {
+		case dictionary.AttributeInteger:
+			tag, value, err := TaggedInteger(attr)
+			if err != nil {
+				return nil, err
+			}
+			typed.Tag, typed.Value = tag, value
+			return typed, nil
+		case dictionary.AttributeString:
+			typed.Tag, attr = TaggedString(attr)
+		}
+	}
+
+	var value interface{}
+	var err error
+	switch dictAttr.Type {
+	case dictionary.AttributeString:
+		value = String(attr)
+	case dictionary.AttributeIPAddr:
+		value, err = IPAddr(attr)
+	case dictionary.AttributeIPv6Addr:
+		value, err = IPv6Addr(attr)
+	case dictionary.AttributeIPv6Prefix:
+		value, err = IPv6Prefix(attr)
+	case dictionary.AttributeIFID:
+		value, err = IFID(attr)
+	case dictionary.AttributeDate:
+		value, err = Date(attr)
+	case dictionary.AttributeInteger:
+		value, err = Integer(attr)
+	case dictionary.AttributeInteger64:
+		value, err = Integer64(attr)
+	case dictionary.AttributeShort:
+		value, err = Short(attr)
+	case dictionary.AttributeByte:
+		if len(attr) != 1 {
+			err = errors.New("invalid length")
+		} else {
+			value = attr[0]
+		}
+	default:
+		return typed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	typed.Value = value
+	return typed, nil
+}
+
+// Typed decodes all attributes in a using the dictionary registered with
+// RegisterDictionary (see AVP.Typed). Wire order is preserved.
+func (a *Attributes) Typed() ([]*TypedAVP, error) {
+	typed := make([]*TypedAVP, 0, len(*a))
+	for _, avp := range *a {
+		t, err := avp.Typed()
+		if err != nil {
+			return nil, err
+		}
+		typed = append(typed, t)
+	}
+	return typed, nil
+}
+
+// Attribute encodes t back to its wire value. A Value of type []byte or
+// Attribute is used as-is, regardless of Kind. An error is returned if Value
+// is not of the type expected for Kind.
+func (t *TypedAVP) Attribute() (Attribute, error) {
+	var tagged bool
+	if dictAttr := registeredAttribute(t.Type); dictAttr != nil {
+		tagged = dictAttr.HasTag()
+	}
+	return encodeValue(t.Kind, tagged, t.Tag, t.Value)
+}
+
+// encodeValue encodes value, whose dynamic type must be one of those listed in
+// the TypedAVP documentation for kind, to its wire format.
+func encodeValue(kind dictionary.AttributeType, tagged bool, tag byte, value interface{}) (Attribute, error) {
+	switch v := value.(type) {
+	case Attribute:
+		return v, nil
+	case []byte:
+		return v, nil
+	case string:
+		if kind != dictionary.AttributeString && kind != dictionary.AttributeOctets {
+			break
+		}
+		if tagged {
+			return NewTaggedString(tag, v)
+		}
+		return NewString(v)
+	case net.IP:
+		switch kind {
+		case dictionary.AttributeIPAddr:
+			return NewIPAddr(v)
+		case dictionary.AttributeIPv6Addr:
+			return NewIPv6Addr(v)
+		}
+	case *net.IPNet:
+		if kind == dictionary.AttributeIPv6Prefix {
+			return NewIPv6Prefix(v)
+		}
+	case net.HardwareAddr:
+		if kind == dictionary.AttributeIFID {
+			return NewIFID(v)
+		}
+	case time.Time:
+		if kind == dictionary.AttributeDate {
+			return NewDate(v)
+		}
+	case uint32:
+		if kind != dictionary.AttributeInteger {
+			break
+		}
+		if tagged {
+			return NewTaggedInteger(tag, v)
+		}
+		return NewInteger(v), nil
+	case uint64:
+		if kind == dictionary.AttributeInteger64 {
+			return NewInteger64(v), nil
+		}
+	case uint16:
+		if kind == dictionary.AttributeShort {
+			return NewShort(v), nil
+		}
+	case byte:
+		if kind == dictionary.AttributeByte {
+			return Attribute{v}, nil
+		}
+	}
+	return nil, errors.New("radius: unsupported value for attribute kind " + kind.String())
+}
+
+// AddTyped encodes t and appends it to a.
+func (a *Attributes) AddTyped(t *TypedAVP) error {
+	attr, err := t.Attribute()
+	if err != nil {
+		return err
+	}
+	a.Add(t.Type, attr)
+	return nil
+}
+
+// lookupTyped returns the first attribute with the given dictionary name,
+// decoded using the dictionary registered with RegisterDictionary.
+func (a *Attributes) lookupTyped(name string) (*TypedAVP, error) {
+	dictAttr := registeredAttributeByName(name)
+	if dictAttr == nil {
+		return nil, errors.New("radius: unknown attribute " + strconv.Quote(name))
+	}
+	if dictAttr.FlagEncrypt.Valid {
+		return nil, errors.New("radius: attribute " + strconv.Quote(name) + " is encrypted")
+	}
+	for _, avp := range *a {
+		if avp.Type == Type(dictAttr.OID[0]) {
+			return avp.Typed()
+		}
+	}
+	return nil, ErrNoAttribute
+}
+
+// LookupString returns the value of the first attribute with the given
+// dictionary name as a string. The attribute must be of kind string or
+// octets.
+//
+// An error is returned if the name is not in the dictionary registered with
+// RegisterDictionary, if the attribute is of a different kind or is
+// encrypted, or if it is malformed. ErrNoAttribute is returned if there is no
+// such attribute in a.
+func (a *Attributes) LookupString(name string) (string, error) {
+	typed, err := a.lookupTyped(name)
+	if err != nil {
+		return "", err
+	}
+	switch v := typed.Value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		if typed.Kind == dictionary.AttributeOctets {
+			return string(v), nil
+		}
+	}
+	return "", errors.New("radius: attribute " + strconv.Quote(name) + " is not a string")
+}
+
+// GetString returns the value of LookupString, or an empty string if it
+// returns an error.
+func (a *Attributes) GetString(name string) string {
+	v, _ := a.LookupString(name)
+	return v
+}
+
+// LookupInt returns the value of the first attribute with the given
+// dictionary name as an integer. The attribute must be of kind integer,
+// integer64, short, or byte; the tag of tagged integers is not included.
+// Errors are returned like LookupString.
+func (a *Attributes) LookupInt(name string) (uint64, error) {
+	typed, err := a.lookupTyped(name)
+	if err != nil {
+		return 0, err
+	}
+	switch v := typed.Value.(type) {
+	case uint32:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case uint16:
+		return uint64(v), nil
+	case byte:
+		return uint64(v), nil
+	}
+	return 0, errors.New("radius: attribute " + strconv.Quote(name) + " is not an integer")
+}
+
+// GetInt returns the value of LookupInt, or 0 if it returns an error.
+func (a *Attributes) GetInt(name string) uint64 {
+	v, _ := a.LookupInt(name)
+	return v
+}
+
+// LookupIP returns the value of the first attribute with the given dictionary
+// name as an IP address. The attribute must be of kind ipaddr or ipv6addr.
+// Errors are returned like LookupString.
+func (a *Attributes) LookupIP(name string) (net.IP, error) {
+	typed, err := a.lookupTyped(name)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := typed.Value.(net.IP); ok {
+		return v, nil
+	}
+	return nil, errors.New("radius: attribute " + strconv.Quote(name) + " is not an IP address")
+}
+
+// GetIP returns the value of LookupIP, or nil if it returns an error.
+func (a *Attributes) GetIP(name string) net.IP {
+	v, _ := a.LookupIP(name)
+	return v
+}
+
+// LookupTime returns the value of the first attribute with the given
+// dictionary name as a time. The attribute must be of kind date. Errors are
+// returned like LookupString.
+func (a *Attributes) LookupTime(name string) (time.Time, error) {
+	typed, err := a.lookupTyped(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if v, ok := typed.Value.(time.Time); ok {
+		return v, nil
+	}
+	return time.Time{}, errors.New("radius: attribute " + strconv.Quote(name) + " is not a date")
+}
+
+// GetTime returns the value of LookupTime, or the zero time if it returns an
+// error.
+func (a *Attributes) GetTime(name string) time.Time {
+	v, _ := a.LookupTime(name)
+	return v
+}
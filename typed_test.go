@@ -0,0 +1,133 @@
+package radius
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"layeh.com/radius/dictionary"
+)
+
+func TestAttributes_Typed(t *testing.T) {
+	RegisterDictionary(&dictionary.Dictionary{
+		Attributes: []*dictionary.Attribute{
+			{Name: "User-Name", OID: dictionary.OID{1}, Type: dictionary.AttributeString},
+			{Name: "User-Password", OID: dictionary.OID{2}, Type: dictionary.AttributeString, FlagEncrypt: dictionary.IntFlag{Int: dictionary.EncryptUserPassword, Valid: true}},
+			{Name: "NAS-IP-Address", OID: dictionary.OID{4}, Type: dictionary.AttributeIPAddr},
+			{Name: "Session-Timeout", OID: dictionary.OID{27}, Type: dictionary.AttributeInteger},
+			{Name: "Event-Timestamp", OID: dictionary.OID{55}, Type: dictionary.AttributeDate},
+			{Name: "Tunnel-Type", OID: dictionary.OID{64}, Type: dictionary.AttributeInteger, FlagHasTag: dictionary.BoolFlag{Bool: true, Valid: true}},
+		},
+	})
+	defer RegisterDictionary(nil)
+
+	timestamp := time.Date(2018, 5, 13, 11, 55, 10, 0, time.UTC)
+	var a Attributes
+	a.Add(1, Attribute(`bob`))
+	a.Add(2, Attribute{0x01, 0x02})
+	a.Add(4, Attribute{10, 0, 0, 1})
+	a.Add(27, NewInteger(3600))
+	date, _ := NewDate(timestamp)
+	a.Add(55, date)
+	a.Add(64, Attribute{0x02, 0x00, 0x00, 0x0d})
+	a.Add(200, Attribute{0xff})
+
+	typed, err := a.Typed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := typed[0].Value.(string); !ok || v != "bob" || typed[0].Name != "User-Name" {
+		t.Fatalf("unexpected User-Name %+v", typed[0])
+	}
+	if v, ok := typed[1].Value.([]byte); !ok || !bytes.Equal(v, []byte{0x01, 0x02}) {
+		t.Fatalf("expecting encrypted User-Password to be left as raw bytes; got %+v", typed[1])
+	}
+	if v, ok := typed[2].Value.(net.IP); !ok || !v.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Fatalf("unexpected NAS-IP-Address %+v", typed[2])
+	}
+	if v, ok := typed[3].Value.(uint32); !ok || v != 3600 {
+		t.Fatalf("unexpected Session-Timeout %+v", typed[3])
+	}
+	if v, ok := typed[4].Value.(time.Time); !ok || !v.Equal(timestamp) {
+		t.Fatalf("unexpected Event-Timestamp %+v", typed[4])
+	}
+	if v, ok := typed[5].Value.(uint32); !ok || v != 13 || typed[5].Tag != 2 {
+		t.Fatalf("unexpected Tunnel-Type %+v", typed[5])
+	}
+	if typed[6].Name != "" || typed[6].Kind != dictionary.AttributeOctets {
+		t.Fatalf("unexpected unknown attribute %+v", typed[6])
+	}
+
+	var b Attributes
+	for _, avp := range typed {
+		if err := b.AddTyped(avp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !b.Equal(&a) {
+		t.Fatalf("got %v; expecting %v", b, a)
+	}
+
+	a.Add(4, Attribute{0x01})
+	if _, err := a.Typed(); err == nil {
+		t.Fatal("expecting malformed NAS-IP-Address error")
+	}
+	if err := b.AddTyped(&TypedAVP{Type: 27, Kind: dictionary.AttributeInteger, Value: 1}); err == nil {
+		t.Fatal("expecting unsupported value error")
+	}
+}
+
+func TestAttributes_namedGetters(t *testing.T) {
+	RegisterDictionary(&dictionary.Dictionary{
+		Attributes: []*dictionary.Attribute{
+			{Name: "User-Password", OID: dictionary.OID{2}, Type: dictionary.AttributeString, FlagEncrypt: dictionary.IntFlag{Int: dictionary.EncryptUserPassword, Valid: true}},
+			{Name: "NAS-IP-Address", OID: dictionary.OID{4}, Type: dictionary.AttributeIPAddr},
+			{Name: "State", OID: dictionary.OID{24}, Type: dictionary.AttributeOctets},
+			{Name: "Session-Timeout", OID: dictionary.OID{27}, Type: dictionary.AttributeInteger},
+			{Name: "Calling-Station-Id", OID: dictionary.OID{31}, Type: dictionary.AttributeString},
+			{Name: "Event-Timestamp", OID: dictionary.OID{55}, Type: dictionary.AttributeDate},
+		},
+	})
+	defer RegisterDictionary(nil)
+
+	timestamp := time.Date(2018, 5, 13, 11, 55, 10, 0, time.UTC)
+	var a Attributes
+	a.Add(2, Attribute{0x01, 0x02})
+	a.Add(4, Attribute{10, 0, 0, 1})
+	a.Add(24, Attribute(`state`))
+	a.Add(27, NewInteger(3600))
+	a.Add(31, Attribute(`00-11-22-33-44-55`))
+	date, _ := NewDate(timestamp)
+	a.Add(55, date)
+
+	if v := a.GetString("Calling-Station-Id"); v != "00-11-22-33-44-55" {
+		t.Fatalf("got %q", v)
+	}
+	if v := a.GetString("State"); v != "state" {
+		t.Fatalf("got %q", v)
+	}
+	if v := a.GetInt("Session-Timeout"); v != 3600 {
+		t.Fatalf("got %d", v)
+	}
+	if v := a.GetIP("NAS-IP-Address"); !v.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Fatalf("got %v", v)
+	}
+	if v := a.GetTime("Event-Timestamp"); !v.Equal(timestamp) {
+		t.Fatalf("got %v", v)
+	}
+
+	if _, err := a.LookupString("Unknown"); err == nil {
+		t.Fatal("expecting unknown attribute error")
+	}
+	if _, err := a.LookupString("User-Password"); err == nil {
+		t.Fatal("expecting encrypted attribute error")
+	}
+	if _, err := a.LookupInt("Calling-Station-Id"); err == nil {
+		t.Fatal("expecting kind mismatch error")
+	}
+	var empty Attributes
+	if _, err := empty.LookupIP("NAS-IP-Address"); err != ErrNoAttribute {
+		t.Fatalf("got %v; expecting ErrNoAttribute", err)
+	}
+}
@@ -0,0 +1,234 @@
+package radius
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// occurrence is the permitted number of times an attribute may appear in a
+// packet. A negative Max means that there is no upper limit.
+type occurrence struct {
+	Min, Max int
+}
+
+var (
+	occurrenceNone       = occurrence{0, 0}
+	occurrenceOptional   = occurrence{0, 1}
+	occurrenceExactlyOne = occurrence{1, 1}
+)
+
+// standardOccurrences contains the attribute occurrence rules of request
+// packets, as defined in the tables of RFC 2865 section 5.44 and RFC 2866
+// section 5.13. Attributes that are not listed may appear any number of times.
+var standardOccurrences = map[Code]map[Type]occurrence{
+	CodeAccessRequest: {
+		1:  occurrenceOptional, // User-Name
+		2:  occurrenceOptional, // User-Password
+		3:  occurrenceOptional, // CHAP-Password
+		4:  occurrenceOptional, // NAS-IP-Address
+		5:  occurrenceOptional, // NAS-Port
+		6:  occurrenceOptional, // Service-Type
+		18: occurrenceNone,     // Reply-Message
+		24: occurrenceOptional, // State
+		25: occurrenceNone,     // Class
+		30: occurrenceOptional, // Called-Station-Id
+		31: occurrenceOptional, // Calling-Station-Id
+		32: occurrenceOptional, // NAS-Identifier
+		60: occurrenceOptional, // CHAP-Challenge
+		61: occurrenceOptional, // NAS-Port-Type
+		80: occurrenceOptional, // Message-Authenticator
+	},
+	CodeAccountingRequest: {
+		1:  occurrenceOptional,   // User-Name
+		2:  occurrenceNone,       // User-Password
+		3:  occurrenceNone,       // CHAP-Password
+		4:  occurrenceOptional,   // NAS-IP-Address
+		5:  occurrenceOptional,   // NAS-Port
+		6:  occurrenceOptional,   // Service-Type
+		18: occurrenceNone,       // Reply-Message
+		24: occurrenceOptional,   // State
+		30: occurrenceOptional,   // Called-Station-Id
+		31: occurrenceOptional,   // Calling-Station-Id
+		32: occurrenceOptional,   // NAS-Identifier
+		40: occurrenceExactlyOne, // Acct-Status-Type
+		41: occurrenceOptional,   // Acct-Delay-Time
+		42: occurrenceOptional,   // Acct-Input-Octets
+		43: occurrenceOptional,   // Acct-Output-Octets
+		44: occurrenceExactlyOne, // Acct-Session-Id
+		45: occurrenceOptional,   // Acct-Authentic
+		46: occurrenceOptional,   // Acct-Session-Time
+		47: occurrenceOptional,   // Acct-Input-Packets
+		48: occurrenceOptional,   // Acct-Output-Packets
+		49: occurrenceOptional,   // Acct-Terminate-Cause
+		50: occurrenceOptional,   // Acct-Multi-Session-Id
+		51: occurrenceOptional,   // Acct-Link-Count
+		61: occurrenceOptional,   // NAS-Port-Type
+	},
+}
+
+// AttributeCountError is returned when a packet contains an attribute more or
+// fewer times than is permitted for its code.
+type AttributeCountError struct {
+	Code     Code
+	Type     Type
+	Count    int
+	Min, Max int
+}
+
+func (e *AttributeCountError) Error() string {
+	var expecting string
+	switch {
+	case e.Max == 0:
+		expecting = "none"
+	case e.Min == e.Max:
+		expecting = "exactly " + strconv.Itoa(e.Min)
+	default:
+		expecting = "at most " + strconv.Itoa(e.Max)
+	}
+	return `radius: ` + e.Code.String() + ` contains attribute ` + strconv.Itoa(int(e.Type)) + ` ` + strconv.Itoa(e.Count) + ` times (expecting ` + expecting + `)`
+}
+
+// ValidationError is returned by Packet.Validate. It contains every rule
+// violation that was found in the packet.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = strings.TrimPrefix(err.Error(), "radius: ")
+	}
+	return `radius: invalid packet: ` + strings.Join(messages, "; ")
+}
+
+// Attribute types used by the semantic rules of Validate.
+const (
+	typeCHAPPassword   Type = 3
+	typeState          Type = 24
+	typeEAPMessage     Type = 79
+	typeNASIPv6Address Type = 95
+)
+
+// Validate checks p against the code-specific rules of RFC 2865, RFC 2866,
+// and RFC 3579:
+//
+//   - Access-Request packets must contain one of User-Password, CHAP-Password,
+//     State, or EAP-Message; must not contain both User-Password and
+//     CHAP-Password, nor EAP-Message with either; and must contain a
+//     NAS-IP-Address, NAS-IPv6-Address, or NAS-Identifier.
+//   - Accounting-Request packets must contain a NAS-IP-Address,
+//     NAS-IPv6-Address, or NAS-Identifier.
+//   - Request attributes must appear a permitted number of times (e.g.
+//     Accounting-Request packets must not contain a User-Password, and must
+//     contain exactly one Acct-Status-Type).
+//   - Standard attributes must have a permitted length (see
+//     ValidateStandard).
+//
+// A *ValidationError containing all of the violations is returned if any
+// rule is broken.
+func (p *Packet) Validate() error {
+	var errs []error
+
+	has := func(t Type) bool {
+		if t == typeUserPassword && p.userPassword != nil {
+			// Set with SetUserPassword, and added by Encode.
+			return true
+		}
+		_, ok := p.Lookup(t)
+		return ok
+	}
+	hasNAS := has(typeNASIPAddress) || has(typeNASIPv6Address) || has(typeNASIdentifier)
+
+	switch p.Code {
+	case CodeAccessRequest:
+		password, chap := has(typeUserPassword), has(typeCHAPPassword)
+		if !password && !chap && !has(typeState) && !has(typeEAPMessage) {
+			errs = append(errs, errors.New("radius: Access-Request contains no User-Password, CHAP-Password, State, or EAP-Message"))
+		}
+		if password && chap {
+			errs = append(errs, errors.New("radius: Access-Request contains both User-Password and CHAP-Password"))
+		}
+		if has(typeEAPMessage) && (password || chap) {
+			errs = append(errs, errors.New("radius: Access-Request contains EAP-Message with User-Password or CHAP-Password"))
+		}
+		if !hasNAS {
+			errs = append(errs, errors.New("radius: Access-Request contains no NAS-IP-Address, NAS-IPv6-Address, or NAS-Identifier"))
+		}
+	case CodeAccountingRequest:
+		if !hasNAS {
+			errs = append(errs, errors.New("radius: Accounting-Request contains no NAS-IP-Address, NAS-IPv6-Address, or NAS-Identifier"))
+		}
+	}
+
+	if rules, ok := standardOccurrences[p.Code]; ok {
+		counts := make(map[Type]int)
+		for _, avp := range p.Attributes {
+			counts[avp.Type]++
+		}
+		if p.userPassword != nil {
+			// Encode replaces any User-Password attributes.
+			counts[typeUserPassword] = 1
+		}
+		for _, t := range sortedOccurrenceTypes(rules) {
+			rule := rules[t]
+			if n := counts[t]; n < rule.Min || (rule.Max >= 0 && n > rule.Max) {
+				errs = append(errs, &AttributeCountError{
+					Code:  p.Code,
+					Type:  t,
+					Count: n,
+					Min:   rule.Min,
+					Max:   rule.Max,
+				})
+			}
+		}
+	}
+
+	checkLength := func(t Type, l int) {
+		constraint, ok := standardLengths[t]
+		if !ok {
+			return
+		}
+		if l < constraint.Min || l > constraint.Max {
+			errs = append(errs, &InvalidAttributeLengthError{
+				Type:   t,
+				Length: l,
+				Min:    constraint.Min,
+				Max:    constraint.Max,
+			})
+		}
+	}
+	for _, avp := range p.Attributes {
+		if avp.Type == typeUserPassword && p.userPassword != nil {
+			continue
+		}
+		checkLength(avp.Type, len(avp.Attribute))
+	}
+	if p.userPassword != nil {
+		// The length of the encrypted password, which is padded to a
+		// multiple of 16 bytes.
+		l := (len(p.userPassword) + 15) / 16 * 16
+		if l == 0 {
+			l = 16
+		}
+		checkLength(typeUserPassword, l)
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// sortedOccurrenceTypes returns the types of rules in ascending order, so that
+// errors are reported deterministically.
+func sortedOccurrenceTypes(rules map[Type]occurrence) []Type {
+	types := make([]Type, 0, len(rules))
+	for t := range rules {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
@@ -0,0 +1,96 @@
+package radius
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPacket_Validate(t *testing.T) {
+	secret := []byte(`12345`)
+
+	p := New(CodeAccessRequest, secret)
+	p.Add(typeUserName, Attribute("bob"))
+	p.Add(typeNASIdentifier, Attribute("nas"))
+	p.SetUserPassword([]byte("pass"))
+	encoded, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = Parse(encoded, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("got %v; expecting valid packet", err)
+	}
+
+	p.Add(typeCHAPPassword, make(Attribute, 17))
+	p.Add(typeUserName, Attribute("alice"))
+	p.Del(typeNASIdentifier)
+	err = p.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("got %v; expecting *ValidationError", err)
+	}
+	if n := len(verr.Errors); n != 3 {
+		t.Fatalf("got %d errors (%v); expecting 3", n, err)
+	}
+	if countErr, ok := verr.Errors[2].(*AttributeCountError); !ok || countErr.Type != typeUserName || countErr.Count != 2 {
+		t.Fatalf("got %v; expecting User-Name count error", verr.Errors[2])
+	}
+	if !strings.Contains(err.Error(), "both User-Password and CHAP-Password") {
+		t.Fatalf("unexpected error message %q", err.Error())
+	}
+}
+
+func TestPacket_Validate_accounting(t *testing.T) {
+	p := New(CodeAccountingRequest, []byte(`12345`))
+	p.Add(typeNASIPAddress, Attribute{10, 0, 0, 1})
+	p.Add(typeUserPassword, make(Attribute, 16))
+	p.Add(44, Attribute("session"))
+
+	err := p.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("got %v; expecting *ValidationError", err)
+	}
+	var types []Type
+	for _, e := range verr.Errors {
+		if countErr, ok := e.(*AttributeCountError); ok {
+			types = append(types, countErr.Type)
+		}
+	}
+	if len(types) != 2 || types[0] != typeUserPassword || types[1] != 40 {
+		t.Fatalf("got count errors for %v; expecting [2 40]", types)
+	}
+
+	p.Del(typeUserPassword)
+	p.Add(40, Attribute{0, 0, 0, 1})
+	if err := p.Validate(); err != nil {
+		t.Fatalf("got %v; expecting valid packet", err)
+	}
+}
+
+func TestPacket_Validate_setUserPassword(t *testing.T) {
+	p := New(CodeAccessRequest, []byte(`12345`))
+	p.Add(typeNASIdentifier, Attribute("nas"))
+	p.SetUserPassword([]byte("pass"))
+	if err := p.Validate(); err != nil {
+		t.Fatalf("got %v; expecting valid packet", err)
+	}
+
+	p.Add(typeCHAPPassword, make(Attribute, 17))
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "both User-Password and CHAP-Password") {
+		t.Fatalf("got %v; expecting User-Password and CHAP-Password error", err)
+	}
+
+	p.Del(typeCHAPPassword)
+	p.SetUserPassword(make([]byte, 129))
+	verr, ok := p.Validate().(*ValidationError)
+	if !ok || len(verr.Errors) != 1 {
+		t.Fatalf("got %v; expecting a single error", verr)
+	}
+	if lengthErr, ok := verr.Errors[0].(*InvalidAttributeLengthError); !ok || lengthErr.Type != typeUserPassword || lengthErr.Length != 144 {
+		t.Fatalf("got %v; expecting User-Password length error", verr.Errors[0])
+	}
+}
@@ -0,0 +1,120 @@
+package radius
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// typeVendorSpecific is the RFC 2865 Vendor-Specific attribute type.
+const typeVendorSpecific Type = 26
+
+// AddVendor appends a Vendor-Specific attribute to a that contains a single
+// sub-attribute of the given vendor and type, using the framing suggested by
+// RFC 2865 section 5.26 (a one octet type and a one octet length).
+func (a *Attributes) AddVendor(vendorID uint32, subType byte, value Attribute) error {
+	if len(value) > 247 {
+		return errors.New("radius: vendor attribute too large")
+	}
+	vsa := make(Attribute, 4+2+len(value))
+	binary.BigEndian.PutUint32(vsa, vendorID)
+	vsa[4] = subType
+	vsa[5] = byte(2 + len(value))
+	copy(vsa[6:], value)
+	a.Add(typeVendorSpecific, vsa)
+	return nil
+}
+
+// GetVendor returns the first sub-attribute of the given vendor and type. nil
+// is returned if no such sub-attribute exists in a.
+func (a *Attributes) GetVendor(vendorID uint32, subType byte) Attribute {
+	attr, _ := a.LookupVendor(vendorID, subType)
+	return attr
+}
+
+// LookupVendor returns the first sub-attribute of the given vendor and type.
+// Vendor-Specific attributes that contain multiple sub-attributes are
+// searched in order. nil and false is returned if no such sub-attribute exists
+// in a.
+func (a *Attributes) LookupVendor(vendorID uint32, subType byte) (Attribute, bool) {
+	for _, avp := range *a {
+		if avp.Type != typeVendorSpecific {
+			continue
+		}
+		var value Attribute
+		var found bool
+		forEachVendorAttribute(avp.Attribute, vendorID, func(typ byte, attr Attribute) bool {
+			if typ == subType {
+				value, found = attr, true
+				return false
+			}
+			return true
+		})
+		if found {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// DelVendor removes all sub-attributes of the given vendor and type from a.
+// Other sub-attributes packed into the same Vendor-Specific attribute are
+// kept; Vendor-Specific attributes that become empty are removed.
+func (a *Attributes) DelVendor(vendorID uint32, subType byte) {
+	for i := 0; i < len(*a); {
+		avp := (*a)[i]
+		if avp.Type != typeVendorSpecific {
+			i++
+			continue
+		}
+
+		var remaining Attribute
+		var removed bool
+		valid := forEachVendorAttribute(avp.Attribute, vendorID, func(typ byte, attr Attribute) bool {
+			if typ == subType {
+				removed = true
+			} else {
+				remaining = append(remaining, typ, byte(2+len(attr)))
+				remaining = append(remaining, attr...)
+			}
+			return true
+		})
+		switch {
+		case !valid || !removed:
+			i++
+		case len(remaining) == 0:
+			*a = append((*a)[:i], (*a)[i+1:]...)
+		default:
+			(*a)[i] = &AVP{
+				Type:      typeVendorSpecific,
+				Attribute: append(avp.Attribute[:4:4], remaining...),
+			}
+			i++
+		}
+	}
+}
+
+// forEachVendorAttribute calls fn for each sub-attribute in vsa, if vsa is a
+// Vendor-Specific attribute value of the given vendor. Iteration stops when fn
+// returns false. false is returned if vsa belongs to a different vendor, or if
+// its sub-attributes are malformed; in the latter case, fn may already have
+// been called for the preceding sub-attributes.
+func forEachVendorAttribute(vsa Attribute, vendorID uint32, fn func(typ byte, attr Attribute) bool) bool {
+	if len(vsa) < 4 || binary.BigEndian.Uint32(vsa) != vendorID {
+		return false
+	}
+	vsa = vsa[4:]
+	for len(vsa) > 0 {
+		if len(vsa) < 2 {
+			return false
+		}
+		length := int(vsa[1])
+		if length < 2 || length > len(vsa) {
+			return false
+		}
+		if !fn(vsa[0], vsa[2:length]) {
+			return true
+		}
+		vsa = vsa[length:]
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package radius
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAttributes_vendor(t *testing.T) {
+	var a Attributes
+	if err := a.AddVendor(311, 1, []byte(`one`)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte("\x00\x00\x01\x37\x01\x05one"); !bytes.Equal(a[0].Attribute, expected) {
+		t.Fatalf("got %x; expecting %x", a[0].Attribute, expected)
+	}
+	if err := a.AddVendor(311, 1, make([]byte, 248)); err == nil {
+		t.Fatal("expecting error for oversized value")
+	}
+
+	// Multiple sub-attributes packed into one Vendor-Specific attribute
+	a.Add(26, []byte("\x00\x00\x01\x37\x02\x05two\x03\x02"))
+	a.Add(26, []byte("\x00\x00\x00\x09\x02\x05cis"))
+	// Malformed sub-attribute length
+	a.Add(26, []byte("\x00\x00\x01\x37\x03\x09ab"))
+
+	if v := a.GetVendor(311, 1); string(v) != `one` {
+		t.Fatalf("got %q; expecting one", v)
+	}
+	if v := a.GetVendor(311, 2); string(v) != `two` {
+		t.Fatalf("got %q; expecting two", v)
+	}
+	if v, ok := a.LookupVendor(311, 3); !ok || len(v) != 0 {
+		t.Fatalf("got %q, %v; expecting empty value", v, ok)
+	}
+	if v := a.GetVendor(9, 2); string(v) != `cis` {
+		t.Fatalf("got %q; expecting cis", v)
+	}
+	if _, ok := a.LookupVendor(311, 4); ok {
+		t.Fatal("expecting missing sub-attribute lookup to fail")
+	}
+
+	a.DelVendor(311, 3)
+	if len(a) != 4 {
+		t.Fatalf("got %d attributes; expecting 4", len(a))
+	}
+	if expected := []byte("\x00\x00\x01\x37\x02\x05two"); !bytes.Equal(a[1].Attribute, expected) {
+		t.Fatalf("got %x; expecting %x", a[1].Attribute, expected)
+	}
+	if expected := []byte("\x00\x00\x01\x37\x03\x09ab"); !bytes.Equal(a[3].Attribute, expected) {
+		t.Fatal("expecting malformed Vendor-Specific attribute to be left untouched")
+	}
+
+	a.DelVendor(311, 1)
+	a.DelVendor(311, 2)
+	if len(a) != 2 || a.GetVendor(9, 2) == nil {
+		t.Fatalf("unexpected attributes %v", a)
+	}
+}
@@ -0,0 +1,69 @@
+package microsoft
+
+import (
+	"crypto/md5"
+	"errors"
+)
+
+// DecryptMSMPPEKeyTo decrypts an RFC 2548 encrypted MS-MPPE-Send-Key or
+// MS-MPPE-Recv-Key attribute value into dst, and returns the number of key
+// bytes written. attr is the raw attribute value (salt followed by the
+// encrypted string). The requestAuth must be from the Access-Request packet.
+//
+// Unlike the generated MSMPPESendKey and MSMPPERecvKey lookup functions,
+// DecryptMSMPPEKeyTo does not allocate when the secret is at most 110 bytes
+// long.
+//
+// An error is returned if the attribute is malformed, or if dst is too short
+// to hold the key.
+func DecryptMSMPPEKeyTo(dst, attr, secret, requestAuth []byte) (int, error) {
+	if len(attr) > 252 || len(attr) < 18 || (len(attr)-2)%16 != 0 {
+		return 0, errors.New("invalid length")
+	}
+	if len(secret) == 0 {
+		return 0, errors.New("empty secret")
+	}
+	if len(requestAuth) != 16 {
+		return 0, errors.New("invalid requestAuthenticator length")
+	}
+	if attr[0]&0x80 != 0x80 { // salt MSB must be 1
+		return 0, errors.New("invalid salt")
+	}
+
+	salt, c := attr[:2], attr[2:]
+
+	// Large enough for secret + requestAuth + salt with typical secrets, so
+	// the MD5 input does not need to be heap allocated.
+	var buf [128]byte
+
+	var keyLength, n int
+	for i := 0; i < len(c); i += md5.Size {
+		in := append(buf[:0], secret...)
+		if i == 0 {
+			in = append(in, requestAuth...)
+			in = append(in, salt...)
+		} else {
+			in = append(in, c[i-md5.Size:i]...)
+		}
+		b := md5.Sum(in)
+
+		for j := 0; j < md5.Size; j++ {
+			p := c[i+j] ^ b[j]
+			if i == 0 && j == 0 {
+				keyLength = int(p)
+				if keyLength > len(c)-1 {
+					return 0, errors.New("invalid key length")
+				}
+				if keyLength > len(dst) {
+					return 0, errors.New("destination too short")
+				}
+				continue
+			}
+			if n < keyLength {
+				dst[n] = p
+				n++
+			}
+		}
+	}
+	return n, nil
+}
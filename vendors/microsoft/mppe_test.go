@@ -0,0 +1,90 @@
+package microsoft
+
+import (
+	"bytes"
+	"testing"
+
+	"layeh.com/radius"
+)
+
+var (
+	mppeSecret        = []byte(`xyzzy5461`)
+	mppeAuthenticator = []byte{
+		0x0f, 0x40, 0x3f, 0x94, 0x73, 0x97, 0x80, 0x57,
+		0xbd, 0x83, 0xd5, 0xcb, 0x98, 0xf4, 0x22, 0x7a,
+	}
+	mppeKey = bytes.Repeat([]byte{0xaa, 0xbb, 0xcc, 0xdd}, 8)
+)
+
+func newEncryptedMPPEKey(tb testing.TB) radius.Attribute {
+	attr, err := radius.NewTunnelPassword(mppeKey, []byte{0x85, 0x12}, mppeSecret, mppeAuthenticator)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return attr
+}
+
+func TestDecryptMSMPPEKeyTo(t *testing.T) {
+	attr := newEncryptedMPPEKey(t)
+
+	var dst [64]byte
+	n, err := DecryptMSMPPEKeyTo(dst[:], attr, mppeSecret, mppeAuthenticator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst[:n], mppeKey) {
+		t.Fatalf("got %x; expecting %x", dst[:n], mppeKey)
+	}
+
+	if _, err := DecryptMSMPPEKeyTo(dst[:len(mppeKey)-1], attr, mppeSecret, mppeAuthenticator); err == nil {
+		t.Fatal("expecting short destination error")
+	}
+	if _, err := DecryptMSMPPEKeyTo(dst[:], attr[:17], mppeSecret, mppeAuthenticator); err == nil {
+		t.Fatal("expecting invalid length error")
+	}
+}
+
+func TestDecryptMSMPPEKeyTo_packet(t *testing.T) {
+	request := radius.New(radius.CodeAccessRequest, mppeSecret)
+	response := request.Response(radius.CodeAccessAccept)
+	if err := MSMPPESendKey_Add(response, mppeKey); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := MSMPPESendKey_Lookup(response, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst [64]byte
+	n, err := DecryptMSMPPEKeyTo(dst[:], _Microsoft_GetsVendor(response, 16)[0], mppeSecret, request.Authenticator[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst[:n], expected) {
+		t.Fatalf("got %x; expecting %x", dst[:n], expected)
+	}
+}
+
+func BenchmarkTunnelPassword(b *testing.B) {
+	attr := newEncryptedMPPEKey(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := radius.TunnelPassword(attr, mppeSecret, mppeAuthenticator); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptMSMPPEKeyTo(b *testing.B) {
+	attr := newEncryptedMPPEKey(b)
+	var dst [64]byte
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecryptMSMPPEKeyTo(dst[:], attr, mppeSecret, mppeAuthenticator); err != nil {
+			b.Fatal(err)
+		}
+	}
+}